@@ -13,6 +13,12 @@ type JwtPayload struct {
 	Admin    bool   `json:"admin"`
 	UserId   uint   `json:"userId"`
 	Theme    string `json:"theme"`
+	// SessionVersion is the user's session version at login. Tokens from
+	// before the version last changed are no longer accepted.
+	SessionVersion int32 `json:"sessionVersion"`
+	// TwoFactor is set when the account had two-factor authentication
+	// turned on when the session began.
+	TwoFactor bool `json:"twoFactor"`
 }
 
 type JwtRefreshPayload struct {
@@ -105,17 +111,31 @@ func verifyJwtToken(token string, jwtSecret []byte) (payload *JwtPayload, err er
 		return nil, err
 	}
 
-	if _, ok := jwtToken.Claims.(jwt.MapClaims); ok && jwtToken.Valid {
-		payload = &JwtPayload{
-			Username: jwtToken.Claims.(jwt.MapClaims)["username"].(string),
-			Admin:    jwtToken.Claims.(jwt.MapClaims)["admin"].(bool),
-			UserId:   uint(jwtToken.Claims.(jwt.MapClaims)["userId"].(float64)),
-			Theme:    jwtToken.Claims.(jwt.MapClaims)["theme"].(string),
-		}
-		return payload, nil
+	claims, ok := jwtToken.Claims.(jwt.MapClaims)
+	// Tokens with a purpose are email links and the like, never sessions.
+	if _, hasPurpose := claims["purpose"]; !ok || !jwtToken.Valid || hasPurpose {
+		return nil, fmt.Errorf("invalid token")
 	}
-
-	return nil, fmt.Errorf("invalid token")
+	username, usernameOk := claims["username"].(string)
+	admin, adminOk := claims["admin"].(bool)
+	userId, userIdOk := claims["userId"].(float64)
+	theme, themeOk := claims["theme"].(string)
+	if !usernameOk || !adminOk || !userIdOk || !themeOk {
+		return nil, fmt.Errorf("invalid token")
+	}
+	// Tokens issued before session versions existed have none, which
+	// matches the version every user started with.
+	sessionVersion, _ := claims["sessionVersion"].(float64)
+	twoFactor, _ := claims["twoFactor"].(bool)
+	payload = &JwtPayload{
+		Username:       username,
+		Admin:          admin,
+		UserId:         uint(userId),
+		Theme:          theme,
+		SessionVersion: int32(sessionVersion),
+		TwoFactor:      twoFactor,
+	}
+	return payload, nil
 }
 
 func verifyRefreshToken(token string, jwtSecret []byte) (payload *JwtRefreshPayload, err error) {
@@ -131,14 +151,15 @@ func verifyRefreshToken(token string, jwtSecret []byte) (payload *JwtRefreshPayl
 		return nil, err
 	}
 
-	if _, ok := jwtToken.Claims.(jwt.MapClaims); ok && jwtToken.Valid {
-		payload = &JwtRefreshPayload{
-			JwtToken: jwtToken.Claims.(jwt.MapClaims)["jwtToken"].(string),
-		}
-		return payload, nil
+	claims, ok := jwtToken.Claims.(jwt.MapClaims)
+	if _, hasPurpose := claims["purpose"]; !ok || !jwtToken.Valid || hasPurpose {
+		return nil, fmt.Errorf("invalid token")
 	}
-
-	return nil, fmt.Errorf("invalid token")
+	sessionToken, ok := claims["jwtToken"].(string)
+	if !ok {
+		return nil, fmt.Errorf("invalid token")
+	}
+	return &JwtRefreshPayload{JwtToken: sessionToken}, nil
 }
 
 func GenerateTokens(payload *JwtPayload) (token string, refreshToken string, err error) {
@@ -173,6 +194,8 @@ func generateJwtToken(payload *JwtPayload, jwtSecret []byte) (string, error) {
 	claims["admin"] = payload.Admin
 	claims["userId"] = payload.UserId
 	claims["theme"] = payload.Theme
+	claims["sessionVersion"] = payload.SessionVersion
+	claims["twoFactor"] = payload.TwoFactor
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 
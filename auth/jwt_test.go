@@ -3,6 +3,8 @@ package auth
 import (
 	"crypto/rand"
 	"testing"
+
+	"github.com/golang-jwt/jwt"
 )
 
 func TestJwtTokens(t *testing.T) {
@@ -13,9 +15,11 @@ func TestJwtTokens(t *testing.T) {
 	}
 
 	token, refreshToken, err := GenerateTokens(&JwtPayload{
-		Username: "test",
-		Admin:    true,
-		UserId:   1,
+		Username:       "test",
+		Admin:          true,
+		UserId:         1,
+		SessionVersion: 3,
+		TwoFactor:      true,
 	})
 	if err != nil {
 		t.Error(err)
@@ -40,6 +44,12 @@ func TestJwtTokens(t *testing.T) {
 	if payload.UserId != 1 {
 		t.Error("userId doesn't match")
 	}
+	if payload.SessionVersion != 3 {
+		t.Error("sessionVersion doesn't match")
+	}
+	if !payload.TwoFactor {
+		t.Error("twoFactor doesn't match")
+	}
 
 	refreshPayload, err := VerifyRefreshToken(refreshToken)
 	if err != nil {
@@ -54,3 +64,23 @@ func TestJwtTokens(t *testing.T) {
 		t.Error("jwtToken doesn't match")
 	}
 }
+
+func TestVerifyJwtTokenRejectsOtherTokens(t *testing.T) {
+	secret := []byte("test secret")
+	for name, claims := range map[string]jwt.MapClaims{
+		"missing claims": {"email": "ann@example.com"},
+		"wrong types":    {"username": 1, "admin": "yes", "userId": "1", "theme": nil},
+		"purpose":        {"purpose": "verify-email", "username": "ann", "admin": true, "userId": 1, "theme": ""},
+	} {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := verifyJwtToken(token, secret); err == nil {
+			t.Errorf("%s: accepted as a session", name)
+		}
+		if _, err := verifyRefreshToken(token, secret); err == nil {
+			t.Errorf("%s: accepted as a refresh token", name)
+		}
+	}
+}
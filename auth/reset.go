@@ -0,0 +1,30 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"time"
+)
+
+// PasswordResetTTL is how long a password reset link works.
+const PasswordResetTTL = time.Hour
+
+// NewPasswordResetToken makes a random token to mail to the user, and the
+// hash to store in its place. Someone reading the database can't use the
+// hash to reset a password.
+func NewPasswordResetToken() (token string, hash []byte, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", nil, err
+	}
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, HashPasswordResetToken(token), nil
+}
+
+// HashPasswordResetToken is the hash a token is stored and looked up by.
+// Tokens are random enough that a plain hash does, unlike passwords.
+func HashPasswordResetToken(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+	return sum[:]
+}
@@ -0,0 +1,33 @@
+package auth
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPasswordResetToken(t *testing.T) {
+	token, hash, err := NewPasswordResetToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(token) < 40 {
+		t.Errorf("token %q is too short", token)
+	}
+	if !bytes.Equal(HashPasswordResetToken(token), hash) {
+		t.Error("hash doesn't match the token")
+	}
+	if bytes.Contains(hash, []byte(token)) {
+		t.Error("hash contains the token")
+	}
+
+	other, otherHash, err := NewPasswordResetToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other == token || bytes.Equal(otherHash, hash) {
+		t.Error("tokens repeat")
+	}
+	if bytes.Equal(HashPasswordResetToken(token+"x"), hash) {
+		t.Error("different tokens have the same hash")
+	}
+}
@@ -0,0 +1,108 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// TwoFactorLoginTTL is how long someone who got their password right has to
+// enter their second factor.
+const TwoFactorLoginTTL = 5 * time.Minute
+
+// RecoveryCodeCount is how many recovery codes are made at a time.
+const RecoveryCodeCount = 10
+
+const purposeTwoFactorLogin = "2fa-login"
+
+var ErrTwoFactorLoginExpired = errors.New("two-factor login expired")
+
+// recoveryAlphabet leaves out characters that are easily mistaken for
+// others.
+const recoveryAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+
+// NewRecoveryCodes makes a fresh set of recovery codes to show the user
+// once, and the hashes to store in their place.
+func NewRecoveryCodes() (codes []string, hashes [][]byte, err error) {
+	for range RecoveryCodeCount {
+		b, err := randomRecoveryChars(10)
+		if err != nil {
+			return nil, nil, err
+		}
+		code := string(b[:5]) + "-" + string(b[5:])
+		codes = append(codes, code)
+		hashes = append(hashes, HashRecoveryCode(code))
+	}
+	return codes, hashes, nil
+}
+
+// randomRecoveryChars picks n characters from recoveryAlphabet. Bytes past
+// the last whole multiple of the alphabet's length are thrown away, so
+// every character is as likely.
+func randomRecoveryChars(n int) ([]byte, error) {
+	limit := 256 - 256%len(recoveryAlphabet)
+	out := make([]byte, 0, n)
+	buf := make([]byte, n)
+	for len(out) < n {
+		if _, err := rand.Read(buf); err != nil {
+			return nil, err
+		}
+		for _, b := range buf {
+			if int(b) < limit && len(out) < n {
+				out = append(out, recoveryAlphabet[int(b)%len(recoveryAlphabet)])
+			}
+		}
+	}
+	return out, nil
+}
+
+// HashRecoveryCode is the hash a recovery code is stored and looked up by.
+// Case, spaces and dashes don't matter.
+func HashRecoveryCode(code string) []byte {
+	code = strings.ToLower(code)
+	code = strings.NewReplacer("-", "", " ", "").Replace(code)
+	sum := sha256.Sum256([]byte(code))
+	return sum[:]
+}
+
+// TwoFactorLoginToken signs the form asking for the second factor, once the
+// password has been checked. It is only good for the session version it was
+// made with, so changing the password cancels it.
+func TwoFactorLoginToken(userId int64, sessionVersion int32) (string, error) {
+	return twoFactorLoginToken(userId, sessionVersion, time.Now(), []byte(os.Getenv("JWT_SECRET")))
+}
+
+// VerifyTwoFactorLoginToken returns the user and session version of a
+// genuine token that hasn't expired.
+func VerifyTwoFactorLoginToken(token string) (userId int64, sessionVersion int32, err error) {
+	return verifyTwoFactorLoginToken(token, time.Now(), []byte(os.Getenv("JWT_SECRET")))
+}
+
+func twoFactorLoginToken(userId int64, sessionVersion int32, now time.Time, jwtSecret []byte) (string, error) {
+	return SignPurposeToken(jwtSecret, purposeTwoFactorLogin, jwt.MapClaims{
+		"userId":         userId,
+		"sessionVersion": sessionVersion,
+		"exp":            now.Add(TwoFactorLoginTTL).Unix(),
+	})
+}
+
+func verifyTwoFactorLoginToken(token string, now time.Time, jwtSecret []byte) (int64, int32, error) {
+	claims, err := ParsePurposeToken(jwtSecret, token, purposeTwoFactorLogin, now)
+	if errors.Is(err, ErrTokenExpired) {
+		return 0, 0, ErrTwoFactorLoginExpired
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+	userId, idOk := claims["userId"].(float64)
+	sessionVersion, versionOk := claims["sessionVersion"].(float64)
+	if !idOk || !versionOk {
+		return 0, 0, ErrInvalidToken
+	}
+	return int64(userId), int32(sessionVersion), nil
+}
@@ -0,0 +1,70 @@
+package auth
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, hashes, err := NewRecoveryCodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(codes) != RecoveryCodeCount || len(hashes) != RecoveryCodeCount {
+		t.Fatalf("got %d codes and %d hashes", len(codes), len(hashes))
+	}
+
+	seen := map[string]bool{}
+	for i, code := range codes {
+		if len(code) != 11 || code[5] != '-' || seen[code] {
+			t.Errorf("bad or repeated code %q", code)
+		}
+		seen[code] = true
+		if !bytes.Equal(HashRecoveryCode(code), hashes[i]) {
+			t.Errorf("hash of %q doesn't match", code)
+		}
+		// Codes typed in by hand still match.
+		typed := strings.ToUpper(strings.ReplaceAll(code, "-", " "))
+		if !bytes.Equal(HashRecoveryCode(typed), hashes[i]) {
+			t.Errorf("%q doesn't match %q", typed, code)
+		}
+	}
+}
+
+func TestTwoFactorLoginToken(t *testing.T) {
+	secret := []byte("test secret")
+	now := time.Date(2026, time.October, 18, 12, 0, 0, 0, time.UTC)
+
+	token, err := twoFactorLoginToken(7, 2, now, secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	userId, version, err := verifyTwoFactorLoginToken(token, now.Add(time.Minute), secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if userId != 7 || version != 2 {
+		t.Errorf("got %d %d", userId, version)
+	}
+
+	if _, _, err := verifyTwoFactorLoginToken(token, now.Add(TwoFactorLoginTTL+time.Second), secret); !errors.Is(err, ErrTwoFactorLoginExpired) {
+		t.Errorf("expired token: %v", err)
+	}
+	if _, _, err := verifyTwoFactorLoginToken(token, now, []byte("other secret")); err == nil {
+		t.Error("token signed with another secret verified")
+	}
+
+	// An email verification link is signed with the same secret but can't
+	// stand in for a password.
+	link, err := emailVerificationToken(7, "ann@example.com", now, secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := verifyTwoFactorLoginToken(link, now, secret); err == nil {
+		t.Error("verification link accepted as a two-factor login")
+	}
+}
@@ -0,0 +1,54 @@
+package auth
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// EmailVerificationTTL is how long an email verification link works.
+const EmailVerificationTTL = 48 * time.Hour
+
+const purposeVerifyEmail = "verify-email"
+
+var ErrVerificationExpired = errors.New("verification link expired")
+
+// EmailVerificationToken signs the link that proves userId can read mail
+// sent to email. Changing the address makes older links useless, since the
+// address they verify no longer matches.
+func EmailVerificationToken(userId int64, email string) (string, error) {
+	return emailVerificationToken(userId, email, time.Now(), []byte(os.Getenv("JWT_SECRET")))
+}
+
+// VerifyEmailVerificationToken returns the user and address of a genuine
+// link that hasn't expired.
+func VerifyEmailVerificationToken(token string) (userId int64, email string, err error) {
+	return verifyEmailVerificationToken(token, time.Now(), []byte(os.Getenv("JWT_SECRET")))
+}
+
+func emailVerificationToken(userId int64, email string, now time.Time, jwtSecret []byte) (string, error) {
+	return SignPurposeToken(jwtSecret, purposeVerifyEmail, jwt.MapClaims{
+		"userId": userId,
+		"email":  email,
+		"exp":    now.Add(EmailVerificationTTL).Unix(),
+	})
+}
+
+func verifyEmailVerificationToken(token string, now time.Time, jwtSecret []byte) (int64, string, error) {
+	claims, err := ParsePurposeToken(jwtSecret, token, purposeVerifyEmail, now)
+	if errors.Is(err, ErrTokenExpired) {
+		return 0, "", ErrVerificationExpired
+	}
+	if err != nil {
+		return 0, "", err
+	}
+	userId, idOk := claims["userId"].(float64)
+	email, emailOk := claims["email"].(string)
+	if !idOk || !emailOk || !strings.Contains(email, "@") {
+		return 0, "", ErrInvalidToken
+	}
+	return int64(userId), email, nil
+}
@@ -0,0 +1,42 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestEmailVerificationToken(t *testing.T) {
+	secret := []byte("test secret")
+	now := time.Date(2026, time.October, 18, 12, 0, 0, 0, time.UTC)
+
+	token, err := emailVerificationToken(7, "ann@example.com", now, secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	userId, email, err := verifyEmailVerificationToken(token, now.Add(time.Hour), secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if userId != 7 || email != "ann@example.com" {
+		t.Errorf("got %d %q", userId, email)
+	}
+
+	if _, _, err := verifyEmailVerificationToken(token, now.Add(EmailVerificationTTL+time.Second), secret); !errors.Is(err, ErrVerificationExpired) {
+		t.Errorf("expired token: %v", err)
+	}
+	if _, _, err := verifyEmailVerificationToken(token, now, []byte("other secret")); err == nil {
+		t.Error("token signed with another secret verified")
+	}
+
+	// A session token is signed with the same secret but isn't a
+	// verification link.
+	session, err := generateJwtToken(&JwtPayload{Username: "ann", UserId: 7}, secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := verifyEmailVerificationToken(session, now, secret); err == nil {
+		t.Error("session token accepted as a verification link")
+	}
+}
@@ -7,22 +7,82 @@ package db
 
 import (
 	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const countApprovedCommentsByUser = `-- name: CountApprovedCommentsByUser :one
+SELECT COUNT(*) FROM comments
+WHERE user_id = $1 AND status = 'approved'
+`
+
+func (q *Queries) CountApprovedCommentsByUser(ctx context.Context, userID *int64) (int64, error) {
+	row := q.db.QueryRow(ctx, countApprovedCommentsByUser, userID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countCommentsByStatus = `-- name: CountCommentsByStatus :many
+SELECT status, COUNT(*) AS count FROM comments
+WHERE deleted_at IS NULL
+GROUP BY status
+`
+
+type CountCommentsByStatusRow struct {
+	Status string `json:"status"`
+	Count  int64  `json:"count"`
+}
+
+func (q *Queries) CountCommentsByStatus(ctx context.Context) ([]CountCommentsByStatusRow, error) {
+	rows, err := q.db.Query(ctx, countCommentsByStatus)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []CountCommentsByStatusRow
+	for rows.Next() {
+		var i CountCommentsByStatusRow
+		if err := rows.Scan(
+			&i.Status,
+			&i.Count,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const createComment = `-- name: CreateComment :one
-INSERT INTO comments (blog_post_id, author, comment)
-VALUES ($1, $2, $3)
-RETURNING id, created_at, updated_at, deleted_at, blog_post_id, author, comment
+INSERT INTO comments (blog_post_id, parent_id, user_id, author, comment, status, spam_reason)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING id, created_at, updated_at, deleted_at, blog_post_id, author, comment, parent_id, status, user_id, edited_at, spam_reason, trained_as
 `
 
 type CreateCommentParams struct {
-	BlogPostID int64  `json:"blog_post_id"`
-	Author     string `json:"author"`
-	Comment    string `json:"comment"`
+	BlogPostID int64   `json:"blog_post_id"`
+	ParentID   *int64  `json:"parent_id"`
+	UserID     *int64  `json:"user_id"`
+	Author     string  `json:"author"`
+	Comment    string  `json:"comment"`
+	Status     string  `json:"status"`
+	SpamReason *string `json:"spam_reason"`
 }
 
 func (q *Queries) CreateComment(ctx context.Context, arg CreateCommentParams) (Comment, error) {
-	row := q.db.QueryRow(ctx, createComment, arg.BlogPostID, arg.Author, arg.Comment)
+	row := q.db.QueryRow(ctx, createComment,
+		arg.BlogPostID,
+		arg.ParentID,
+		arg.UserID,
+		arg.Author,
+		arg.Comment,
+		arg.Status,
+		arg.SpamReason,
+	)
 	var i Comment
 	err := row.Scan(
 		&i.ID,
@@ -32,25 +92,99 @@ func (q *Queries) CreateComment(ctx context.Context, arg CreateCommentParams) (C
 		&i.BlogPostID,
 		&i.Author,
 		&i.Comment,
+		&i.ParentID,
+		&i.Status,
+		&i.UserID,
+		&i.EditedAt,
+		&i.SpamReason,
+		&i.TrainedAs,
+	)
+	return i, err
+}
+
+const getCommentByID = `-- name: GetCommentByID :one
+SELECT comments.id, comments.created_at, comments.updated_at, comments.deleted_at, comments.blog_post_id, comments.author, comments.comment, comments.parent_id, comments.status, comments.user_id, comments.edited_at, comments.spam_reason, comments.trained_as, users.username AS username
+FROM comments
+LEFT JOIN users ON users.id = comments.user_id
+WHERE comments.id = $1 AND comments.status = 'approved' AND comments.deleted_at IS NULL
+LIMIT 1
+`
+
+type GetCommentByIDRow struct {
+	ID         int64              `json:"id"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt  pgtype.Timestamptz `json:"updated_at"`
+	DeletedAt  pgtype.Timestamptz `json:"deleted_at"`
+	BlogPostID int64              `json:"blog_post_id"`
+	Author     string             `json:"author"`
+	Comment    string             `json:"comment"`
+	ParentID   *int64             `json:"parent_id"`
+	Status     string             `json:"status"`
+	UserID     *int64             `json:"user_id"`
+	EditedAt   pgtype.Timestamptz `json:"edited_at"`
+	SpamReason *string            `json:"spam_reason"`
+	TrainedAs  *string            `json:"trained_as"`
+	Username   *string            `json:"username"`
+}
+
+func (q *Queries) GetCommentByID(ctx context.Context, id int64) (GetCommentByIDRow, error) {
+	row := q.db.QueryRow(ctx, getCommentByID, id)
+	var i GetCommentByIDRow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.BlogPostID,
+		&i.Author,
+		&i.Comment,
+		&i.ParentID,
+		&i.Status,
+		&i.UserID,
+		&i.EditedAt,
+		&i.SpamReason,
+		&i.TrainedAs,
+		&i.Username,
 	)
 	return i, err
 }
 
 const getCommentsByPostID = `-- name: GetCommentsByPostID :many
-SELECT id, created_at, updated_at, deleted_at, blog_post_id, author, comment FROM comments
-WHERE blog_post_id = $1 AND deleted_at IS NULL
-ORDER BY created_at DESC
+SELECT comments.id, comments.created_at, comments.updated_at, comments.deleted_at, comments.blog_post_id, comments.author, comments.comment, comments.parent_id, comments.status, comments.user_id, comments.edited_at, comments.spam_reason, comments.trained_as, users.username AS username
+FROM comments
+LEFT JOIN users ON users.id = comments.user_id
+WHERE comments.blog_post_id = $1 AND comments.status = 'approved'
+ORDER BY comments.created_at ASC, comments.id ASC
 `
 
-func (q *Queries) GetCommentsByPostID(ctx context.Context, blogPostID int64) ([]Comment, error) {
+type GetCommentsByPostIDRow struct {
+	ID         int64              `json:"id"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt  pgtype.Timestamptz `json:"updated_at"`
+	DeletedAt  pgtype.Timestamptz `json:"deleted_at"`
+	BlogPostID int64              `json:"blog_post_id"`
+	Author     string             `json:"author"`
+	Comment    string             `json:"comment"`
+	ParentID   *int64             `json:"parent_id"`
+	Status     string             `json:"status"`
+	UserID     *int64             `json:"user_id"`
+	EditedAt   pgtype.Timestamptz `json:"edited_at"`
+	SpamReason *string            `json:"spam_reason"`
+	TrainedAs  *string            `json:"trained_as"`
+	Username   *string            `json:"username"`
+}
+
+// Deleted comments are included so replies to them keep their place in the
+// thread.
+func (q *Queries) GetCommentsByPostID(ctx context.Context, blogPostID int64) ([]GetCommentsByPostIDRow, error) {
 	rows, err := q.db.Query(ctx, getCommentsByPostID, blogPostID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Comment
+	var items []GetCommentsByPostIDRow
 	for rows.Next() {
-		var i Comment
+		var i GetCommentsByPostIDRow
 		if err := rows.Scan(
 			&i.ID,
 			&i.CreatedAt,
@@ -59,6 +193,13 @@ func (q *Queries) GetCommentsByPostID(ctx context.Context, blogPostID int64) ([]
 			&i.BlogPostID,
 			&i.Author,
 			&i.Comment,
+			&i.ParentID,
+			&i.Status,
+			&i.UserID,
+			&i.EditedAt,
+			&i.SpamReason,
+			&i.TrainedAs,
+			&i.Username,
 		); err != nil {
 			return nil, err
 		}
@@ -69,3 +210,163 @@ func (q *Queries) GetCommentsByPostID(ctx context.Context, blogPostID int64) ([]
 	}
 	return items, nil
 }
+
+const getCommentsByStatus = `-- name: GetCommentsByStatus :many
+SELECT comments.id, comments.created_at, comments.updated_at, comments.deleted_at, comments.blog_post_id, comments.author, comments.comment, comments.parent_id, comments.status, comments.user_id, comments.edited_at, comments.spam_reason, comments.trained_as, users.username AS username, blog_posts.title AS post_title
+FROM comments
+JOIN blog_posts ON blog_posts.id = comments.blog_post_id
+LEFT JOIN users ON users.id = comments.user_id
+WHERE comments.status = $1 AND comments.deleted_at IS NULL
+ORDER BY comments.created_at ASC, comments.id ASC
+LIMIT $2
+`
+
+type GetCommentsByStatusParams struct {
+	Status   string `json:"status"`
+	PageSize int32  `json:"page_size"`
+}
+
+type GetCommentsByStatusRow struct {
+	ID         int64              `json:"id"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt  pgtype.Timestamptz `json:"updated_at"`
+	DeletedAt  pgtype.Timestamptz `json:"deleted_at"`
+	BlogPostID int64              `json:"blog_post_id"`
+	Author     string             `json:"author"`
+	Comment    string             `json:"comment"`
+	ParentID   *int64             `json:"parent_id"`
+	Status     string             `json:"status"`
+	UserID     *int64             `json:"user_id"`
+	EditedAt   pgtype.Timestamptz `json:"edited_at"`
+	SpamReason *string            `json:"spam_reason"`
+	TrainedAs  *string            `json:"trained_as"`
+	Username   *string            `json:"username"`
+	PostTitle  string             `json:"post_title"`
+}
+
+func (q *Queries) GetCommentsByStatus(ctx context.Context, arg GetCommentsByStatusParams) ([]GetCommentsByStatusRow, error) {
+	rows, err := q.db.Query(ctx, getCommentsByStatus, arg.Status, arg.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetCommentsByStatusRow
+	for rows.Next() {
+		var i GetCommentsByStatusRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DeletedAt,
+			&i.BlogPostID,
+			&i.Author,
+			&i.Comment,
+			&i.ParentID,
+			&i.Status,
+			&i.UserID,
+			&i.EditedAt,
+			&i.SpamReason,
+			&i.TrainedAs,
+			&i.Username,
+			&i.PostTitle,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getOwnCommentByID = `-- name: GetOwnCommentByID :one
+SELECT id, created_at, updated_at, deleted_at, blog_post_id, author, comment, parent_id, status, user_id, edited_at, spam_reason, trained_as FROM comments
+WHERE id = $1 AND blog_post_id = $2 AND deleted_at IS NULL
+LIMIT 1
+`
+
+type GetOwnCommentByIDParams struct {
+	ID         int64 `json:"id"`
+	BlogPostID int64 `json:"blog_post_id"`
+}
+
+func (q *Queries) GetOwnCommentByID(ctx context.Context, arg GetOwnCommentByIDParams) (Comment, error) {
+	row := q.db.QueryRow(ctx, getOwnCommentByID, arg.ID, arg.BlogPostID)
+	var i Comment
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.BlogPostID,
+		&i.Author,
+		&i.Comment,
+		&i.ParentID,
+		&i.Status,
+		&i.UserID,
+		&i.EditedAt,
+		&i.SpamReason,
+		&i.TrainedAs,
+	)
+	return i, err
+}
+
+const setCommentsStatus = `-- name: SetCommentsStatus :exec
+UPDATE comments
+SET status = $1, updated_at = NOW()
+WHERE id = ANY($2::bigint[])
+`
+
+type SetCommentsStatusParams struct {
+	Status string  `json:"status"`
+	Ids    []int64 `json:"ids"`
+}
+
+func (q *Queries) SetCommentsStatus(ctx context.Context, arg SetCommentsStatusParams) error {
+	_, err := q.db.Exec(ctx, setCommentsStatus, arg.Status, arg.Ids)
+	return err
+}
+
+const softDeleteComment = `-- name: SoftDeleteComment :exec
+UPDATE comments SET deleted_at = NOW(), updated_at = NOW() WHERE id = $1
+`
+
+func (q *Queries) SoftDeleteComment(ctx context.Context, id int64) error {
+	_, err := q.db.Exec(ctx, softDeleteComment, id)
+	return err
+}
+
+const softDeleteComments = `-- name: SoftDeleteComments :exec
+UPDATE comments SET deleted_at = NOW(), updated_at = NOW() WHERE id = ANY($1::bigint[])
+`
+
+func (q *Queries) SoftDeleteComments(ctx context.Context, ids []int64) error {
+	_, err := q.db.Exec(ctx, softDeleteComments, ids)
+	return err
+}
+
+const updateCommentText = `-- name: UpdateCommentText :exec
+UPDATE comments
+SET comment = $1, status = $2, spam_reason = $3, edited_at = NOW(), updated_at = NOW()
+WHERE id = $4
+`
+
+type UpdateCommentTextParams struct {
+	Comment    string  `json:"comment"`
+	Status     string  `json:"status"`
+	SpamReason *string `json:"spam_reason"`
+	ID         int64   `json:"id"`
+}
+
+// An edit can send the comment back to moderation, so its status is set
+// along with the text.
+func (q *Queries) UpdateCommentText(ctx context.Context, arg UpdateCommentTextParams) error {
+	_, err := q.db.Exec(ctx, updateCommentText,
+		arg.Comment,
+		arg.Status,
+		arg.SpamReason,
+		arg.ID,
+	)
+	return err
+}
@@ -9,17 +9,35 @@ import (
 )
 
 type BlogPost struct {
-	ID          int64              `json:"id"`
-	CreatedAt   pgtype.Timestamptz `json:"created_at"`
-	UpdatedAt   pgtype.Timestamptz `json:"updated_at"`
-	DeletedAt   pgtype.Timestamptz `json:"deleted_at"`
-	Title       string             `json:"title"`
-	Author      string             `json:"author"`
-	Slug        string             `json:"slug"`
-	Content     string             `json:"content"`
-	Description string             `json:"description"`
-	Draft       bool               `json:"draft"`
-	PublishedAt pgtype.Timestamptz `json:"published_at"`
+	ID           int64              `json:"id"`
+	CreatedAt    pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt    pgtype.Timestamptz `json:"updated_at"`
+	DeletedAt    pgtype.Timestamptz `json:"deleted_at"`
+	Title        string             `json:"title"`
+	Author       string             `json:"author"`
+	Slug         string             `json:"slug"`
+	Content      string             `json:"content"`
+	Description  string             `json:"description"`
+	Draft        bool               `json:"draft"`
+	PublishedAt  pgtype.Timestamptz `json:"published_at"`
+	SearchVector interface{}        `json:"search_vector"`
+	ScheduledAt  pgtype.Timestamptz `json:"scheduled_at"`
+}
+
+type BlogPostRevision struct {
+	ID         int64              `json:"id"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+	BlogPostID int64              `json:"blog_post_id"`
+	Author     string             `json:"author"`
+	Title      string             `json:"title"`
+	Content    string             `json:"content"`
+}
+
+type BlogPostSlugHistory struct {
+	ID         int64              `json:"id"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+	BlogPostID int64              `json:"blog_post_id"`
+	Slug       string             `json:"slug"`
 }
 
 type BlogPostTag struct {
@@ -35,6 +53,111 @@ type Comment struct {
 	BlogPostID int64              `json:"blog_post_id"`
 	Author     string             `json:"author"`
 	Comment    string             `json:"comment"`
+	ParentID   *int64             `json:"parent_id"`
+	Status     string             `json:"status"`
+	UserID     *int64             `json:"user_id"`
+	EditedAt   pgtype.Timestamptz `json:"edited_at"`
+	SpamReason *string            `json:"spam_reason"`
+	TrainedAs  *string            `json:"trained_as"`
+}
+
+type Invite struct {
+	ID        int64              `json:"id"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+	Code      string             `json:"code"`
+	Note      string             `json:"note"`
+	CreatedBy *int64             `json:"created_by"`
+	UsedAt    pgtype.Timestamptz `json:"used_at"`
+	UsedBy    *int64             `json:"used_by"`
+}
+
+type NewsletterIssue struct {
+	BlogPostID       int64              `json:"blog_post_id"`
+	CreatedAt        pgtype.Timestamptz `json:"created_at"`
+	LastSubscriberID int64              `json:"last_subscriber_id"`
+	Recipients       int32              `json:"recipients"`
+	SentAt           pgtype.Timestamptz `json:"sent_at"`
+}
+
+type Notification struct {
+	ID        int64              `json:"id"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+	UserID    int64              `json:"user_id"`
+	CommentID int64              `json:"comment_id"`
+	Kind      string             `json:"kind"`
+	SentAt    pgtype.Timestamptz `json:"sent_at"`
+}
+
+type Outbox struct {
+	ID            int64              `json:"id"`
+	CreatedAt     pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt     pgtype.Timestamptz `json:"updated_at"`
+	Sender        string             `json:"sender"`
+	Recipients    []string           `json:"recipients"`
+	Subject       string             `json:"subject"`
+	Message       []byte             `json:"message"`
+	Status        string             `json:"status"`
+	Attempts      int32              `json:"attempts"`
+	NextAttemptAt pgtype.Timestamptz `json:"next_attempt_at"`
+	LastError     *string            `json:"last_error"`
+	SentAt        pgtype.Timestamptz `json:"sent_at"`
+}
+
+type PasswordReset struct {
+	ID        int64              `json:"id"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+	UserID    int64              `json:"user_id"`
+	TokenHash []byte             `json:"token_hash"`
+	ExpiresAt pgtype.Timestamptz `json:"expires_at"`
+	UsedAt    pgtype.Timestamptz `json:"used_at"`
+}
+
+type RecoveryCode struct {
+	ID       int64              `json:"id"`
+	UserID   int64              `json:"user_id"`
+	CodeHash []byte             `json:"code_hash"`
+	UsedAt   pgtype.Timestamptz `json:"used_at"`
+}
+
+type Redirect struct {
+	ID         int64              `json:"id"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+	SourcePath string             `json:"source_path"`
+	TargetPath string             `json:"target_path"`
+	StatusCode int32              `json:"status_code"`
+	Hits       int64              `json:"hits"`
+	LastHitAt  pgtype.Timestamptz `json:"last_hit_at"`
+}
+
+type SpamBlocklist struct {
+	ID        int64              `json:"id"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+	Kind      string             `json:"kind"`
+	Value     string             `json:"value"`
+}
+
+type SpamToken struct {
+	Token     string `json:"token"`
+	HamCount  int64  `json:"ham_count"`
+	SpamCount int64  `json:"spam_count"`
+}
+
+type Subscriber struct {
+	ID             int64              `json:"id"`
+	CreatedAt      pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt      pgtype.Timestamptz `json:"updated_at"`
+	Email          string             `json:"email"`
+	AllPosts       bool               `json:"all_posts"`
+	ConfirmedAt    pgtype.Timestamptz `json:"confirmed_at"`
+	UnsubscribedAt pgtype.Timestamptz `json:"unsubscribed_at"`
+	Bounces        int32              `json:"bounces"`
+	LastBounceAt   pgtype.Timestamptz `json:"last_bounce_at"`
+	LastBounce     *string            `json:"last_bounce"`
+}
+
+type SubscriberTag struct {
+	SubscriberID int64 `json:"subscriber_id"`
+	TagID        int64 `json:"tag_id"`
 }
 
 type Tag struct {
@@ -46,12 +169,20 @@ type Tag struct {
 }
 
 type User struct {
-	ID        int64              `json:"id"`
-	CreatedAt pgtype.Timestamptz `json:"created_at"`
-	UpdatedAt pgtype.Timestamptz `json:"updated_at"`
-	DeletedAt pgtype.Timestamptz `json:"deleted_at"`
-	Username  string             `json:"username"`
-	Password  string             `json:"password"`
-	Admin     bool               `json:"admin"`
-	Theme     string             `json:"theme"`
+	ID              int64              `json:"id"`
+	CreatedAt       pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt       pgtype.Timestamptz `json:"updated_at"`
+	DeletedAt       pgtype.Timestamptz `json:"deleted_at"`
+	Username        string             `json:"username"`
+	Password        string             `json:"password"`
+	Admin           bool               `json:"admin"`
+	Theme           string             `json:"theme"`
+	Email           *string            `json:"email"`
+	NotifyComments  bool               `json:"notify_comments"`
+	NotifyReplies   bool               `json:"notify_replies"`
+	EmailVerifiedAt pgtype.Timestamptz `json:"email_verified_at"`
+	SessionVersion  int32              `json:"session_version"`
+	TotpSecret      *string            `json:"totp_secret"`
+	TotpEnabledAt   pgtype.Timestamptz `json:"totp_enabled_at"`
+	TotpLastStep    int64              `json:"totp_last_step"`
 }
@@ -11,10 +11,22 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const countDraftPosts = `-- name: CountDraftPosts :one
+SELECT COUNT(*) FROM blog_posts
+WHERE draft = true AND scheduled_at IS NULL AND deleted_at IS NULL
+`
+
+func (q *Queries) CountDraftPosts(ctx context.Context) (int64, error) {
+	row := q.db.QueryRow(ctx, countDraftPosts)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createPost = `-- name: CreatePost :one
 INSERT INTO blog_posts (title, author, slug, content, description, draft, published_at)
 VALUES ($1, $2, $3, $4, $5, $6, $7)
-RETURNING id, created_at, updated_at, deleted_at, title, author, slug, content, description, draft, published_at
+RETURNING id, created_at, updated_at, deleted_at, title, author, slug, content, description, draft, published_at, search_vector, scheduled_at
 `
 
 type CreatePostParams struct {
@@ -50,16 +62,43 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (BlogPos
 		&i.Description,
 		&i.Draft,
 		&i.PublishedAt,
+		&i.SearchVector,
+		&i.ScheduledAt,
 	)
 	return i, err
 }
 
+const createPostSlugHistory = `-- name: CreatePostSlugHistory :exec
+INSERT INTO blog_post_slug_history (blog_post_id, slug)
+VALUES ($1, $2)
+`
+
+type CreatePostSlugHistoryParams struct {
+	BlogPostID int64  `json:"blog_post_id"`
+	Slug       string `json:"slug"`
+}
+
+func (q *Queries) CreatePostSlugHistory(ctx context.Context, arg CreatePostSlugHistoryParams) error {
+	_, err := q.db.Exec(ctx, createPostSlugHistory, arg.BlogPostID, arg.Slug)
+	return err
+}
+
 const getAllPostsAdmin = `-- name: GetAllPostsAdmin :many
-SELECT id, created_at, updated_at, deleted_at, title, author, slug, content, description, draft, published_at FROM blog_posts WHERE deleted_at IS NULL ORDER BY created_at DESC LIMIT 10
+SELECT id, created_at, updated_at, deleted_at, title, author, slug, content, description, draft, published_at, search_vector, scheduled_at FROM blog_posts
+WHERE deleted_at IS NULL
+  AND (COALESCE(published_at, created_at), id) < ($1::timestamptz, $2::bigint)
+ORDER BY COALESCE(published_at, created_at) DESC, id DESC
+LIMIT $3
 `
 
-func (q *Queries) GetAllPostsAdmin(ctx context.Context) ([]BlogPost, error) {
-	rows, err := q.db.Query(ctx, getAllPostsAdmin)
+type GetAllPostsAdminParams struct {
+	CursorTime pgtype.Timestamptz `json:"cursor_time"`
+	CursorID   int64              `json:"cursor_id"`
+	PageSize   int32              `json:"page_size"`
+}
+
+func (q *Queries) GetAllPostsAdmin(ctx context.Context, arg GetAllPostsAdminParams) ([]BlogPost, error) {
+	rows, err := q.db.Query(ctx, getAllPostsAdmin, arg.CursorTime, arg.CursorID, arg.PageSize)
 	if err != nil {
 		return nil, err
 	}
@@ -79,6 +118,56 @@ func (q *Queries) GetAllPostsAdmin(ctx context.Context) ([]BlogPost, error) {
 			&i.Description,
 			&i.Draft,
 			&i.PublishedAt,
+			&i.SearchVector,
+			&i.ScheduledAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getAllPostsAdminBefore = `-- name: GetAllPostsAdminBefore :many
+SELECT id, created_at, updated_at, deleted_at, title, author, slug, content, description, draft, published_at, search_vector, scheduled_at FROM blog_posts
+WHERE deleted_at IS NULL
+  AND (COALESCE(published_at, created_at), id) > ($1::timestamptz, $2::bigint)
+ORDER BY COALESCE(published_at, created_at) ASC, id ASC
+LIMIT $3
+`
+
+type GetAllPostsAdminBeforeParams struct {
+	CursorTime pgtype.Timestamptz `json:"cursor_time"`
+	CursorID   int64              `json:"cursor_id"`
+	PageSize   int32              `json:"page_size"`
+}
+
+func (q *Queries) GetAllPostsAdminBefore(ctx context.Context, arg GetAllPostsAdminBeforeParams) ([]BlogPost, error) {
+	rows, err := q.db.Query(ctx, getAllPostsAdminBefore, arg.CursorTime, arg.CursorID, arg.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []BlogPost
+	for rows.Next() {
+		var i BlogPost
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DeletedAt,
+			&i.Title,
+			&i.Author,
+			&i.Slug,
+			&i.Content,
+			&i.Description,
+			&i.Draft,
+			&i.PublishedAt,
+			&i.SearchVector,
+			&i.ScheduledAt,
 		); err != nil {
 			return nil, err
 		}
@@ -91,12 +180,69 @@ func (q *Queries) GetAllPostsAdmin(ctx context.Context) ([]BlogPost, error) {
 }
 
 const getDraftPosts = `-- name: GetDraftPosts :many
-SELECT id, created_at, updated_at, deleted_at, title, author, slug, content, description, draft, published_at FROM blog_posts
-WHERE draft = true AND deleted_at IS NULL ORDER BY created_at DESC LIMIT 10
+SELECT id, created_at, updated_at, deleted_at, title, author, slug, content, description, draft, published_at, search_vector, scheduled_at FROM blog_posts
+WHERE draft = true AND scheduled_at IS NULL AND deleted_at IS NULL
+  AND (COALESCE(published_at, created_at), id) < ($1::timestamptz, $2::bigint)
+ORDER BY COALESCE(published_at, created_at) DESC, id DESC
+LIMIT $3
+`
+
+type GetDraftPostsParams struct {
+	CursorTime pgtype.Timestamptz `json:"cursor_time"`
+	CursorID   int64              `json:"cursor_id"`
+	PageSize   int32              `json:"page_size"`
+}
+
+func (q *Queries) GetDraftPosts(ctx context.Context, arg GetDraftPostsParams) ([]BlogPost, error) {
+	rows, err := q.db.Query(ctx, getDraftPosts, arg.CursorTime, arg.CursorID, arg.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []BlogPost
+	for rows.Next() {
+		var i BlogPost
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DeletedAt,
+			&i.Title,
+			&i.Author,
+			&i.Slug,
+			&i.Content,
+			&i.Description,
+			&i.Draft,
+			&i.PublishedAt,
+			&i.SearchVector,
+			&i.ScheduledAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getDraftPostsBefore = `-- name: GetDraftPostsBefore :many
+SELECT id, created_at, updated_at, deleted_at, title, author, slug, content, description, draft, published_at, search_vector, scheduled_at FROM blog_posts
+WHERE draft = true AND scheduled_at IS NULL AND deleted_at IS NULL
+  AND (COALESCE(published_at, created_at), id) > ($1::timestamptz, $2::bigint)
+ORDER BY COALESCE(published_at, created_at) ASC, id ASC
+LIMIT $3
 `
 
-func (q *Queries) GetDraftPosts(ctx context.Context) ([]BlogPost, error) {
-	rows, err := q.db.Query(ctx, getDraftPosts)
+type GetDraftPostsBeforeParams struct {
+	CursorTime pgtype.Timestamptz `json:"cursor_time"`
+	CursorID   int64              `json:"cursor_id"`
+	PageSize   int32              `json:"page_size"`
+}
+
+func (q *Queries) GetDraftPostsBefore(ctx context.Context, arg GetDraftPostsBeforeParams) ([]BlogPost, error) {
+	rows, err := q.db.Query(ctx, getDraftPostsBefore, arg.CursorTime, arg.CursorID, arg.PageSize)
 	if err != nil {
 		return nil, err
 	}
@@ -116,6 +262,50 @@ func (q *Queries) GetDraftPosts(ctx context.Context) ([]BlogPost, error) {
 			&i.Description,
 			&i.Draft,
 			&i.PublishedAt,
+			&i.SearchVector,
+			&i.ScheduledAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getDueScheduledPosts = `-- name: GetDueScheduledPosts :many
+SELECT id, created_at, updated_at, deleted_at, title, author, slug, content, description, draft, published_at, search_vector, scheduled_at FROM blog_posts
+WHERE draft = true AND scheduled_at <= NOW() AND deleted_at IS NULL
+ORDER BY scheduled_at ASC, id ASC
+LIMIT $1
+FOR UPDATE SKIP LOCKED
+`
+
+func (q *Queries) GetDueScheduledPosts(ctx context.Context, batchSize int32) ([]BlogPost, error) {
+	rows, err := q.db.Query(ctx, getDueScheduledPosts, batchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []BlogPost
+	for rows.Next() {
+		var i BlogPost
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DeletedAt,
+			&i.Title,
+			&i.Author,
+			&i.Slug,
+			&i.Content,
+			&i.Description,
+			&i.Draft,
+			&i.PublishedAt,
+			&i.SearchVector,
+			&i.ScheduledAt,
 		); err != nil {
 			return nil, err
 		}
@@ -128,7 +318,7 @@ func (q *Queries) GetDraftPosts(ctx context.Context) ([]BlogPost, error) {
 }
 
 const getPostByID = `-- name: GetPostByID :one
-SELECT id, created_at, updated_at, deleted_at, title, author, slug, content, description, draft, published_at FROM blog_posts WHERE id = $1 AND deleted_at IS NULL LIMIT 1
+SELECT id, created_at, updated_at, deleted_at, title, author, slug, content, description, draft, published_at, search_vector, scheduled_at FROM blog_posts WHERE id = $1 AND deleted_at IS NULL LIMIT 1
 `
 
 func (q *Queries) GetPostByID(ctx context.Context, id int64) (BlogPost, error) {
@@ -146,27 +336,142 @@ func (q *Queries) GetPostByID(ctx context.Context, id int64) (BlogPost, error) {
 		&i.Description,
 		&i.Draft,
 		&i.PublishedAt,
+		&i.SearchVector,
+		&i.ScheduledAt,
 	)
 	return i, err
 }
 
-const getPostBySlugAndDate = `-- name: GetPostBySlugAndDate :one
-SELECT id, created_at, updated_at, deleted_at, title, author, slug, content, description, draft, published_at FROM blog_posts
+const getPostIDByOldSlug = `-- name: GetPostIDByOldSlug :one
+SELECT blog_post_id FROM blog_post_slug_history
 WHERE slug = $1
-  AND published_at >= $2
-  AND published_at < $3
-  AND deleted_at IS NULL
+ORDER BY created_at DESC
 LIMIT 1
 `
 
-type GetPostBySlugAndDateParams struct {
-	Slug       string             `json:"slug"`
-	StartOfDay pgtype.Timestamptz `json:"start_of_day"`
-	EndOfDay   pgtype.Timestamptz `json:"end_of_day"`
+func (q *Queries) GetPostIDByOldSlug(ctx context.Context, slug string) (int64, error) {
+	row := q.db.QueryRow(ctx, getPostIDByOldSlug, slug)
+	var blog_post_id int64
+	err := row.Scan(&blog_post_id)
+	return blog_post_id, err
+}
+
+const getPostsByAuthor = `-- name: GetPostsByAuthor :many
+SELECT id, created_at, updated_at, deleted_at, title, author, slug, content, description, draft, published_at, search_vector, scheduled_at FROM blog_posts
+WHERE author = $1 AND draft = false AND deleted_at IS NULL
+  AND (published_at, id) < ($2::timestamptz, $3::bigint)
+ORDER BY published_at DESC, id DESC
+LIMIT $4
+`
+
+type GetPostsByAuthorParams struct {
+	Author     string             `json:"author"`
+	CursorTime pgtype.Timestamptz `json:"cursor_time"`
+	CursorID   int64              `json:"cursor_id"`
+	PageSize   int32              `json:"page_size"`
 }
 
-func (q *Queries) GetPostBySlugAndDate(ctx context.Context, arg GetPostBySlugAndDateParams) (BlogPost, error) {
-	row := q.db.QueryRow(ctx, getPostBySlugAndDate, arg.Slug, arg.StartOfDay, arg.EndOfDay)
+func (q *Queries) GetPostsByAuthor(ctx context.Context, arg GetPostsByAuthorParams) ([]BlogPost, error) {
+	rows, err := q.db.Query(ctx, getPostsByAuthor,
+		arg.Author,
+		arg.CursorTime,
+		arg.CursorID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []BlogPost
+	for rows.Next() {
+		var i BlogPost
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DeletedAt,
+			&i.Title,
+			&i.Author,
+			&i.Slug,
+			&i.Content,
+			&i.Description,
+			&i.Draft,
+			&i.PublishedAt,
+			&i.SearchVector,
+			&i.ScheduledAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPostsByAuthorBefore = `-- name: GetPostsByAuthorBefore :many
+SELECT id, created_at, updated_at, deleted_at, title, author, slug, content, description, draft, published_at, search_vector, scheduled_at FROM blog_posts
+WHERE author = $1 AND draft = false AND deleted_at IS NULL
+  AND (published_at, id) > ($2::timestamptz, $3::bigint)
+ORDER BY published_at ASC, id ASC
+LIMIT $4
+`
+
+type GetPostsByAuthorBeforeParams struct {
+	Author     string             `json:"author"`
+	CursorTime pgtype.Timestamptz `json:"cursor_time"`
+	CursorID   int64              `json:"cursor_id"`
+	PageSize   int32              `json:"page_size"`
+}
+
+func (q *Queries) GetPostsByAuthorBefore(ctx context.Context, arg GetPostsByAuthorBeforeParams) ([]BlogPost, error) {
+	rows, err := q.db.Query(ctx, getPostsByAuthorBefore,
+		arg.Author,
+		arg.CursorTime,
+		arg.CursorID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []BlogPost
+	for rows.Next() {
+		var i BlogPost
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DeletedAt,
+			&i.Title,
+			&i.Author,
+			&i.Slug,
+			&i.Content,
+			&i.Description,
+			&i.Draft,
+			&i.PublishedAt,
+			&i.SearchVector,
+			&i.ScheduledAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPublishedPostByID = `-- name: GetPublishedPostByID :one
+SELECT id, created_at, updated_at, deleted_at, title, author, slug, content, description, draft, published_at, search_vector, scheduled_at FROM blog_posts
+WHERE id = $1 AND draft = false AND deleted_at IS NULL
+LIMIT 1
+`
+
+func (q *Queries) GetPublishedPostByID(ctx context.Context, id int64) (BlogPost, error) {
+	row := q.db.QueryRow(ctx, getPublishedPostByID, id)
 	var i BlogPost
 	err := row.Scan(
 		&i.ID,
@@ -180,18 +485,55 @@ func (q *Queries) GetPostBySlugAndDate(ctx context.Context, arg GetPostBySlugAnd
 		&i.Description,
 		&i.Draft,
 		&i.PublishedAt,
+		&i.SearchVector,
+		&i.ScheduledAt,
 	)
 	return i, err
 }
 
-const getPostsByAuthor = `-- name: GetPostsByAuthor :many
-SELECT id, created_at, updated_at, deleted_at, title, author, slug, content, description, draft, published_at FROM blog_posts
-WHERE author = $1 AND draft = false AND deleted_at IS NULL
-ORDER BY created_at DESC
+const getPublishedPostBySlug = `-- name: GetPublishedPostBySlug :one
+SELECT id, created_at, updated_at, deleted_at, title, author, slug, content, description, draft, published_at, search_vector, scheduled_at FROM blog_posts
+WHERE slug = $1 AND draft = false AND deleted_at IS NULL
+LIMIT 1
+`
+
+func (q *Queries) GetPublishedPostBySlug(ctx context.Context, slug string) (BlogPost, error) {
+	row := q.db.QueryRow(ctx, getPublishedPostBySlug, slug)
+	var i BlogPost
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.Title,
+		&i.Author,
+		&i.Slug,
+		&i.Content,
+		&i.Description,
+		&i.Draft,
+		&i.PublishedAt,
+		&i.SearchVector,
+		&i.ScheduledAt,
+	)
+	return i, err
+}
+
+const getPublishedPosts = `-- name: GetPublishedPosts :many
+SELECT id, created_at, updated_at, deleted_at, title, author, slug, content, description, draft, published_at, search_vector, scheduled_at FROM blog_posts
+WHERE draft = false AND deleted_at IS NULL
+  AND (published_at, id) < ($1::timestamptz, $2::bigint)
+ORDER BY published_at DESC, id DESC
+LIMIT $3
 `
 
-func (q *Queries) GetPostsByAuthor(ctx context.Context, author string) ([]BlogPost, error) {
-	rows, err := q.db.Query(ctx, getPostsByAuthor, author)
+type GetPublishedPostsParams struct {
+	CursorTime pgtype.Timestamptz `json:"cursor_time"`
+	CursorID   int64              `json:"cursor_id"`
+	PageSize   int32              `json:"page_size"`
+}
+
+func (q *Queries) GetPublishedPosts(ctx context.Context, arg GetPublishedPostsParams) ([]BlogPost, error) {
+	rows, err := q.db.Query(ctx, getPublishedPosts, arg.CursorTime, arg.CursorID, arg.PageSize)
 	if err != nil {
 		return nil, err
 	}
@@ -211,6 +553,8 @@ func (q *Queries) GetPostsByAuthor(ctx context.Context, author string) ([]BlogPo
 			&i.Description,
 			&i.Draft,
 			&i.PublishedAt,
+			&i.SearchVector,
+			&i.ScheduledAt,
 		); err != nil {
 			return nil, err
 		}
@@ -222,14 +566,22 @@ func (q *Queries) GetPostsByAuthor(ctx context.Context, author string) ([]BlogPo
 	return items, nil
 }
 
-const getPublishedPosts = `-- name: GetPublishedPosts :many
-SELECT id, created_at, updated_at, deleted_at, title, author, slug, content, description, draft, published_at FROM blog_posts
+const getPublishedPostsBefore = `-- name: GetPublishedPostsBefore :many
+SELECT id, created_at, updated_at, deleted_at, title, author, slug, content, description, draft, published_at, search_vector, scheduled_at FROM blog_posts
 WHERE draft = false AND deleted_at IS NULL
-ORDER BY created_at DESC LIMIT 10
+  AND (published_at, id) > ($1::timestamptz, $2::bigint)
+ORDER BY published_at ASC, id ASC
+LIMIT $3
 `
 
-func (q *Queries) GetPublishedPosts(ctx context.Context) ([]BlogPost, error) {
-	rows, err := q.db.Query(ctx, getPublishedPosts)
+type GetPublishedPostsBeforeParams struct {
+	CursorTime pgtype.Timestamptz `json:"cursor_time"`
+	CursorID   int64              `json:"cursor_id"`
+	PageSize   int32              `json:"page_size"`
+}
+
+func (q *Queries) GetPublishedPostsBefore(ctx context.Context, arg GetPublishedPostsBeforeParams) ([]BlogPost, error) {
+	rows, err := q.db.Query(ctx, getPublishedPostsBefore, arg.CursorTime, arg.CursorID, arg.PageSize)
 	if err != nil {
 		return nil, err
 	}
@@ -249,6 +601,259 @@ func (q *Queries) GetPublishedPosts(ctx context.Context) ([]BlogPost, error) {
 			&i.Description,
 			&i.Draft,
 			&i.PublishedAt,
+			&i.SearchVector,
+			&i.ScheduledAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getScheduledPosts = `-- name: GetScheduledPosts :many
+SELECT id, created_at, updated_at, deleted_at, title, author, slug, content, description, draft, published_at, search_vector, scheduled_at FROM blog_posts
+WHERE draft = true AND scheduled_at IS NOT NULL AND deleted_at IS NULL
+ORDER BY scheduled_at ASC, id ASC
+`
+
+func (q *Queries) GetScheduledPosts(ctx context.Context) ([]BlogPost, error) {
+	rows, err := q.db.Query(ctx, getScheduledPosts)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []BlogPost
+	for rows.Next() {
+		var i BlogPost
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DeletedAt,
+			&i.Title,
+			&i.Author,
+			&i.Slug,
+			&i.Content,
+			&i.Description,
+			&i.Draft,
+			&i.PublishedAt,
+			&i.SearchVector,
+			&i.ScheduledAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getSitemapAuthors = `-- name: GetSitemapAuthors :many
+SELECT author, MAX(updated_at)::timestamptz AS last_modified FROM blog_posts
+WHERE draft = false AND deleted_at IS NULL
+GROUP BY author
+ORDER BY author
+`
+
+type GetSitemapAuthorsRow struct {
+	Author       string             `json:"author"`
+	LastModified pgtype.Timestamptz `json:"last_modified"`
+}
+
+func (q *Queries) GetSitemapAuthors(ctx context.Context) ([]GetSitemapAuthorsRow, error) {
+	rows, err := q.db.Query(ctx, getSitemapAuthors)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetSitemapAuthorsRow
+	for rows.Next() {
+		var i GetSitemapAuthorsRow
+		if err := rows.Scan(
+			&i.Author,
+			&i.LastModified,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getSitemapPosts = `-- name: GetSitemapPosts :many
+SELECT id, created_at, updated_at, slug, published_at FROM blog_posts
+WHERE draft = false AND deleted_at IS NULL
+ORDER BY published_at DESC
+`
+
+type GetSitemapPostsRow struct {
+	ID          int64              `json:"id"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt   pgtype.Timestamptz `json:"updated_at"`
+	Slug        string             `json:"slug"`
+	PublishedAt pgtype.Timestamptz `json:"published_at"`
+}
+
+func (q *Queries) GetSitemapPosts(ctx context.Context) ([]GetSitemapPostsRow, error) {
+	rows, err := q.db.Query(ctx, getSitemapPosts)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetSitemapPostsRow
+	for rows.Next() {
+		var i GetSitemapPostsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Slug,
+			&i.PublishedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const isPostIDDeleted = `-- name: IsPostIDDeleted :one
+SELECT EXISTS (
+    SELECT 1 FROM blog_posts WHERE id = $1 AND deleted_at IS NOT NULL
+)
+`
+
+func (q *Queries) IsPostIDDeleted(ctx context.Context, id int64) (bool, error) {
+	row := q.db.QueryRow(ctx, isPostIDDeleted, id)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const isPostSlugDeleted = `-- name: IsPostSlugDeleted :one
+SELECT EXISTS (
+    SELECT 1 FROM blog_posts WHERE slug = $1 AND deleted_at IS NOT NULL
+)
+`
+
+func (q *Queries) IsPostSlugDeleted(ctx context.Context, slug string) (bool, error) {
+	row := q.db.QueryRow(ctx, isPostSlugDeleted, slug)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const isSlugTaken = `-- name: IsSlugTaken :one
+SELECT EXISTS (
+    SELECT 1 FROM blog_posts WHERE slug = $1 AND id <> $2
+)
+`
+
+type IsSlugTakenParams struct {
+	Slug string `json:"slug"`
+	ID   int64  `json:"id"`
+}
+
+func (q *Queries) IsSlugTaken(ctx context.Context, arg IsSlugTakenParams) (bool, error) {
+	row := q.db.QueryRow(ctx, isSlugTaken, arg.Slug, arg.ID)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const publishScheduledPost = `-- name: PublishScheduledPost :one
+UPDATE blog_posts
+SET draft = false, published_at = scheduled_at, scheduled_at = NULL, updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, deleted_at, title, author, slug, content, description, draft, published_at, search_vector, scheduled_at
+`
+
+func (q *Queries) PublishScheduledPost(ctx context.Context, id int64) (BlogPost, error) {
+	row := q.db.QueryRow(ctx, publishScheduledPost, id)
+	var i BlogPost
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.Title,
+		&i.Author,
+		&i.Slug,
+		&i.Content,
+		&i.Description,
+		&i.Draft,
+		&i.PublishedAt,
+		&i.SearchVector,
+		&i.ScheduledAt,
+	)
+	return i, err
+}
+
+const searchPosts = `-- name: SearchPosts :many
+SELECT id, created_at, updated_at, title, author, slug, description, draft, published_at,
+    ts_rank(search_vector, query)::real AS rank,
+    ts_headline('english', content, query, 'MaxFragments=2, MaxWords=30, MinWords=10, StartSel=<mark>, StopSel=</mark>')::text AS snippet
+FROM blog_posts, websearch_to_tsquery('english', $1::text) query
+WHERE search_vector @@ query
+  AND deleted_at IS NULL
+  AND (draft = false OR $2::boolean)
+ORDER BY rank DESC, id DESC
+LIMIT $3
+`
+
+type SearchPostsParams struct {
+	Query         string `json:"query"`
+	IncludeDrafts bool   `json:"include_drafts"`
+	PageSize      int32  `json:"page_size"`
+}
+
+type SearchPostsRow struct {
+	ID          int64              `json:"id"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt   pgtype.Timestamptz `json:"updated_at"`
+	Title       string             `json:"title"`
+	Author      string             `json:"author"`
+	Slug        string             `json:"slug"`
+	Description string             `json:"description"`
+	Draft       bool               `json:"draft"`
+	PublishedAt pgtype.Timestamptz `json:"published_at"`
+	Rank        float32            `json:"rank"`
+	Snippet     string             `json:"snippet"`
+}
+
+func (q *Queries) SearchPosts(ctx context.Context, arg SearchPostsParams) ([]SearchPostsRow, error) {
+	rows, err := q.db.Query(ctx, searchPosts, arg.Query, arg.IncludeDrafts, arg.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchPostsRow
+	for rows.Next() {
+		var i SearchPostsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Author,
+			&i.Slug,
+			&i.Description,
+			&i.Draft,
+			&i.PublishedAt,
+			&i.Rank,
+			&i.Snippet,
 		); err != nil {
 			return nil, err
 		}
@@ -271,28 +876,32 @@ func (q *Queries) SoftDeletePost(ctx context.Context, id int64) error {
 
 const updatePost = `-- name: UpdatePost :one
 UPDATE blog_posts
-SET title = $1, content = $2, slug = $3,
-    draft = $4, published_at = $5, updated_at = NOW()
-WHERE id = $6 AND deleted_at IS NULL
-RETURNING id, created_at, updated_at, deleted_at, title, author, slug, content, description, draft, published_at
+SET title = $1, description = $2, content = $3, slug = $4,
+    draft = $5, published_at = $6, scheduled_at = $7, updated_at = NOW()
+WHERE id = $8 AND deleted_at IS NULL
+RETURNING id, created_at, updated_at, deleted_at, title, author, slug, content, description, draft, published_at, search_vector, scheduled_at
 `
 
 type UpdatePostParams struct {
 	Title       string             `json:"title"`
+	Description string             `json:"description"`
 	Content     string             `json:"content"`
 	Slug        string             `json:"slug"`
 	Draft       bool               `json:"draft"`
 	PublishedAt pgtype.Timestamptz `json:"published_at"`
+	ScheduledAt pgtype.Timestamptz `json:"scheduled_at"`
 	ID          int64              `json:"id"`
 }
 
 func (q *Queries) UpdatePost(ctx context.Context, arg UpdatePostParams) (BlogPost, error) {
 	row := q.db.QueryRow(ctx, updatePost,
 		arg.Title,
+		arg.Description,
 		arg.Content,
 		arg.Slug,
 		arg.Draft,
 		arg.PublishedAt,
+		arg.ScheduledAt,
 		arg.ID,
 	)
 	var i BlogPost
@@ -308,6 +917,8 @@ func (q *Queries) UpdatePost(ctx context.Context, arg UpdatePostParams) (BlogPos
 		&i.Description,
 		&i.Draft,
 		&i.PublishedAt,
+		&i.SearchVector,
+		&i.ScheduledAt,
 	)
 	return i, err
 }
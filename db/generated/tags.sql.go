@@ -7,6 +7,8 @@ package db
 
 import (
 	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 const addTagToPost = `-- name: AddTagToPost :exec
@@ -69,18 +71,91 @@ func (q *Queries) GetPostIDsByTag(ctx context.Context, name string) ([]int64, er
 }
 
 const getPublishedPostsByTag = `-- name: GetPublishedPostsByTag :many
-SELECT id, created_at, updated_at, deleted_at, title, author, slug, content, description, draft, published_at FROM blog_posts
+SELECT id, created_at, updated_at, deleted_at, title, author, slug, content, description, draft, published_at, search_vector, scheduled_at FROM blog_posts
+WHERE id IN (
+    SELECT bpt.blog_post_id FROM blog_post_tags bpt
+    JOIN tags t ON t.id = bpt.tag_id
+    WHERE t.name = $1 AND t.deleted_at IS NULL
+)
+AND draft = false AND deleted_at IS NULL
+AND (published_at, id) < ($2::timestamptz, $3::bigint)
+ORDER BY published_at DESC, id DESC
+LIMIT $4
+`
+
+type GetPublishedPostsByTagParams struct {
+	Name       string             `json:"name"`
+	CursorTime pgtype.Timestamptz `json:"cursor_time"`
+	CursorID   int64              `json:"cursor_id"`
+	PageSize   int32              `json:"page_size"`
+}
+
+func (q *Queries) GetPublishedPostsByTag(ctx context.Context, arg GetPublishedPostsByTagParams) ([]BlogPost, error) {
+	rows, err := q.db.Query(ctx, getPublishedPostsByTag,
+		arg.Name,
+		arg.CursorTime,
+		arg.CursorID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []BlogPost
+	for rows.Next() {
+		var i BlogPost
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DeletedAt,
+			&i.Title,
+			&i.Author,
+			&i.Slug,
+			&i.Content,
+			&i.Description,
+			&i.Draft,
+			&i.PublishedAt,
+			&i.SearchVector,
+			&i.ScheduledAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPublishedPostsByTagBefore = `-- name: GetPublishedPostsByTagBefore :many
+SELECT id, created_at, updated_at, deleted_at, title, author, slug, content, description, draft, published_at, search_vector, scheduled_at FROM blog_posts
 WHERE id IN (
     SELECT bpt.blog_post_id FROM blog_post_tags bpt
     JOIN tags t ON t.id = bpt.tag_id
     WHERE t.name = $1 AND t.deleted_at IS NULL
 )
 AND draft = false AND deleted_at IS NULL
-ORDER BY created_at DESC
+AND (published_at, id) > ($2::timestamptz, $3::bigint)
+ORDER BY published_at ASC, id ASC
+LIMIT $4
 `
 
-func (q *Queries) GetPublishedPostsByTag(ctx context.Context, name string) ([]BlogPost, error) {
-	rows, err := q.db.Query(ctx, getPublishedPostsByTag, name)
+type GetPublishedPostsByTagBeforeParams struct {
+	Name       string             `json:"name"`
+	CursorTime pgtype.Timestamptz `json:"cursor_time"`
+	CursorID   int64              `json:"cursor_id"`
+	PageSize   int32              `json:"page_size"`
+}
+
+func (q *Queries) GetPublishedPostsByTagBefore(ctx context.Context, arg GetPublishedPostsByTagBeforeParams) ([]BlogPost, error) {
+	rows, err := q.db.Query(ctx, getPublishedPostsByTagBefore,
+		arg.Name,
+		arg.CursorTime,
+		arg.CursorID,
+		arg.PageSize,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -100,6 +175,81 @@ func (q *Queries) GetPublishedPostsByTag(ctx context.Context, name string) ([]Bl
 			&i.Description,
 			&i.Draft,
 			&i.PublishedAt,
+			&i.SearchVector,
+			&i.ScheduledAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPublishedTags = `-- name: GetPublishedTags :many
+SELECT id, created_at, updated_at, deleted_at, name FROM tags t
+WHERE t.deleted_at IS NULL AND EXISTS (
+    SELECT 1 FROM blog_post_tags bpt
+    JOIN blog_posts bp ON bp.id = bpt.blog_post_id
+    WHERE bpt.tag_id = t.id AND bp.draft = false AND bp.deleted_at IS NULL
+)
+ORDER BY t.name ASC
+`
+
+func (q *Queries) GetPublishedTags(ctx context.Context) ([]Tag, error) {
+	rows, err := q.db.Query(ctx, getPublishedTags)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Tag
+	for rows.Next() {
+		var i Tag
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DeletedAt,
+			&i.Name,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getSitemapTags = `-- name: GetSitemapTags :many
+SELECT t.name, MAX(bp.updated_at)::timestamptz AS last_modified FROM tags t
+JOIN blog_post_tags bpt ON bpt.tag_id = t.id
+JOIN blog_posts bp ON bp.id = bpt.blog_post_id
+WHERE t.deleted_at IS NULL AND bp.draft = false AND bp.deleted_at IS NULL
+GROUP BY t.name
+ORDER BY t.name
+`
+
+type GetSitemapTagsRow struct {
+	Name         string             `json:"name"`
+	LastModified pgtype.Timestamptz `json:"last_modified"`
+}
+
+func (q *Queries) GetSitemapTags(ctx context.Context) ([]GetSitemapTagsRow, error) {
+	rows, err := q.db.Query(ctx, getSitemapTags)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetSitemapTagsRow
+	for rows.Next() {
+		var i GetSitemapTagsRow
+		if err := rows.Scan(
+			&i.Name,
+			&i.LastModified,
 		); err != nil {
 			return nil, err
 		}
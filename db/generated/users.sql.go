@@ -9,8 +9,72 @@ import (
 	"context"
 )
 
+const createUser = `-- name: CreateUser :one
+INSERT INTO users (username, email, password)
+VALUES ($1, $2, $3)
+RETURNING id, created_at, updated_at, deleted_at, username, password, admin, theme, email, notify_comments, notify_replies, email_verified_at, session_version, totp_secret, totp_enabled_at, totp_last_step
+`
+
+type CreateUserParams struct {
+	Username string  `json:"username"`
+	Email    *string `json:"email"`
+	Password string  `json:"password"`
+}
+
+func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
+	row := q.db.QueryRow(ctx, createUser, arg.Username, arg.Email, arg.Password)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.Username,
+		&i.Password,
+		&i.Admin,
+		&i.Theme,
+		&i.Email,
+		&i.NotifyComments,
+		&i.NotifyReplies,
+		&i.EmailVerifiedAt,
+		&i.SessionVersion,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+	)
+	return i, err
+}
+
+const getUserByEmail = `-- name: GetUserByEmail :one
+SELECT id, created_at, updated_at, deleted_at, username, password, admin, theme, email, notify_comments, notify_replies, email_verified_at, session_version, totp_secret, totp_enabled_at, totp_last_step FROM users WHERE LOWER(email) = LOWER($1::text) AND deleted_at IS NULL LIMIT 1
+`
+
+func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error) {
+	row := q.db.QueryRow(ctx, getUserByEmail, email)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.Username,
+		&i.Password,
+		&i.Admin,
+		&i.Theme,
+		&i.Email,
+		&i.NotifyComments,
+		&i.NotifyReplies,
+		&i.EmailVerifiedAt,
+		&i.SessionVersion,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+	)
+	return i, err
+}
+
 const getUserByID = `-- name: GetUserByID :one
-SELECT id, created_at, updated_at, deleted_at, username, password, admin, theme FROM users WHERE id = $1 AND deleted_at IS NULL LIMIT 1
+SELECT id, created_at, updated_at, deleted_at, username, password, admin, theme, email, notify_comments, notify_replies, email_verified_at, session_version, totp_secret, totp_enabled_at, totp_last_step FROM users WHERE id = $1 AND deleted_at IS NULL LIMIT 1
 `
 
 func (q *Queries) GetUserByID(ctx context.Context, id int64) (User, error) {
@@ -25,12 +89,20 @@ func (q *Queries) GetUserByID(ctx context.Context, id int64) (User, error) {
 		&i.Password,
 		&i.Admin,
 		&i.Theme,
+		&i.Email,
+		&i.NotifyComments,
+		&i.NotifyReplies,
+		&i.EmailVerifiedAt,
+		&i.SessionVersion,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
 	)
 	return i, err
 }
 
 const getUserByUsername = `-- name: GetUserByUsername :one
-SELECT id, created_at, updated_at, deleted_at, username, password, admin, theme FROM users WHERE username = $1 AND deleted_at IS NULL LIMIT 1
+SELECT id, created_at, updated_at, deleted_at, username, password, admin, theme, email, notify_comments, notify_replies, email_verified_at, session_version, totp_secret, totp_enabled_at, totp_last_step FROM users WHERE username = $1 AND deleted_at IS NULL LIMIT 1
 `
 
 func (q *Queries) GetUserByUsername(ctx context.Context, username string) (User, error) {
@@ -45,12 +117,103 @@ func (q *Queries) GetUserByUsername(ctx context.Context, username string) (User,
 		&i.Password,
 		&i.Admin,
 		&i.Theme,
+		&i.Email,
+		&i.NotifyComments,
+		&i.NotifyReplies,
+		&i.EmailVerifiedAt,
+		&i.SessionVersion,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
 	)
 	return i, err
 }
 
-const updateUserPassword = `-- name: UpdateUserPassword :exec
-UPDATE users SET password = $1, updated_at = NOW() WHERE id = $2
+const getUserSessionVersion = `-- name: GetUserSessionVersion :one
+SELECT session_version FROM users WHERE id = $1 AND deleted_at IS NULL
+`
+
+func (q *Queries) GetUserSessionVersion(ctx context.Context, id int64) (int32, error) {
+	row := q.db.QueryRow(ctx, getUserSessionVersion, id)
+	var session_version int32
+	err := row.Scan(&session_version)
+	return session_version, err
+}
+
+const isEmailTaken = `-- name: IsEmailTaken :one
+SELECT EXISTS (
+    SELECT 1 FROM users WHERE LOWER(email) = LOWER($1::text)
+)
+`
+
+func (q *Queries) IsEmailTaken(ctx context.Context, email string) (bool, error) {
+	row := q.db.QueryRow(ctx, isEmailTaken, email)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const isUsernameTaken = `-- name: IsUsernameTaken :one
+SELECT EXISTS (
+    SELECT 1 FROM users WHERE LOWER(username) = LOWER($1::text)
+)
+`
+
+func (q *Queries) IsUsernameTaken(ctx context.Context, username string) (bool, error) {
+	row := q.db.QueryRow(ctx, isUsernameTaken, username)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const unsubscribeUserComments = `-- name: UnsubscribeUserComments :exec
+UPDATE users SET notify_comments = FALSE, updated_at = NOW() WHERE id = $1
+`
+
+func (q *Queries) UnsubscribeUserComments(ctx context.Context, id int64) error {
+	_, err := q.db.Exec(ctx, unsubscribeUserComments, id)
+	return err
+}
+
+const unsubscribeUserReplies = `-- name: UnsubscribeUserReplies :exec
+UPDATE users SET notify_replies = FALSE, updated_at = NOW() WHERE id = $1
+`
+
+func (q *Queries) UnsubscribeUserReplies(ctx context.Context, id int64) error {
+	_, err := q.db.Exec(ctx, unsubscribeUserReplies, id)
+	return err
+}
+
+const updateUserNotifications = `-- name: UpdateUserNotifications :exec
+UPDATE users
+SET email = $1, notify_comments = $2, notify_replies = $3,
+    email_verified_at = CASE WHEN LOWER(email) IS NOT DISTINCT FROM LOWER($1) THEN email_verified_at END,
+    updated_at = NOW()
+WHERE id = $4
+`
+
+type UpdateUserNotificationsParams struct {
+	Email          *string `json:"email"`
+	NotifyComments bool    `json:"notify_comments"`
+	NotifyReplies  bool    `json:"notify_replies"`
+	ID             int64   `json:"id"`
+}
+
+// A new address has to be verified again.
+func (q *Queries) UpdateUserNotifications(ctx context.Context, arg UpdateUserNotificationsParams) error {
+	_, err := q.db.Exec(ctx, updateUserNotifications,
+		arg.Email,
+		arg.NotifyComments,
+		arg.NotifyReplies,
+		arg.ID,
+	)
+	return err
+}
+
+const updateUserPassword = `-- name: UpdateUserPassword :one
+UPDATE users SET password = $1, session_version = session_version + 1, updated_at = NOW()
+WHERE id = $2
+RETURNING session_version
 `
 
 type UpdateUserPasswordParams struct {
@@ -58,9 +221,13 @@ type UpdateUserPasswordParams struct {
 	ID       int64  `json:"id"`
 }
 
-func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) error {
-	_, err := q.db.Exec(ctx, updateUserPassword, arg.Password, arg.ID)
-	return err
+// Signs the user out everywhere by bumping the session version, and returns
+// the new one.
+func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) (int32, error) {
+	row := q.db.QueryRow(ctx, updateUserPassword, arg.Password, arg.ID)
+	var session_version int32
+	err := row.Scan(&session_version)
+	return session_version, err
 }
 
 const updateUserUsername = `-- name: UpdateUserUsername :exec
@@ -76,3 +243,23 @@ func (q *Queries) UpdateUserUsername(ctx context.Context, arg UpdateUserUsername
 	_, err := q.db.Exec(ctx, updateUserUsername, arg.Username, arg.ID)
 	return err
 }
+
+const verifyUserEmail = `-- name: VerifyUserEmail :execrows
+UPDATE users SET email_verified_at = NOW(), updated_at = NOW()
+WHERE id = $1 AND LOWER(email) = LOWER($2::text) AND email_verified_at IS NULL
+`
+
+type VerifyUserEmailParams struct {
+	ID    int64  `json:"id"`
+	Email string `json:"email"`
+}
+
+// Only verifies the address the link was sent to, in case it has changed
+// since.
+func (q *Queries) VerifyUserEmail(ctx context.Context, arg VerifyUserEmailParams) (int64, error) {
+	result, err := q.db.Exec(ctx, verifyUserEmail, arg.ID, arg.Email)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
@@ -0,0 +1,12 @@
+-- +goose Up
+CREATE INDEX IF NOT EXISTS blog_posts_published_keyset_idx
+    ON blog_posts (published_at DESC, id DESC)
+    WHERE draft = false AND deleted_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS blog_posts_admin_keyset_idx
+    ON blog_posts ((COALESCE(published_at, created_at)) DESC, id DESC)
+    WHERE deleted_at IS NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS blog_posts_admin_keyset_idx;
+DROP INDEX IF EXISTS blog_posts_published_keyset_idx;
\ No newline at end of file
@@ -0,0 +1,12 @@
+-- +goose Up
+ALTER TABLE blog_posts ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+    setweight(to_tsvector('english', coalesce(description, '')), 'B') ||
+    setweight(to_tsvector('english', coalesce(content, '')), 'C')
+) STORED;
+
+CREATE INDEX IF NOT EXISTS blog_posts_search_idx ON blog_posts USING GIN (search_vector);
+
+-- +goose Down
+DROP INDEX IF EXISTS blog_posts_search_idx;
+ALTER TABLE blog_posts DROP COLUMN IF EXISTS search_vector;
\ No newline at end of file
@@ -0,0 +1,17 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS blog_post_revisions (
+    id           BIGSERIAL PRIMARY KEY,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    blog_post_id BIGINT NOT NULL REFERENCES blog_posts(id) ON DELETE CASCADE,
+    author       TEXT NOT NULL,
+    title        TEXT NOT NULL,
+    content      TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS blog_post_revisions_post_idx ON blog_post_revisions (blog_post_id, created_at DESC);
+
+INSERT INTO blog_post_revisions (created_at, blog_post_id, author, title, content)
+SELECT updated_at, id, author, title, content FROM blog_posts;
+
+-- +goose Down
+DROP TABLE IF EXISTS blog_post_revisions;
\ No newline at end of file
@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE blog_posts ADD COLUMN IF NOT EXISTS scheduled_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS blog_posts_scheduled_idx ON blog_posts (scheduled_at)
+    WHERE draft = true AND scheduled_at IS NOT NULL AND deleted_at IS NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS blog_posts_scheduled_idx;
+ALTER TABLE blog_posts DROP COLUMN IF EXISTS scheduled_at;
\ No newline at end of file
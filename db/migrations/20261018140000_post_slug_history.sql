@@ -0,0 +1,12 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS blog_post_slug_history (
+    id           BIGSERIAL PRIMARY KEY,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    blog_post_id BIGINT NOT NULL REFERENCES blog_posts(id) ON DELETE CASCADE,
+    slug         VARCHAR(100) NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS blog_post_slug_history_slug_idx ON blog_post_slug_history (slug, created_at DESC);
+
+-- +goose Down
+DROP TABLE IF EXISTS blog_post_slug_history;
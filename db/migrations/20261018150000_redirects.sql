@@ -0,0 +1,13 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS redirects (
+    id          BIGSERIAL PRIMARY KEY,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    source_path TEXT UNIQUE NOT NULL,
+    target_path TEXT NOT NULL,
+    status_code INTEGER NOT NULL DEFAULT 301 CHECK (status_code IN (301, 302, 307, 308)),
+    hits        BIGINT NOT NULL DEFAULT 0,
+    last_hit_at TIMESTAMPTZ
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS redirects;
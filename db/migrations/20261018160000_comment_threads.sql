@@ -0,0 +1,8 @@
+-- +goose Up
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS parent_id BIGINT REFERENCES comments(id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS comments_post_idx ON comments (blog_post_id, created_at);
+
+-- +goose Down
+DROP INDEX IF EXISTS comments_post_idx;
+ALTER TABLE comments DROP COLUMN IF EXISTS parent_id;
@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'approved'
+    CHECK (status IN ('pending', 'approved', 'spam'));
+
+CREATE INDEX IF NOT EXISTS comments_status_idx ON comments (status, created_at);
+
+-- +goose Down
+DROP INDEX IF EXISTS comments_status_idx;
+ALTER TABLE comments DROP COLUMN IF EXISTS status;
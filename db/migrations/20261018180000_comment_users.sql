@@ -0,0 +1,12 @@
+-- +goose Up
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS user_id BIGINT REFERENCES users(id) ON DELETE SET NULL;
+
+-- Older comments are left without a user. They only kept the name typed
+-- into the form, which anyone could have typed, so matching it to an account
+-- would hand the comment to whoever owns that name.
+
+CREATE INDEX IF NOT EXISTS comments_user_idx ON comments (user_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS comments_user_idx;
+ALTER TABLE comments DROP COLUMN IF EXISTS user_id;
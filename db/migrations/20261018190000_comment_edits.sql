@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS edited_at TIMESTAMPTZ;
+
+-- +goose Down
+ALTER TABLE comments DROP COLUMN IF EXISTS edited_at;
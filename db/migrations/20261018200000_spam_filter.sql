@@ -0,0 +1,25 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS spam_tokens (
+    token      TEXT PRIMARY KEY,
+    ham_count  BIGINT NOT NULL DEFAULT 0,
+    spam_count BIGINT NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS spam_blocklist (
+    id         BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    kind       TEXT NOT NULL CHECK (kind IN ('word', 'domain')),
+    value      TEXT NOT NULL,
+    UNIQUE (kind, value)
+);
+
+-- spam_reason is why the filter held a comment. trained_as is what the
+-- classifier last learnt the comment as, so a changed decision can be undone.
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS spam_reason TEXT;
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS trained_as TEXT CHECK (trained_as IN ('ham', 'spam'));
+
+-- +goose Down
+ALTER TABLE comments DROP COLUMN IF EXISTS trained_as;
+ALTER TABLE comments DROP COLUMN IF EXISTS spam_reason;
+DROP TABLE IF EXISTS spam_blocklist;
+DROP TABLE IF EXISTS spam_tokens;
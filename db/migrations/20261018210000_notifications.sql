@@ -0,0 +1,26 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email TEXT;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS notify_comments BOOLEAN NOT NULL DEFAULT TRUE;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS notify_replies BOOLEAN NOT NULL DEFAULT TRUE;
+CREATE UNIQUE INDEX IF NOT EXISTS users_email_key ON users (LOWER(email));
+
+-- notifications queues emails about new comments until the notifier sends
+-- them, alone or gathered into a digest.
+CREATE TABLE IF NOT EXISTS notifications (
+    id         BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    user_id    BIGINT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    comment_id BIGINT NOT NULL REFERENCES comments (id) ON DELETE CASCADE,
+    kind       TEXT NOT NULL CHECK (kind IN ('comment', 'reply')),
+    sent_at    TIMESTAMPTZ,
+    UNIQUE (user_id, comment_id)
+);
+
+CREATE INDEX IF NOT EXISTS notifications_unsent_idx ON notifications (user_id, created_at) WHERE sent_at IS NULL;
+
+-- +goose Down
+DROP TABLE IF EXISTS notifications;
+DROP INDEX IF EXISTS users_email_key;
+ALTER TABLE users DROP COLUMN IF EXISTS notify_replies;
+ALTER TABLE users DROP COLUMN IF EXISTS notify_comments;
+ALTER TABLE users DROP COLUMN IF EXISTS email;
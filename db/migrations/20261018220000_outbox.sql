@@ -0,0 +1,23 @@
+-- +goose Up
+-- outbox holds every outgoing email until a worker has delivered it, so a
+-- mail server hiccup never loses a message.
+CREATE TABLE IF NOT EXISTS outbox (
+    id              BIGSERIAL PRIMARY KEY,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    sender          TEXT NOT NULL,
+    recipients      TEXT[] NOT NULL,
+    subject         TEXT NOT NULL,
+    message         BYTEA NOT NULL,
+    status          TEXT NOT NULL DEFAULT 'queued' CHECK (status IN ('queued', 'sent', 'failed')),
+    attempts        INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_error      TEXT,
+    sent_at         TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS outbox_due_idx ON outbox (next_attempt_at) WHERE status = 'queued';
+CREATE INDEX IF NOT EXISTS outbox_status_idx ON outbox (status, created_at);
+
+-- +goose Down
+DROP TABLE IF EXISTS outbox;
@@ -0,0 +1,43 @@
+-- +goose Up
+-- subscribers are readers who get new posts by email. A row is added when
+-- someone asks to subscribe, and confirmed_at is set once they follow the
+-- link mailed to them.
+CREATE TABLE IF NOT EXISTS subscribers (
+    id              BIGSERIAL PRIMARY KEY,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    email           TEXT NOT NULL,
+    all_posts       BOOLEAN NOT NULL DEFAULT TRUE,
+    confirmed_at    TIMESTAMPTZ,
+    unsubscribed_at TIMESTAMPTZ,
+    bounces         INTEGER NOT NULL DEFAULT 0,
+    last_bounce_at  TIMESTAMPTZ,
+    last_bounce     TEXT
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS subscribers_email_key ON subscribers (LOWER(email));
+
+-- subscriber_tags are the tags a subscriber without all_posts hears about.
+CREATE TABLE IF NOT EXISTS subscriber_tags (
+    subscriber_id BIGINT NOT NULL REFERENCES subscribers (id) ON DELETE CASCADE,
+    tag_id        BIGINT NOT NULL REFERENCES tags (id) ON DELETE CASCADE,
+    PRIMARY KEY (subscriber_id, tag_id)
+);
+
+-- newsletter_issues has a row for every post sent to subscribers, so a post
+-- is only sent the first time it is published. Subscribers are worked
+-- through in id order, last_subscriber_id being the last one done.
+CREATE TABLE IF NOT EXISTS newsletter_issues (
+    blog_post_id       BIGINT PRIMARY KEY REFERENCES blog_posts (id) ON DELETE CASCADE,
+    created_at         TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_subscriber_id BIGINT NOT NULL DEFAULT 0,
+    recipients         INTEGER NOT NULL DEFAULT 0,
+    sent_at            TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS newsletter_issues_unsent_idx ON newsletter_issues (created_at) WHERE sent_at IS NULL;
+
+-- +goose Down
+DROP TABLE IF EXISTS newsletter_issues;
+DROP TABLE IF EXISTS subscriber_tags;
+DROP TABLE IF EXISTS subscribers;
@@ -0,0 +1,24 @@
+-- +goose Up
+-- Accounts made by hand before registration existed count as verified,
+-- with or without an address. Changing the address unverifies it.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMPTZ;
+UPDATE users SET email_verified_at = NOW() WHERE email_verified_at IS NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS users_username_lower_key ON users (LOWER(username));
+
+-- invites let someone register while registration is invite-only. Each
+-- code works once.
+CREATE TABLE IF NOT EXISTS invites (
+    id         BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    code       TEXT NOT NULL UNIQUE,
+    note       TEXT NOT NULL DEFAULT '',
+    created_by BIGINT REFERENCES users (id) ON DELETE SET NULL,
+    used_at    TIMESTAMPTZ,
+    used_by    BIGINT REFERENCES users (id) ON DELETE SET NULL
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS invites;
+DROP INDEX IF EXISTS users_username_lower_key;
+ALTER TABLE users DROP COLUMN IF EXISTS email_verified_at;
@@ -1,9 +1,61 @@
 -- name: CreateComment :one
-INSERT INTO comments (blog_post_id, author, comment)
-VALUES (@blog_post_id, @author, @comment)
+INSERT INTO comments (blog_post_id, parent_id, user_id, author, comment, status, spam_reason)
+VALUES (@blog_post_id, sqlc.narg(parent_id), sqlc.narg(user_id), @author, @comment, @status, sqlc.narg(spam_reason))
 RETURNING *;
 
+-- name: GetCommentByID :one
+SELECT comments.*, users.username AS username
+FROM comments
+LEFT JOIN users ON users.id = comments.user_id
+WHERE comments.id = @id AND comments.status = 'approved' AND comments.deleted_at IS NULL
+LIMIT 1;
+
 -- name: GetCommentsByPostID :many
+-- Deleted comments are included so replies to them keep their place in the
+-- thread.
+SELECT comments.*, users.username AS username
+FROM comments
+LEFT JOIN users ON users.id = comments.user_id
+WHERE comments.blog_post_id = @blog_post_id AND comments.status = 'approved'
+ORDER BY comments.created_at ASC, comments.id ASC;
+
+-- name: GetOwnCommentByID :one
 SELECT * FROM comments
-WHERE blog_post_id = @blog_post_id AND deleted_at IS NULL
-ORDER BY created_at DESC;
\ No newline at end of file
+WHERE id = @id AND blog_post_id = @blog_post_id AND deleted_at IS NULL
+LIMIT 1;
+
+-- name: UpdateCommentText :exec
+-- An edit can send the comment back to moderation, so its status is set
+-- along with the text.
+UPDATE comments
+SET comment = @comment, status = @status, spam_reason = sqlc.narg(spam_reason), edited_at = NOW(), updated_at = NOW()
+WHERE id = @id;
+
+-- name: SoftDeleteComment :exec
+UPDATE comments SET deleted_at = NOW(), updated_at = NOW() WHERE id = @id;
+
+-- name: SoftDeleteComments :exec
+UPDATE comments SET deleted_at = NOW(), updated_at = NOW() WHERE id = ANY(@ids::bigint[]);
+
+-- name: CountApprovedCommentsByUser :one
+SELECT COUNT(*) FROM comments
+WHERE user_id = @user_id AND status = 'approved';
+
+-- name: GetCommentsByStatus :many
+SELECT comments.*, users.username AS username, blog_posts.title AS post_title
+FROM comments
+JOIN blog_posts ON blog_posts.id = comments.blog_post_id
+LEFT JOIN users ON users.id = comments.user_id
+WHERE comments.status = @status AND comments.deleted_at IS NULL
+ORDER BY comments.created_at ASC, comments.id ASC
+LIMIT @page_size;
+
+-- name: CountCommentsByStatus :many
+SELECT status, COUNT(*) AS count FROM comments
+WHERE deleted_at IS NULL
+GROUP BY status;
+
+-- name: SetCommentsStatus :exec
+UPDATE comments
+SET status = @status, updated_at = NOW()
+WHERE id = ANY(@ids::bigint[]);
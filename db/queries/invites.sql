@@ -0,0 +1,26 @@
+-- name: CreateInvite :one
+INSERT INTO invites (code, note, created_by)
+VALUES (@code, @note, @created_by)
+RETURNING *;
+
+-- name: GetInvites :many
+SELECT * FROM invites
+ORDER BY used_at IS NOT NULL, created_at DESC
+LIMIT @page_size::int;
+
+-- name: ClaimInvite :one
+SELECT * FROM invites
+WHERE code = @code AND used_at IS NULL
+LIMIT 1
+FOR UPDATE;
+
+-- name: IsInviteValid :one
+SELECT EXISTS (
+    SELECT 1 FROM invites WHERE code = @code AND used_at IS NULL
+);
+
+-- name: UseInvite :exec
+UPDATE invites SET used_at = NOW(), used_by = @used_by WHERE id = @id;
+
+-- name: DeleteInvite :exec
+DELETE FROM invites WHERE id = @id AND used_at IS NULL;
@@ -0,0 +1,100 @@
+-- name: CreateSubscriber :exec
+-- Adds an unconfirmed subscriber, unless the address is already known.
+INSERT INTO subscribers (email) VALUES (@email)
+ON CONFLICT ((LOWER(email))) DO NOTHING;
+
+-- name: ConfirmSubscriber :one
+-- Confirming again after unsubscribing or bouncing starts afresh, since the
+-- address evidently works.
+INSERT INTO subscribers (email, all_posts, confirmed_at) VALUES (@email, @all_posts, NOW())
+ON CONFLICT ((LOWER(email))) DO UPDATE
+SET all_posts = EXCLUDED.all_posts, confirmed_at = COALESCE(subscribers.confirmed_at, NOW()),
+    unsubscribed_at = NULL, bounces = 0, updated_at = NOW()
+RETURNING id;
+
+-- name: ClearSubscriberTags :exec
+DELETE FROM subscriber_tags WHERE subscriber_id = @subscriber_id;
+
+-- name: AddSubscriberTags :exec
+INSERT INTO subscriber_tags (subscriber_id, tag_id)
+SELECT @subscriber_id, t.id FROM tags t
+WHERE t.id = ANY(@tag_ids::bigint[]) AND t.deleted_at IS NULL
+ON CONFLICT DO NOTHING;
+
+-- name: UnsubscribeSubscriber :exec
+UPDATE subscribers SET unsubscribed_at = NOW(), updated_at = NOW()
+WHERE id = @id AND unsubscribed_at IS NULL;
+
+-- name: RecordSubscriberBounce :exec
+-- Counts a permanent delivery failure against whichever subscribers the
+-- mail was for.
+UPDATE subscribers
+SET bounces = bounces + 1, last_bounce_at = NOW(), last_bounce = @last_bounce, updated_at = NOW()
+WHERE LOWER(email) IN (SELECT LOWER(e) FROM UNNEST(@emails::text[]) e);
+
+-- name: ResetSubscriberBounces :exec
+UPDATE subscribers SET bounces = 0, last_bounce = NULL, updated_at = NOW()
+WHERE id = @id;
+
+-- name: QueueNewsletterIssue :exec
+INSERT INTO newsletter_issues (blog_post_id) VALUES (@blog_post_id)
+ON CONFLICT DO NOTHING;
+
+-- name: GetUnsentNewsletterIssue :one
+SELECT * FROM newsletter_issues
+WHERE sent_at IS NULL
+ORDER BY created_at ASC
+LIMIT 1
+FOR UPDATE SKIP LOCKED;
+
+-- name: GetNewsletterRecipients :many
+-- The next batch of active subscribers after after_id who want the post:
+-- everyone on all posts, and those following one of its tags.
+SELECT s.id, s.email FROM subscribers s
+WHERE s.confirmed_at IS NOT NULL AND s.unsubscribed_at IS NULL
+  AND s.bounces < @max_bounces::int
+  AND s.id > @after_id::bigint
+  AND (s.all_posts OR EXISTS (
+      SELECT 1 FROM subscriber_tags st
+      JOIN blog_post_tags bpt ON bpt.tag_id = st.tag_id
+      WHERE st.subscriber_id = s.id AND bpt.blog_post_id = @post_id::bigint
+  ))
+ORDER BY s.id ASC
+LIMIT @batch_size::int;
+
+-- name: UpdateNewsletterIssue :exec
+UPDATE newsletter_issues
+SET last_subscriber_id = @last_subscriber_id, recipients = recipients + @sent::int,
+    sent_at = CASE WHEN (@done::boolean) THEN NOW() END
+WHERE blog_post_id = @blog_post_id;
+
+-- name: CountSubscribers :one
+SELECT
+    COUNT(*) FILTER (WHERE confirmed_at IS NOT NULL AND unsubscribed_at IS NULL AND bounces < @max_bounces::int) AS active,
+    COUNT(*) FILTER (WHERE confirmed_at IS NOT NULL AND unsubscribed_at IS NULL AND bounces < @max_bounces::int AND all_posts) AS all_posts,
+    COUNT(*) FILTER (WHERE confirmed_at IS NULL) AS pending,
+    COUNT(*) FILTER (WHERE unsubscribed_at IS NOT NULL) AS unsubscribed,
+    COUNT(*) FILTER (WHERE unsubscribed_at IS NULL AND bounces >= @max_bounces::int) AS bounced
+FROM subscribers;
+
+-- name: CountSubscribersByTag :many
+SELECT t.name, COUNT(*) AS count FROM subscriber_tags st
+JOIN subscribers s ON s.id = st.subscriber_id
+JOIN tags t ON t.id = st.tag_id
+WHERE s.confirmed_at IS NOT NULL AND s.unsubscribed_at IS NULL
+  AND NOT s.all_posts AND t.deleted_at IS NULL
+  AND s.bounces < @max_bounces::int
+GROUP BY t.name
+ORDER BY count DESC, t.name ASC;
+
+-- name: GetBouncedSubscribers :many
+SELECT id, email, bounces, last_bounce_at, last_bounce FROM subscribers
+WHERE bounces > 0 AND unsubscribed_at IS NULL
+ORDER BY last_bounce_at DESC, id DESC
+LIMIT @page_size::int;
+
+-- name: GetNewsletterIssues :many
+SELECT ni.blog_post_id, ni.created_at, ni.recipients, ni.sent_at, bp.title FROM newsletter_issues ni
+JOIN blog_posts bp ON bp.id = ni.blog_post_id
+ORDER BY ni.created_at DESC
+LIMIT @page_size::int;
@@ -0,0 +1,53 @@
+-- name: QueueReplyNotifications :exec
+-- Tells the authors of the parent comments about approved replies, unless
+-- they replied to themselves.
+INSERT INTO notifications (user_id, comment_id, kind)
+SELECT users.id, comments.id, 'reply'
+FROM comments
+JOIN comments AS parents ON parents.id = comments.parent_id
+JOIN users ON users.id = parents.user_id
+WHERE comments.id = ANY(@ids::bigint[])
+  AND comments.status = 'approved'
+  AND users.notify_replies AND users.email_verified_at IS NOT NULL AND users.deleted_at IS NULL
+  AND comments.user_id IS DISTINCT FROM users.id
+ON CONFLICT (user_id, comment_id) DO NOTHING;
+
+-- name: QueueCommentNotifications :exec
+-- Tells post authors about approved comments on their posts, except their
+-- own.
+INSERT INTO notifications (user_id, comment_id, kind)
+SELECT users.id, comments.id, 'comment'
+FROM comments
+JOIN blog_posts ON blog_posts.id = comments.blog_post_id
+JOIN users ON users.username = blog_posts.author
+WHERE comments.id = ANY(@ids::bigint[])
+  AND comments.status = 'approved'
+  AND users.notify_comments AND users.email_verified_at IS NOT NULL AND users.deleted_at IS NULL
+  AND comments.user_id IS DISTINCT FROM users.id
+ON CONFLICT (user_id, comment_id) DO NOTHING;
+
+-- name: GetDueNotificationUsers :many
+-- Users whose oldest unsent notification has waited long enough for others
+-- to join it in a digest.
+SELECT user_id FROM notifications
+WHERE sent_at IS NULL
+GROUP BY user_id
+HAVING MIN(created_at) <= @cutoff::timestamptz
+LIMIT @batch_size::int;
+
+-- name: GetUnsentNotifications :many
+SELECT notifications.id, notifications.kind, notifications.created_at,
+    comments.author AS comment_author, comments.comment AS comment_text,
+    comments.id AS comment_id, comments.status AS comment_status, comments.deleted_at AS comment_deleted_at,
+    blog_posts.id AS post_id, blog_posts.title AS post_title, blog_posts.slug AS post_slug,
+    blog_posts.created_at AS post_created_at, blog_posts.published_at AS post_published_at
+FROM notifications
+JOIN comments ON comments.id = notifications.comment_id
+JOIN blog_posts ON blog_posts.id = comments.blog_post_id
+WHERE notifications.user_id = @user_id AND notifications.sent_at IS NULL
+ORDER BY notifications.created_at ASC, notifications.id ASC
+FOR UPDATE OF notifications SKIP LOCKED;
+
+-- name: MarkNotificationsSent :exec
+UPDATE notifications SET sent_at = NOW()
+WHERE id = ANY(@ids::bigint[]);
@@ -0,0 +1,54 @@
+-- name: EnqueueMail :one
+INSERT INTO outbox (sender, recipients, subject, message)
+VALUES (@sender, @recipients::text[], @subject, @message)
+RETURNING id;
+
+-- name: ClaimDueMail :many
+-- Claims a batch of due mail by moving its next attempt to the end of the
+-- lease, so other workers leave it alone while it is delivered. Mail that
+-- is never marked, because the worker died, is tried again after that.
+UPDATE outbox
+SET next_attempt_at = @lease_until, updated_at = NOW()
+WHERE id IN (
+    SELECT id FROM outbox
+    WHERE status = 'queued' AND next_attempt_at <= NOW()
+    ORDER BY next_attempt_at ASC, id ASC
+    LIMIT @batch_size::int
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: MarkMailSent :exec
+UPDATE outbox
+SET status = 'sent', attempts = attempts + 1, sent_at = NOW(), last_error = NULL, updated_at = NOW()
+WHERE id = @id;
+
+-- name: MarkMailRetry :exec
+UPDATE outbox
+SET attempts = attempts + 1, last_error = @last_error, next_attempt_at = @next_attempt_at, updated_at = NOW()
+WHERE id = @id;
+
+-- name: MarkMailFailed :exec
+UPDATE outbox
+SET status = 'failed', attempts = attempts + 1, last_error = @last_error, updated_at = NOW()
+WHERE id = @id;
+
+-- name: GetOutboxByStatus :many
+SELECT id, created_at, updated_at, sender, recipients, subject, status, attempts, next_attempt_at, last_error, sent_at
+FROM outbox
+WHERE status = @status
+ORDER BY created_at DESC, id DESC
+LIMIT @page_size::int;
+
+-- name: CountOutboxByStatus :many
+SELECT status, COUNT(*) AS count FROM outbox
+GROUP BY status;
+
+-- name: GetMailByID :one
+SELECT * FROM outbox WHERE id = @id LIMIT 1;
+
+-- name: ResendMail :exec
+-- Puts a message back in the queue to be tried again straight away.
+UPDATE outbox
+SET status = 'queued', attempts = 0, next_attempt_at = NOW(), last_error = NULL, updated_at = NOW()
+WHERE id = @id;
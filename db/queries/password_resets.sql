@@ -0,0 +1,19 @@
+-- name: CreatePasswordReset :exec
+INSERT INTO password_resets (user_id, token_hash, expires_at)
+VALUES (@user_id, @token_hash, @expires_at);
+
+-- name: IsPasswordResetValid :one
+SELECT EXISTS (
+    SELECT 1 FROM password_resets
+    WHERE token_hash = @token_hash AND used_at IS NULL AND expires_at > NOW()
+);
+
+-- name: UsePasswordReset :one
+-- Marks the token used, so it can't be used again, and returns its user.
+UPDATE password_resets SET used_at = NOW()
+WHERE token_hash = @token_hash AND used_at IS NULL AND expires_at > NOW()
+RETURNING user_id;
+
+-- name: DeletePasswordResets :exec
+-- Drops a user's unused tokens once their password has been changed.
+DELETE FROM password_resets WHERE user_id = @user_id AND used_at IS NULL;
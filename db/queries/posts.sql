@@ -1,14 +1,50 @@
 -- name: GetPublishedPosts :many
 SELECT * FROM blog_posts
 WHERE draft = false AND deleted_at IS NULL
-ORDER BY created_at DESC LIMIT 10;
+  AND (published_at, id) < (@cursor_time::timestamptz, @cursor_id::bigint)
+ORDER BY published_at DESC, id DESC
+LIMIT @page_size;
 
--- name: GetPostBySlugAndDate :one
+-- name: GetPublishedPostsBefore :many
 SELECT * FROM blog_posts
+WHERE draft = false AND deleted_at IS NULL
+  AND (published_at, id) > (@cursor_time::timestamptz, @cursor_id::bigint)
+ORDER BY published_at ASC, id ASC
+LIMIT @page_size;
+
+-- name: GetPublishedPostByID :one
+SELECT * FROM blog_posts
+WHERE id = @id AND draft = false AND deleted_at IS NULL
+LIMIT 1;
+
+-- name: GetPublishedPostBySlug :one
+SELECT * FROM blog_posts
+WHERE slug = @slug AND draft = false AND deleted_at IS NULL
+LIMIT 1;
+
+-- name: IsPostSlugDeleted :one
+SELECT EXISTS (
+    SELECT 1 FROM blog_posts WHERE slug = @slug AND deleted_at IS NOT NULL
+);
+
+-- name: IsPostIDDeleted :one
+SELECT EXISTS (
+    SELECT 1 FROM blog_posts WHERE id = @id AND deleted_at IS NOT NULL
+);
+
+-- name: IsSlugTaken :one
+SELECT EXISTS (
+    SELECT 1 FROM blog_posts WHERE slug = @slug AND id <> @id
+);
+
+-- name: CreatePostSlugHistory :exec
+INSERT INTO blog_post_slug_history (blog_post_id, slug)
+VALUES (@blog_post_id, @slug);
+
+-- name: GetPostIDByOldSlug :one
+SELECT blog_post_id FROM blog_post_slug_history
 WHERE slug = @slug
-  AND published_at >= @start_of_day
-  AND published_at < @end_of_day
-  AND deleted_at IS NULL
+ORDER BY created_at DESC
 LIMIT 1;
 
 -- name: GetPostByID :one
@@ -21,8 +57,8 @@ RETURNING *;
 
 -- name: UpdatePost :one
 UPDATE blog_posts
-SET title = @title, content = @content, slug = @slug,
-    draft = @draft, published_at = @published_at, updated_at = NOW()
+SET title = @title, description = @description, content = @content, slug = @slug,
+    draft = @draft, published_at = @published_at, scheduled_at = @scheduled_at, updated_at = NOW()
 WHERE id = @id AND deleted_at IS NULL
 RETURNING *;
 
@@ -32,11 +68,85 @@ UPDATE blog_posts SET deleted_at = NOW() WHERE id = @id;
 -- name: GetPostsByAuthor :many
 SELECT * FROM blog_posts
 WHERE author = @author AND draft = false AND deleted_at IS NULL
-ORDER BY created_at DESC;
+  AND (published_at, id) < (@cursor_time::timestamptz, @cursor_id::bigint)
+ORDER BY published_at DESC, id DESC
+LIMIT @page_size;
+
+-- name: GetPostsByAuthorBefore :many
+SELECT * FROM blog_posts
+WHERE author = @author AND draft = false AND deleted_at IS NULL
+  AND (published_at, id) > (@cursor_time::timestamptz, @cursor_id::bigint)
+ORDER BY published_at ASC, id ASC
+LIMIT @page_size;
 
 -- name: GetAllPostsAdmin :many
-SELECT * FROM blog_posts WHERE deleted_at IS NULL ORDER BY created_at DESC LIMIT 10;
+SELECT * FROM blog_posts
+WHERE deleted_at IS NULL
+  AND (COALESCE(published_at, created_at), id) < (@cursor_time::timestamptz, @cursor_id::bigint)
+ORDER BY COALESCE(published_at, created_at) DESC, id DESC
+LIMIT @page_size;
+
+-- name: GetAllPostsAdminBefore :many
+SELECT * FROM blog_posts
+WHERE deleted_at IS NULL
+  AND (COALESCE(published_at, created_at), id) > (@cursor_time::timestamptz, @cursor_id::bigint)
+ORDER BY COALESCE(published_at, created_at) ASC, id ASC
+LIMIT @page_size;
 
 -- name: GetDraftPosts :many
 SELECT * FROM blog_posts
-WHERE draft = true AND deleted_at IS NULL ORDER BY created_at DESC LIMIT 10;
\ No newline at end of file
+WHERE draft = true AND scheduled_at IS NULL AND deleted_at IS NULL
+  AND (COALESCE(published_at, created_at), id) < (@cursor_time::timestamptz, @cursor_id::bigint)
+ORDER BY COALESCE(published_at, created_at) DESC, id DESC
+LIMIT @page_size;
+
+-- name: GetDraftPostsBefore :many
+SELECT * FROM blog_posts
+WHERE draft = true AND scheduled_at IS NULL AND deleted_at IS NULL
+  AND (COALESCE(published_at, created_at), id) > (@cursor_time::timestamptz, @cursor_id::bigint)
+ORDER BY COALESCE(published_at, created_at) ASC, id ASC
+LIMIT @page_size;
+
+-- name: CountDraftPosts :one
+SELECT COUNT(*) FROM blog_posts
+WHERE draft = true AND scheduled_at IS NULL AND deleted_at IS NULL;
+
+-- name: GetScheduledPosts :many
+SELECT * FROM blog_posts
+WHERE draft = true AND scheduled_at IS NOT NULL AND deleted_at IS NULL
+ORDER BY scheduled_at ASC, id ASC;
+
+-- name: GetDueScheduledPosts :many
+SELECT * FROM blog_posts
+WHERE draft = true AND scheduled_at <= NOW() AND deleted_at IS NULL
+ORDER BY scheduled_at ASC, id ASC
+LIMIT @batch_size
+FOR UPDATE SKIP LOCKED;
+
+-- name: PublishScheduledPost :one
+UPDATE blog_posts
+SET draft = false, published_at = scheduled_at, scheduled_at = NULL, updated_at = NOW()
+WHERE id = @id
+RETURNING *;
+
+-- name: GetSitemapPosts :many
+SELECT id, created_at, updated_at, slug, published_at FROM blog_posts
+WHERE draft = false AND deleted_at IS NULL
+ORDER BY published_at DESC;
+
+-- name: GetSitemapAuthors :many
+SELECT author, MAX(updated_at)::timestamptz AS last_modified FROM blog_posts
+WHERE draft = false AND deleted_at IS NULL
+GROUP BY author
+ORDER BY author;
+
+-- name: SearchPosts :many
+SELECT id, created_at, updated_at, title, author, slug, description, draft, published_at,
+    ts_rank(search_vector, query)::real AS rank,
+    ts_headline('english', content, query, 'MaxFragments=2, MaxWords=30, MinWords=10, StartSel=<mark>, StopSel=</mark>')::text AS snippet
+FROM blog_posts, websearch_to_tsquery('english', @query::text) query
+WHERE search_vector @@ query
+  AND deleted_at IS NULL
+  AND (draft = false OR @include_drafts::boolean)
+ORDER BY rank DESC, id DESC
+LIMIT @page_size;
@@ -0,0 +1,17 @@
+-- name: GetRedirects :many
+SELECT * FROM redirects
+ORDER BY source_path ASC;
+
+-- name: CreateRedirect :one
+INSERT INTO redirects (source_path, target_path, status_code)
+VALUES (@source_path, @target_path, @status_code)
+RETURNING *;
+
+-- name: DeleteRedirect :exec
+DELETE FROM redirects WHERE id = @id;
+
+-- name: HitRedirect :one
+UPDATE redirects
+SET hits = hits + 1, last_hit_at = NOW()
+WHERE source_path = @source_path
+RETURNING *;
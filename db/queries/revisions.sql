@@ -0,0 +1,14 @@
+-- name: CreatePostRevision :one
+INSERT INTO blog_post_revisions (blog_post_id, author, title, content)
+VALUES (@blog_post_id, @author, @title, @content)
+RETURNING *;
+
+-- name: GetPostRevisions :many
+SELECT * FROM blog_post_revisions
+WHERE blog_post_id = @blog_post_id
+ORDER BY created_at DESC, id DESC;
+
+-- name: GetPostRevisionByID :one
+SELECT * FROM blog_post_revisions
+WHERE id = @id AND blog_post_id = @blog_post_id
+LIMIT 1;
\ No newline at end of file
@@ -0,0 +1,41 @@
+-- name: GetSpamTokens :many
+SELECT * FROM spam_tokens
+WHERE token = ANY(@tokens::text[]);
+
+-- name: CountTrainedComments :one
+SELECT
+    COUNT(*) FILTER (WHERE trained_as = 'ham') AS ham,
+    COUNT(*) FILTER (WHERE trained_as = 'spam') AS spam
+FROM comments;
+
+-- name: CountSpamTokens :one
+SELECT COUNT(*) FROM spam_tokens;
+
+-- name: TrainSpamTokens :exec
+-- Adds ham and spam to the counts of every token. Negative amounts unlearn a
+-- comment.
+INSERT INTO spam_tokens (token, ham_count, spam_count)
+SELECT token, GREATEST(@ham::bigint, 0), GREATEST(@spam::bigint, 0)
+FROM unnest(@tokens::text[]) AS token
+ON CONFLICT (token) DO UPDATE
+SET ham_count = GREATEST(spam_tokens.ham_count + @ham::bigint, 0),
+    spam_count = GREATEST(spam_tokens.spam_count + @spam::bigint, 0);
+
+-- name: GetCommentsToTrain :many
+SELECT id, author, comment, trained_as FROM comments
+WHERE id = ANY(@ids::bigint[]);
+
+-- name: SetCommentTrainedAs :exec
+UPDATE comments SET trained_as = sqlc.narg(trained_as) WHERE id = @id;
+
+-- name: GetBlocklist :many
+SELECT * FROM spam_blocklist
+ORDER BY kind ASC, value ASC;
+
+-- name: CreateBlocklistEntry :one
+INSERT INTO spam_blocklist (kind, value)
+VALUES (@kind, @value)
+RETURNING *;
+
+-- name: DeleteBlocklistEntry :exec
+DELETE FROM spam_blocklist WHERE id = @id;
@@ -33,4 +33,35 @@ WHERE id IN (
     WHERE t.name = @name AND t.deleted_at IS NULL
 )
 AND draft = false AND deleted_at IS NULL
-ORDER BY created_at DESC;
\ No newline at end of file
+AND (published_at, id) < (@cursor_time::timestamptz, @cursor_id::bigint)
+ORDER BY published_at DESC, id DESC
+LIMIT @page_size;
+
+-- name: GetPublishedPostsByTagBefore :many
+SELECT * FROM blog_posts
+WHERE id IN (
+    SELECT bpt.blog_post_id FROM blog_post_tags bpt
+    JOIN tags t ON t.id = bpt.tag_id
+    WHERE t.name = @name AND t.deleted_at IS NULL
+)
+AND draft = false AND deleted_at IS NULL
+AND (published_at, id) > (@cursor_time::timestamptz, @cursor_id::bigint)
+ORDER BY published_at ASC, id ASC
+LIMIT @page_size;
+
+-- name: GetSitemapTags :many
+SELECT t.name, MAX(bp.updated_at)::timestamptz AS last_modified FROM tags t
+JOIN blog_post_tags bpt ON bpt.tag_id = t.id
+JOIN blog_posts bp ON bp.id = bpt.blog_post_id
+WHERE t.deleted_at IS NULL AND bp.draft = false AND bp.deleted_at IS NULL
+GROUP BY t.name
+ORDER BY t.name;
+
+-- name: GetPublishedTags :many
+SELECT * FROM tags t
+WHERE t.deleted_at IS NULL AND EXISTS (
+    SELECT 1 FROM blog_post_tags bpt
+    JOIN blog_posts bp ON bp.id = bpt.blog_post_id
+    WHERE bpt.tag_id = t.id AND bp.draft = false AND bp.deleted_at IS NULL
+)
+ORDER BY t.name ASC;
@@ -0,0 +1,37 @@
+-- name: SetPendingTOTPSecret :exec
+-- Starts enrolling, replacing any secret from an earlier attempt.
+UPDATE users SET totp_secret = @totp_secret::text, updated_at = NOW()
+WHERE id = @id AND totp_enabled_at IS NULL;
+
+-- name: EnableTOTP :one
+-- Signs the user out everywhere else, since those sessions never had a
+-- second factor, and returns the new session version.
+UPDATE users
+SET totp_enabled_at = NOW(), totp_last_step = (@totp_last_step::bigint),
+    session_version = session_version + 1, updated_at = NOW()
+WHERE id = @id AND totp_enabled_at IS NULL AND totp_secret IS NOT NULL
+RETURNING session_version;
+
+-- name: DisableTOTP :exec
+UPDATE users SET totp_secret = NULL, totp_enabled_at = NULL, totp_last_step = 0, updated_at = NOW()
+WHERE id = @id;
+
+-- name: UseTOTPStep :execrows
+-- Records the step of an accepted code. No rows means a code from that
+-- step, or a later one, was already used.
+UPDATE users SET totp_last_step = (@step::bigint)
+WHERE id = @id AND totp_last_step < (@step::bigint);
+
+-- name: AddRecoveryCodes :exec
+INSERT INTO recovery_codes (user_id, code_hash)
+SELECT (@user_id::bigint), UNNEST(@code_hashes::bytea[]);
+
+-- name: DeleteRecoveryCodes :exec
+DELETE FROM recovery_codes WHERE user_id = @user_id;
+
+-- name: UseRecoveryCode :execrows
+UPDATE recovery_codes SET used_at = NOW()
+WHERE user_id = @user_id AND code_hash = @code_hash AND used_at IS NULL;
+
+-- name: CountRecoveryCodes :one
+SELECT COUNT(*) FROM recovery_codes WHERE user_id = @user_id AND used_at IS NULL;
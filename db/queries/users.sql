@@ -4,8 +4,53 @@ SELECT * FROM users WHERE username = @username AND deleted_at IS NULL LIMIT 1;
 -- name: GetUserByID :one
 SELECT * FROM users WHERE id = @id AND deleted_at IS NULL LIMIT 1;
 
--- name: UpdateUserPassword :exec
-UPDATE users SET password = @password, updated_at = NOW() WHERE id = @id;
+-- name: UpdateUserPassword :one
+-- Signs the user out everywhere by bumping the session version, and returns
+-- the new one.
+UPDATE users SET password = @password, session_version = session_version + 1, updated_at = NOW()
+WHERE id = @id
+RETURNING session_version;
 
 -- name: UpdateUserUsername :exec
-UPDATE users SET username = @username, updated_at = NOW() WHERE id = @id;
\ No newline at end of file
+UPDATE users SET username = @username, updated_at = NOW() WHERE id = @id;
+
+-- name: UpdateUserNotifications :exec
+-- A new address has to be verified again.
+UPDATE users
+SET email = sqlc.narg(email), notify_comments = @notify_comments, notify_replies = @notify_replies,
+    email_verified_at = CASE WHEN LOWER(email) IS NOT DISTINCT FROM LOWER(sqlc.narg(email)) THEN email_verified_at END,
+    updated_at = NOW()
+WHERE id = @id;
+
+-- name: UnsubscribeUserComments :exec
+UPDATE users SET notify_comments = FALSE, updated_at = NOW() WHERE id = @id;
+
+-- name: UnsubscribeUserReplies :exec
+UPDATE users SET notify_replies = FALSE, updated_at = NOW() WHERE id = @id;
+
+-- name: CreateUser :one
+INSERT INTO users (username, email, password)
+VALUES (@username, @email, @password)
+RETURNING *;
+
+-- name: IsUsernameTaken :one
+SELECT EXISTS (
+    SELECT 1 FROM users WHERE LOWER(username) = LOWER(@username::text)
+);
+
+-- name: IsEmailTaken :one
+SELECT EXISTS (
+    SELECT 1 FROM users WHERE LOWER(email) = LOWER(@email::text)
+);
+
+-- name: VerifyUserEmail :execrows
+-- Only verifies the address the link was sent to, in case it has changed
+-- since.
+UPDATE users SET email_verified_at = NOW(), updated_at = NOW()
+WHERE id = @id AND LOWER(email) = LOWER(@email::text) AND email_verified_at IS NULL;
+
+-- name: GetUserByEmail :one
+SELECT * FROM users WHERE LOWER(email) = LOWER(@email::text) AND deleted_at IS NULL LIMIT 1;
+
+-- name: GetUserSessionVersion :one
+SELECT session_version FROM users WHERE id = @id AND deleted_at IS NULL;
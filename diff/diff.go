@@ -0,0 +1,123 @@
+package diff
+
+import "strings"
+
+type Op int
+
+const (
+	Equal Op = iota
+	Insert
+	Delete
+)
+
+// Line is a single line of a diff. OldNumber and NewNumber are 1-based line
+// numbers in the old and new text, or 0 when the line is absent from it.
+type Line struct {
+	Op        Op
+	Text      string
+	OldNumber int
+	NewNumber int
+}
+
+// Lines computes a line-level diff turning a into b using Myers' algorithm.
+func Lines(a, b string) []Line {
+	return diffLines(splitLines(a), splitLines(b))
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// maxEdits caps the edit distance diffLines searches to. Recording the
+// paths takes memory quadratic in the distance, so texts further apart than
+// this are shown as replaced wholesale instead.
+const maxEdits = 1000
+
+func diffLines(a, b []string) []Line {
+	n, m := len(a), len(b)
+	max := n + m
+	offset := max + 1
+	v := make([]int, 2*max+3)
+	var trace [][]int
+
+	// Forward pass: find the length of the shortest edit script, recording
+	// the furthest reaching paths for every edit distance d. Only diagonals
+	// -d-1 to d+1 are read when backtracking from d, so only those are kept.
+	found := false
+	for d := 0; d <= max && !found; d++ {
+		if d > maxEdits {
+			return replaceLines(a, b)
+		}
+		trace = append(trace, append([]int(nil), v[offset-d-1:offset+d+2]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				found = true
+				break
+			}
+		}
+	}
+
+	// Backtrack through the recorded paths to build the script in reverse.
+	var reversed []Line
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		offset := d + 1
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			reversed = append(reversed, Line{Op: Equal, Text: a[x-1], OldNumber: x, NewNumber: y})
+			x--
+			y--
+		}
+		if d > 0 {
+			if x == prevX {
+				reversed = append(reversed, Line{Op: Insert, Text: b[y-1], NewNumber: y})
+			} else {
+				reversed = append(reversed, Line{Op: Delete, Text: a[x-1], OldNumber: x})
+			}
+		}
+		x, y = prevX, prevY
+	}
+
+	lines := make([]Line, len(reversed))
+	for i, line := range reversed {
+		lines[len(reversed)-1-i] = line
+	}
+	return lines
+}
+
+// replaceLines is the diff that deletes all of a and inserts all of b.
+func replaceLines(a, b []string) []Line {
+	lines := make([]Line, 0, len(a)+len(b))
+	for i, text := range a {
+		lines = append(lines, Line{Op: Delete, Text: text, OldNumber: i + 1})
+	}
+	for i, text := range b {
+		lines = append(lines, Line{Op: Insert, Text: text, NewNumber: i + 1})
+	}
+	return lines
+}
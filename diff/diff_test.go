@@ -0,0 +1,111 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// render formats a diff like a unified diff body for easy comparison.
+func render(lines []Line) string {
+	var sb strings.Builder
+	for _, line := range lines {
+		switch line.Op {
+		case Insert:
+			sb.WriteString("+")
+		case Delete:
+			sb.WriteString("-")
+		default:
+			sb.WriteString(" ")
+		}
+		sb.WriteString(line.Text + "\n")
+	}
+	return sb.String()
+}
+
+func TestLines(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want string
+	}{
+		{"empty", "", "", ""},
+		{"identical", "a\nb\n", "a\nb\n", " a\n b\n"},
+		{"insert", "a\nc", "a\nb\nc", " a\n+b\n c\n"},
+		{"delete", "a\nb\nc", "a\nc", " a\n-b\n c\n"},
+		{"replace", "a\nb\nc", "a\nx\nc", " a\n-b\n+x\n c\n"},
+		{"from empty", "", "a\nb", "+a\n+b\n"},
+		{"to empty", "a\nb", "", "-a\n-b\n"},
+		{"crlf", "a\r\nb\r\n", "a\nb\n", " a\n b\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := render(Lines(tt.a, tt.b)); got != tt.want {
+				t.Errorf("diff =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLineNumbers(t *testing.T) {
+	lines := Lines("a\nb\nc", "a\nx\nc")
+	want := []Line{
+		{Op: Equal, Text: "a", OldNumber: 1, NewNumber: 1},
+		{Op: Delete, Text: "b", OldNumber: 2},
+		{Op: Insert, Text: "x", NewNumber: 2},
+		{Op: Equal, Text: "c", OldNumber: 3, NewNumber: 3},
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("got %d lines, want %d", len(lines), len(want))
+	}
+	for i := range want {
+		if lines[i] != want[i] {
+			t.Errorf("line %d = %+v, want %+v", i, lines[i], want[i])
+		}
+	}
+}
+
+func TestLinesReconstructs(t *testing.T) {
+	a := "one\ntwo\nthree\nfour\nfive\nsix"
+	b := "zero\none\nthree\nfour\n4.5\nfive\nseven"
+
+	var oldLines, newLines []string
+	for _, line := range Lines(a, b) {
+		if line.Op != Insert {
+			oldLines = append(oldLines, line.Text)
+		}
+		if line.Op != Delete {
+			newLines = append(newLines, line.Text)
+		}
+	}
+	if got := strings.Join(oldLines, "\n"); got != a {
+		t.Errorf("old side = %q, want %q", got, a)
+	}
+	if got := strings.Join(newLines, "\n"); got != b {
+		t.Errorf("new side = %q, want %q", got, b)
+	}
+}
+
+func TestLinesFarApart(t *testing.T) {
+	var a, b []string
+	for i := range 2000 {
+		a = append(a, fmt.Sprintf("old %d", i))
+		b = append(b, fmt.Sprintf("new %d", i))
+	}
+
+	// Past maxEdits the texts are shown as replaced wholesale.
+	lines := Lines(strings.Join(a, "\n"), strings.Join(b, "\n"))
+	if len(lines) != 4000 || lines[0] != (Line{Op: Delete, Text: "old 0", OldNumber: 1}) ||
+		lines[2000] != (Line{Op: Insert, Text: "new 0", NewNumber: 1}) {
+		t.Errorf("got %d lines starting %+v", len(lines), lines[0])
+	}
+
+	// Long texts that are close still get a line by line diff.
+	c := append([]string(nil), a...)
+	c[1000] = "changed"
+	lines = Lines(strings.Join(a, "\n"), strings.Join(c, "\n"))
+	if len(lines) != 2001 || lines[1000].Op != Delete || lines[1001].Op != Insert || lines[1001].Text != "changed" {
+		t.Errorf("got %d lines, around the change %+v", len(lines), lines[999:1002])
+	}
+}
@@ -0,0 +1,265 @@
+package feed
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"time"
+)
+
+type Item struct {
+	ID          string
+	Title       string
+	Link        string
+	Description string
+	ContentHtml string
+	Author      string
+	AuthorLink  string
+	Tags        []string
+	Published   time.Time
+	Updated     time.Time
+}
+
+type Feed struct {
+	Title       string
+	Description string
+	Link        string
+	FeedLink    string
+	Items       []Item
+}
+
+// Updated returns the most recent update time of any item in the feed.
+func (f *Feed) Updated() time.Time {
+	var updated time.Time
+	for _, item := range f.Items {
+		if item.Updated.After(updated) {
+			updated = item.Updated
+		}
+	}
+	return updated
+}
+
+type rssFeed struct {
+	XMLName   xml.Name   `xml:"rss"`
+	Version   string     `xml:"version,attr"`
+	AtomNs    string     `xml:"xmlns:atom,attr"`
+	ContentNs string     `xml:"xmlns:content,attr"`
+	DcNs      string     `xml:"xmlns:dc,attr"`
+	Channel   rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	AtomLink      rssLink   `xml:"atom:link"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type rssItem struct {
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	Guid        rssGuid  `xml:"guid"`
+	Description string   `xml:"description"`
+	Content     cdata    `xml:"content:encoded"`
+	Author      string   `xml:"dc:creator,omitempty"`
+	Categories  []string `xml:"category"`
+	PubDate     string   `xml:"pubDate"`
+}
+
+type rssGuid struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type cdata struct {
+	Value string `xml:",cdata"`
+}
+
+// RSS renders the feed as an RSS 2.0 document.
+func (f *Feed) RSS() ([]byte, error) {
+	channel := rssChannel{
+		Title:       f.Title,
+		Link:        f.Link,
+		Description: f.Description,
+		AtomLink: rssLink{
+			Href: f.FeedLink,
+			Rel:  "self",
+			Type: "application/rss+xml",
+		},
+		Items: make([]rssItem, len(f.Items)),
+	}
+	if updated := f.Updated(); !updated.IsZero() {
+		channel.LastBuildDate = updated.UTC().Format(time.RFC1123Z)
+	}
+
+	for i, item := range f.Items {
+		channel.Items[i] = rssItem{
+			Title:       item.Title,
+			Link:        item.Link,
+			Guid:        rssGuid{IsPermaLink: item.ID == item.Link, Value: item.ID},
+			Description: item.Description,
+			Content:     cdata{Value: item.ContentHtml},
+			Author:      item.Author,
+			Categories:  item.Tags,
+			PubDate:     item.Published.UTC().Format(time.RFC1123Z),
+		}
+	}
+
+	return marshalXml(rssFeed{
+		Version:   "2.0",
+		AtomNs:    "http://www.w3.org/2005/Atom",
+		ContentNs: "http://purl.org/rss/1.0/modules/content/",
+		DcNs:      "http://purl.org/dc/elements/1.1/",
+		Channel:   channel,
+	})
+}
+
+type atomFeed struct {
+	XMLName  xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title    string      `xml:"title"`
+	Subtitle string      `xml:"subtitle,omitempty"`
+	ID       string      `xml:"id"`
+	Updated  string      `xml:"updated"`
+	Links    []atomLink  `xml:"link"`
+	Entries  []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomEntry struct {
+	Title      string         `xml:"title"`
+	ID         string         `xml:"id"`
+	Link       atomLink       `xml:"link"`
+	Published  string         `xml:"published"`
+	Updated    string         `xml:"updated"`
+	Author     atomAuthor     `xml:"author"`
+	Summary    string         `xml:"summary,omitempty"`
+	Content    atomContent    `xml:"content"`
+	Categories []atomCategory `xml:"category"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+	URI  string `xml:"uri,omitempty"`
+}
+
+type atomContent struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+// Atom renders the feed as an Atom 1.0 document.
+func (f *Feed) Atom() ([]byte, error) {
+	atom := atomFeed{
+		Title:    f.Title,
+		Subtitle: f.Description,
+		ID:       f.FeedLink,
+		Updated:  f.Updated().UTC().Format(time.RFC3339),
+		Links: []atomLink{
+			{Href: f.FeedLink, Rel: "self", Type: "application/atom+xml"},
+			{Href: f.Link, Rel: "alternate", Type: "text/html"},
+		},
+		Entries: make([]atomEntry, len(f.Items)),
+	}
+
+	for i, item := range f.Items {
+		categories := make([]atomCategory, len(item.Tags))
+		for j, tag := range item.Tags {
+			categories[j] = atomCategory{Term: tag}
+		}
+		atom.Entries[i] = atomEntry{
+			Title:      item.Title,
+			ID:         item.ID,
+			Link:       atomLink{Href: item.Link, Rel: "alternate", Type: "text/html"},
+			Published:  item.Published.UTC().Format(time.RFC3339),
+			Updated:    item.Updated.UTC().Format(time.RFC3339),
+			Author:     atomAuthor{Name: item.Author, URI: item.AuthorLink},
+			Summary:    item.Description,
+			Content:    atomContent{Type: "html", Value: item.ContentHtml},
+			Categories: categories,
+		}
+	}
+
+	return marshalXml(atom)
+}
+
+type jsonFeed struct {
+	Version     string     `json:"version"`
+	Title       string     `json:"title"`
+	HomePageUrl string     `json:"home_page_url"`
+	FeedUrl     string     `json:"feed_url"`
+	Description string     `json:"description,omitempty"`
+	Items       []jsonItem `json:"items"`
+}
+
+type jsonItem struct {
+	ID            string       `json:"id"`
+	Url           string       `json:"url"`
+	Title         string       `json:"title"`
+	ContentHtml   string       `json:"content_html"`
+	Summary       string       `json:"summary,omitempty"`
+	DatePublished string       `json:"date_published"`
+	DateModified  string       `json:"date_modified"`
+	Authors       []jsonAuthor `json:"authors,omitempty"`
+	Tags          []string     `json:"tags,omitempty"`
+}
+
+type jsonAuthor struct {
+	Name string `json:"name"`
+	Url  string `json:"url,omitempty"`
+}
+
+// JSON renders the feed as a JSON Feed 1.1 document.
+func (f *Feed) JSON() ([]byte, error) {
+	jf := jsonFeed{
+		Version:     "https://jsonfeed.org/version/1.1",
+		Title:       f.Title,
+		HomePageUrl: f.Link,
+		FeedUrl:     f.FeedLink,
+		Description: f.Description,
+		Items:       make([]jsonItem, len(f.Items)),
+	}
+
+	for i, item := range f.Items {
+		var authors []jsonAuthor
+		if item.Author != "" {
+			authors = []jsonAuthor{{Name: item.Author, Url: item.AuthorLink}}
+		}
+		jf.Items[i] = jsonItem{
+			ID:            item.ID,
+			Url:           item.Link,
+			Title:         item.Title,
+			ContentHtml:   item.ContentHtml,
+			Summary:       item.Description,
+			DatePublished: item.Published.UTC().Format(time.RFC3339),
+			DateModified:  item.Updated.UTC().Format(time.RFC3339),
+			Authors:       authors,
+			Tags:          item.Tags,
+		}
+	}
+
+	return json.MarshalIndent(jf, "", "  ")
+}
+
+func marshalXml(v any) ([]byte, error) {
+	out, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), out...), nil
+}
@@ -0,0 +1,164 @@
+package feed
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"testing"
+	"time"
+)
+
+func testFeed() *Feed {
+	published := time.Date(2026, time.March, 4, 12, 0, 0, 0, time.UTC)
+	return &Feed{
+		Title:       "test blog",
+		Description: "test description",
+		Link:        "https://example.com/",
+		FeedLink:    "https://example.com/feed.xml",
+		Items: []Item{
+			{
+				ID:          "https://example.com/post/03/04/2026/first",
+				Title:       "First",
+				Link:        "https://example.com/post/03/04/2026/first",
+				Description: "first post",
+				ContentHtml: "<p>Hello <b>world</b></p>",
+				Author:      "test",
+				Tags:        []string{"go", "htmx"},
+				Published:   published,
+				Updated:     published.Add(time.Hour),
+			},
+			{
+				ID:          "https://example.com/post/03/05/2026/second",
+				Title:       "Second",
+				Link:        "https://example.com/post/03/05/2026/second",
+				ContentHtml: "<p>Second</p>",
+				Author:      "test",
+				Published:   published.Add(24 * time.Hour),
+				Updated:     published.Add(24 * time.Hour),
+			},
+		},
+	}
+}
+
+func TestFeedUpdated(t *testing.T) {
+	f := testFeed()
+	if got, want := f.Updated(), f.Items[1].Updated; !got.Equal(want) {
+		t.Errorf("updated = %v, want %v", got, want)
+	}
+}
+
+func TestRSS(t *testing.T) {
+	out, err := testFeed().RSS()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var doc struct {
+		Channel struct {
+			Title string `xml:"title"`
+			Items []struct {
+				Link       string   `xml:"link"`
+				Content    string   `xml:"encoded"`
+				Categories []string `xml:"category"`
+				PubDate    string   `xml:"pubDate"`
+			} `xml:"item"`
+		} `xml:"channel"`
+	}
+	if err := xml.Unmarshal(out, &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	if doc.Channel.Title != "test blog" {
+		t.Errorf("title = %q", doc.Channel.Title)
+	}
+	if len(doc.Channel.Items) != 2 {
+		t.Fatalf("got %d items, want 2", len(doc.Channel.Items))
+	}
+	item := doc.Channel.Items[0]
+	if item.Content != "<p>Hello <b>world</b></p>" {
+		t.Errorf("content = %q", item.Content)
+	}
+	if len(item.Categories) != 2 {
+		t.Errorf("got %d categories, want 2", len(item.Categories))
+	}
+	if item.PubDate != "Wed, 04 Mar 2026 12:00:00 +0000" {
+		t.Errorf("pubDate = %q", item.PubDate)
+	}
+}
+
+func TestAtom(t *testing.T) {
+	out, err := testFeed().Atom()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var doc struct {
+		Updated string `xml:"updated"`
+		Entries []struct {
+			ID      string `xml:"id"`
+			Updated string `xml:"updated"`
+			Content struct {
+				Type  string `xml:"type,attr"`
+				Value string `xml:",chardata"`
+			} `xml:"content"`
+		} `xml:"entry"`
+	}
+	if err := xml.Unmarshal(out, &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	if doc.Updated != "2026-03-05T12:00:00Z" {
+		t.Errorf("updated = %q", doc.Updated)
+	}
+	if len(doc.Entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(doc.Entries))
+	}
+	entry := doc.Entries[0]
+	if entry.Updated != "2026-03-04T13:00:00Z" {
+		t.Errorf("entry updated = %q", entry.Updated)
+	}
+	if entry.Content.Type != "html" || entry.Content.Value != "<p>Hello <b>world</b></p>" {
+		t.Errorf("content = %+v", entry.Content)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	out, err := testFeed().JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var doc struct {
+		Version string `json:"version"`
+		Items   []struct {
+			ID            string   `json:"id"`
+			ContentHtml   string   `json:"content_html"`
+			Summary       string   `json:"summary"`
+			DatePublished string   `json:"date_published"`
+			DateModified  string   `json:"date_modified"`
+			Tags          []string `json:"tags"`
+			Authors       []struct {
+				Name string `json:"name"`
+			} `json:"authors"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(out, &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	if doc.Version != "https://jsonfeed.org/version/1.1" {
+		t.Errorf("version = %q", doc.Version)
+	}
+	if len(doc.Items) != 2 {
+		t.Fatalf("got %d items, want 2", len(doc.Items))
+	}
+	item := doc.Items[0]
+	if item.Summary != "first post" {
+		t.Errorf("summary = %q", item.Summary)
+	}
+	if item.DatePublished != "2026-03-04T12:00:00Z" || item.DateModified != "2026-03-04T13:00:00Z" {
+		t.Errorf("dates = %q, %q", item.DatePublished, item.DateModified)
+	}
+	if len(item.Tags) != 2 || len(item.Authors) != 1 || item.Authors[0].Name != "test" {
+		t.Errorf("tags = %v, authors = %v", item.Tags, item.Authors)
+	}
+}
@@ -0,0 +1,77 @@
+// Forms marked with data-pow="<form name>" solve a proof-of-work challenge
+// before they are sent. The submit is held back, a challenge is fetched and
+// solved in a worker, and the form is submitted again with the solution. The
+// listener runs in the capture phase so it goes before htmx's.
+(function () {
+    function field(form, name) {
+        let input = form.querySelector(`input[name="${name}"]`);
+        if (!input) {
+            input = document.createElement("input");
+            input.type = "hidden";
+            input.name = name;
+            form.appendChild(input);
+        }
+        return input;
+    }
+
+    function solve(challenge) {
+        return new Promise(function (resolve, reject) {
+            const worker = new Worker("/js/pow.worker.js");
+            worker.onmessage = function (event) {
+                worker.terminate();
+                resolve(event.data.solution);
+            };
+            worker.onerror = function (err) {
+                worker.terminate();
+                reject(err);
+            };
+            worker.postMessage(challenge);
+        });
+    }
+
+    document.addEventListener("submit", function (event) {
+        const form = event.target;
+        const purpose = form.dataset && form.dataset.pow;
+        if (!purpose) {
+            return;
+        }
+        // A solved form goes through once; the next submit needs a new one.
+        if (form.dataset.powSolved === "true") {
+            delete form.dataset.powSolved;
+            return;
+        }
+
+        event.preventDefault();
+        event.stopPropagation();
+        if (form.dataset.powSolving === "true") {
+            return;
+        }
+        form.dataset.powSolving = "true";
+        form.classList.add("pow-solving");
+
+        fetch("/pow/challenge/" + encodeURIComponent(purpose))
+            .then(function (res) {
+                if (!res.ok) {
+                    throw new Error("challenge request failed: " + res.status);
+                }
+                return res.json();
+            })
+            .then(function (challenge) {
+                return solve(challenge).then(function (solution) {
+                    field(form, "pow_challenge").value = challenge.challenge;
+                    field(form, "pow_solution").value = solution;
+                });
+            })
+            .then(function () {
+                form.dataset.powSolved = "true";
+                form.requestSubmit(event.submitter);
+            })
+            .catch(function (err) {
+                console.log("Proof of work failed:", err);
+            })
+            .finally(function () {
+                delete form.dataset.powSolving;
+                form.classList.remove("pow-solving");
+            });
+    }, true);
+})();
@@ -0,0 +1,314 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"net/mail"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dkimHeaders are the headers signed when present, in the order Message
+// writes them. From is required.
+var dkimHeaders = []string{
+	"From", "To", "Reply-To", "Subject", "Date", "Message-ID",
+	"List-Unsubscribe", "List-Unsubscribe-Post",
+	"MIME-Version", "Content-Type", "Content-Transfer-Encoding",
+}
+
+// DKIMSigner adds a DKIM-Signature header (RFC 6376) to messages, using
+// relaxed canonicalization for both headers and body. Keys can be RSA,
+// signed with rsa-sha256, or Ed25519, signed with ed25519-sha256 (RFC 8463).
+type DKIMSigner struct {
+	// Domain is the d= tag. When empty, the domain of the From address is
+	// used.
+	Domain   string
+	Selector string
+	Key      crypto.Signer
+	Now      func() time.Time
+}
+
+// NewDKIMSigner checks the key is one DKIM can use.
+func NewDKIMSigner(domain, selector string, key crypto.Signer) (*DKIMSigner, error) {
+	if selector == "" {
+		return nil, errors.New("dkim: no selector")
+	}
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		if k.N.BitLen() < 1024 {
+			return nil, fmt.Errorf("dkim: %d bit RSA key is too short", k.N.BitLen())
+		}
+	case ed25519.PrivateKey:
+	default:
+		return nil, fmt.Errorf("dkim: unsupported key type %T", key)
+	}
+	return &DKIMSigner{Domain: domain, Selector: selector, Key: key}, nil
+}
+
+// ParseDKIMKey reads a PEM encoded RSA or Ed25519 private key, in PKCS #1
+// or PKCS #8 form.
+func ParseDKIMKey(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("dkim: no PEM data in key")
+	}
+	if block.Type == "RSA PRIVATE KEY" {
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("dkim: %w", err)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("dkim: unsupported key type %T", key)
+	}
+	return signer, nil
+}
+
+// NewDKIMSignerFromEnv sets up signing with the key in the file at DKIM_KEY,
+// published under DKIM_SELECTOR. DKIM_DOMAIN overrides the signing domain.
+// It returns nil when DKIM_KEY isn't set.
+func NewDKIMSignerFromEnv() (*DKIMSigner, error) {
+	path := os.Getenv("DKIM_KEY")
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := ParseDKIMKey(data)
+	if err != nil {
+		return nil, err
+	}
+	return NewDKIMSigner(os.Getenv("DKIM_DOMAIN"), os.Getenv("DKIM_SELECTOR"), key)
+}
+
+func (s *DKIMSigner) algorithm() string {
+	if _, ok := s.Key.(ed25519.PrivateKey); ok {
+		return "ed25519-sha256"
+	}
+	return "rsa-sha256"
+}
+
+// DNSRecord is the TXT record to publish at <selector>._domainkey.<domain>.
+func (s *DKIMSigner) DNSRecord() (string, error) {
+	var keyType string
+	var public []byte
+	switch k := s.Key.Public().(type) {
+	case ed25519.PublicKey:
+		keyType, public = "ed25519", k
+	default:
+		der, err := x509.MarshalPKIXPublicKey(k)
+		if err != nil {
+			return "", err
+		}
+		keyType, public = "rsa", der
+	}
+	return "v=DKIM1; k=" + keyType + "; p=" + base64.StdEncoding.EncodeToString(public), nil
+}
+
+// Sign returns msg with a DKIM-Signature header in front. msg must use CRLF
+// line endings, as Message.Bytes does.
+func (s *DKIMSigner) Sign(msg []byte) ([]byte, error) {
+	header, body, ok := bytes.Cut(msg, []byte("\r\n\r\n"))
+	if !ok {
+		header, body = bytes.TrimSuffix(msg, []byte("\r\n")), nil
+	}
+	fields := splitHeader(header)
+
+	domain := s.Domain
+	if domain == "" {
+		from, err := mail.ParseAddress(lastField(fields, "From"))
+		if err != nil {
+			return nil, fmt.Errorf("dkim: from address: %w", err)
+		}
+		domain = from.Address[strings.LastIndex(from.Address, "@")+1:]
+	}
+
+	// Each name in h= covers one instance of that header, taken from the
+	// bottom up.
+	var signed []string
+	var canonical bytes.Buffer
+	used := map[string]int{}
+	for _, name := range dkimHeaders {
+		instances := fieldsNamed(fields, name)
+		for range instances {
+			key := strings.ToLower(name)
+			field := instances[len(instances)-1-used[key]]
+			used[key]++
+			signed = append(signed, name)
+			canonical.WriteString(relaxedHeader(field))
+		}
+	}
+	if used["from"] == 0 {
+		return nil, errors.New("dkim: message has no From header")
+	}
+
+	now := time.Now
+	if s.Now != nil {
+		now = s.Now
+	}
+	bodyHash := sha256.Sum256(relaxedBody(body))
+	value := strings.Join([]string{
+		"v=1",
+		"a=" + s.algorithm(),
+		"c=relaxed/relaxed",
+		"d=" + domain,
+		"s=" + s.Selector,
+		"t=" + strconv.FormatInt(now().Unix(), 10),
+		// Spaces in the lists let writeHeader fold them.
+		"h=" + strings.Join(signed, ": "),
+		"bh=" + base64.StdEncoding.EncodeToString(bodyHash[:]),
+		"b=",
+	}, "; ")
+
+	// The signature covers its own header with an empty b= and no
+	// trailing CRLF.
+	canonical.WriteString(strings.TrimSuffix(relaxedHeader("DKIM-Signature: "+value), "\r\n"))
+	digest := sha256.Sum256(canonical.Bytes())
+	var sig []byte
+	var err error
+	if _, ok := s.Key.(ed25519.PrivateKey); ok {
+		sig, err = s.Key.Sign(rand.Reader, digest[:], crypto.Hash(0))
+	} else {
+		sig, err = s.Key.Sign(rand.Reader, digest[:], crypto.SHA256)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("dkim: %w", err)
+	}
+
+	var out bytes.Buffer
+	writeHeader(&out, "DKIM-Signature", value+chunk(base64.StdEncoding.EncodeToString(sig), 64))
+	out.Write(msg)
+	return out.Bytes(), nil
+}
+
+// chunk splits s with spaces so writeHeader can fold it.
+func chunk(s string, size int) string {
+	var parts []string
+	for len(s) > size {
+		parts = append(parts, s[:size])
+		s = s[size:]
+	}
+	return strings.Join(append(parts, s), " ")
+}
+
+// splitHeader splits a header block into fields, each with its
+// continuation lines.
+func splitHeader(header []byte) []string {
+	var fields []string
+	for _, line := range strings.Split(string(header), "\r\n") {
+		if len(fields) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			fields[len(fields)-1] += "\r\n" + line
+			continue
+		}
+		fields = append(fields, line)
+	}
+	return fields
+}
+
+func fieldsNamed(fields []string, name string) []string {
+	var named []string
+	for _, f := range fields {
+		if n, _, ok := strings.Cut(f, ":"); ok && strings.EqualFold(strings.TrimSpace(n), name) {
+			named = append(named, f)
+		}
+	}
+	return named
+}
+
+func lastField(fields []string, name string) string {
+	named := fieldsNamed(fields, name)
+	if len(named) == 0 {
+		return ""
+	}
+	_, value, _ := strings.Cut(named[len(named)-1], ":")
+	return value
+}
+
+// relaxedHeader canonicalizes a header field as in RFC 6376 section 3.4.2.
+func relaxedHeader(field string) string {
+	name, value, _ := strings.Cut(field, ":")
+	value = strings.ReplaceAll(value, "\r\n", "")
+	return strings.ToLower(strings.TrimSpace(name)) + ":" + strings.Trim(collapseWhitespace(value), " ") + "\r\n"
+}
+
+// relaxedBody canonicalizes a body as in RFC 6376 section 3.4.4.
+func relaxedBody(body []byte) []byte {
+	lines := strings.Split(string(body), "\r\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(collapseWhitespace(line), " ")
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+	return []byte(strings.Join(lines, "\r\n") + "\r\n")
+}
+
+// collapseWhitespace turns each run of spaces and tabs into one space.
+func collapseWhitespace(s string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range s {
+		if r == ' ' || r == '\t' {
+			space = true
+			continue
+		}
+		if space {
+			b.WriteByte(' ')
+			space = false
+		}
+		b.WriteRune(r)
+	}
+	if space {
+		b.WriteByte(' ')
+	}
+	return b.String()
+}
+
+// SigningDriver signs every message before handing it to Driver.
+type SigningDriver struct {
+	Driver
+	Signer *DKIMSigner
+}
+
+func (d *SigningDriver) Deliver(ctx context.Context, from string, to []string, msg []byte) error {
+	signed, err := d.Signer.Sign(msg)
+	if err != nil {
+		// An unsignable message will never sign on a retry either.
+		return Permanent(err)
+	}
+	return d.Driver.Deliver(ctx, from, to, signed)
+}
+
+// logDKIM logs where the signer's public key has to be published.
+func logDKIM(s *DKIMSigner) {
+	record, err := s.DNSRecord()
+	if err != nil {
+		log.Printf("DKIM public key: %v", err)
+		return
+	}
+	domain := s.Domain
+	if domain == "" {
+		domain = "<sender domain>"
+	}
+	log.Printf("Signing mail with DKIM, publish TXT %s._domainkey.%s %q", s.Selector, domain, record)
+}
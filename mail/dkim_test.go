@@ -0,0 +1,308 @@
+package mail
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestRelaxedCanonicalization(t *testing.T) {
+	// The example from RFC 6376 section 3.4.5.
+	var headers strings.Builder
+	for _, field := range splitHeader([]byte("A: X\r\nB : Y\t\r\n\tZ  ")) {
+		headers.WriteString(relaxedHeader(field))
+	}
+	if got, want := headers.String(), "a:X\r\nb:Y Z\r\n"; got != want {
+		t.Errorf("headers = %q, want %q", got, want)
+	}
+	if got, want := string(relaxedBody([]byte(" C \r\nD \t E\r\n\r\n\r\n"))), " C\r\nD E\r\n"; got != want {
+		t.Errorf("body = %q, want %q", got, want)
+	}
+	if got := relaxedBody([]byte("\r\n\r\n")); len(got) != 0 {
+		t.Errorf("empty body = %q", got)
+	}
+}
+
+func TestDKIMSign(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg, err := Message{
+		From:            "Blog <blog@example.com>",
+		To:              []string{"ann@example.com"},
+		Subject:         "Ünïcode subject that is long enough to be folded over more than one line",
+		Text:            "Hello  there \n\n",
+		HTML:            "<p>Hello</p>",
+		ListUnsubscribe: "https://example.com/unsubscribe?token=abc",
+		Date:            testDate,
+		MessageID:       "<1@example.com>",
+	}.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []crypto.Signer{rsaKey, edKey} {
+		t.Run(fmt.Sprintf("%T", key), func(t *testing.T) {
+			s, err := NewDKIMSigner("", "blog", key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			signed, err := s.Sign(msg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.HasSuffix(signed, msg) {
+				t.Fatal("signing changed the message")
+			}
+			for _, line := range strings.Split(string(signed[:len(signed)-len(msg)]), "\r\n") {
+				if len(line) > maxLineLength {
+					t.Errorf("line not folded: %q", line)
+				}
+			}
+
+			tags, err := verifyDKIM(signed, key.Public())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tags["d"] != "example.com" || tags["s"] != "blog" || tags["c"] != "relaxed/relaxed" {
+				t.Errorf("tags = %v", tags)
+			}
+			if !strings.HasPrefix(tags["h"], "From:") || !strings.Contains(tags["h"], "List-Unsubscribe") {
+				t.Errorf("signed headers = %q", tags["h"])
+			}
+
+			// Refolding and trailing whitespace survive relaxed
+			// canonicalization, changed content doesn't.
+			relaxed := bytes.Replace(signed, []byte("\r\nMessage-ID: "), []byte("\r\nmessage-id:\t\r\n  "), 1)
+			relaxed = append(relaxed, "  \r\n\r\n"...)
+			if _, err := verifyDKIM(relaxed, key.Public()); err != nil {
+				t.Errorf("relaxed changes broke the signature: %v", err)
+			}
+			tampered := bytes.Replace(signed, []byte("ann@example.com"), []byte("bob@example.com"), 1)
+			if _, err := verifyDKIM(tampered, key.Public()); err == nil {
+				t.Error("changed header still verifies")
+			}
+			tampered = bytes.Replace(signed, []byte("Hello"), []byte("Howdy"), 1)
+			if _, err := verifyDKIM(tampered, key.Public()); err == nil {
+				t.Error("changed body still verifies")
+			}
+		})
+	}
+}
+
+func TestDKIMSignerFromEnv(t *testing.T) {
+	t.Setenv("DKIM_KEY", "")
+	if s, err := NewDKIMSignerFromEnv(); s != nil || err != nil {
+		t.Fatalf("without DKIM_KEY got %v, %v", s, err)
+	}
+
+	_, key, _ := ed25519.GenerateKey(rand.Reader)
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "dkim.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("DKIM_KEY", path)
+	t.Setenv("DKIM_SELECTOR", "")
+	if _, err := NewDKIMSignerFromEnv(); err == nil {
+		t.Error("no error without a selector")
+	}
+
+	t.Setenv("DKIM_SELECTOR", "mail")
+	t.Setenv("DKIM_DOMAIN", "example.org")
+	s, err := NewDKIMSignerFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Domain != "example.org" || s.Selector != "mail" || !key.Equal(s.Key) {
+		t.Errorf("signer = %+v", s)
+	}
+	record, err := s.DNSRecord()
+	if err != nil || record != "v=DKIM1; k=ed25519; p="+base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey)) {
+		t.Errorf("record = %q, %v", record, err)
+	}
+}
+
+func TestNewDKIMSignerRejectsWeakKeys(t *testing.T) {
+	small, err := rsa.GenerateKey(rand.Reader, 512)
+	if err != nil {
+		t.Skip("can't generate a 512 bit key:", err)
+	}
+	if _, err := NewDKIMSigner("example.com", "blog", small); err == nil {
+		t.Error("512 bit key accepted")
+	}
+}
+
+// rfc8463Message is the example in RFC 8463 appendix A.3, signed with the
+// Ed25519 key from appendix A.2. It checks verifyDKIM against a signature
+// made by someone else's implementation.
+var rfc8463Message = strings.ReplaceAll(`DKIM-Signature: v=1; a=ed25519-sha256; c=relaxed/relaxed;
+ d=football.example.com; i=@football.example.com;
+ q=dns/txt; s=brisbane; t=1528637909; h=from : to :
+ subject : date : message-id : from : subject : date;
+ bh=2jUSOH9NhtVGCQWNr9BrIAPreKQjO6Sn7XIkfJVOzv8=;
+ b=/gCrinpcQOoIfuHNQIbq4pgh9kyIK3AQUdt9OdqQehSwhEIug4D11Bus
+ Fa3bT3FY5OsU7ZbnKELq+eXdp1Q1Dw==
+From: Joe SixPack <joe@football.example.com>
+To: Suzie Q <suzie@shopping.example.net>
+Subject: Is dinner ready?
+Date: Fri, 11 Jul 2003 21:00:37 -0700 (PDT)
+Message-ID: <20030712040037.46341.5F8J@football.example.com>
+
+Hi.
+
+We lost the game.  Are you hungry yet?
+
+Joe.
+`, "\n", "\r\n")
+
+const rfc8463PublicKey = "11qYAYKxCrfVS/7TyWQHOg7hcvPapiMlrwIaaPcHURo="
+
+func TestVerifyDKIMKnownMessage(t *testing.T) {
+	public, _ := base64.StdEncoding.DecodeString(rfc8463PublicKey)
+	if _, err := verifyDKIM([]byte(rfc8463Message), ed25519.PublicKey(public)); err != nil {
+		t.Fatal(err)
+	}
+	tampered := strings.Replace(rfc8463Message, "hungry", "thirsty", 1)
+	if _, err := verifyDKIM([]byte(tampered), ed25519.PublicKey(public)); err == nil {
+		t.Error("changed body still verifies")
+	}
+}
+
+// verifyDKIM checks the first DKIM-Signature in msg the way a receiving
+// server would, and returns its tags. It canonicalizes on its own, straight
+// from RFC 6376 section 3.4, rather than with the code under test.
+func verifyDKIM(msg []byte, public crypto.PublicKey) (map[string]string, error) {
+	header, body, _ := strings.Cut(string(msg), "\r\n\r\n")
+	fields := testHeaderFields(header + "\r\n")
+	sigField := fields[0]
+	if name, _, _ := strings.Cut(sigField, ":"); !strings.EqualFold(name, "DKIM-Signature") {
+		return nil, errors.New("no signature")
+	}
+
+	tags := map[string]string{}
+	_, value, _ := strings.Cut(sigField, ":")
+	for _, tag := range strings.Split(value, ";") {
+		k, v, _ := strings.Cut(tag, "=")
+		tags[strings.TrimSpace(k)] = strings.Join(strings.Fields(v), "")
+	}
+
+	bodyHash := sha256.Sum256(testRelaxedBody(body))
+	if base64.StdEncoding.EncodeToString(bodyHash[:]) != tags["bh"] {
+		return tags, errors.New("body hash mismatch")
+	}
+
+	// Each name in h= takes the next instance of that field from the
+	// bottom up. Names with no instance left sign nothing.
+	var data bytes.Buffer
+	used := map[string]int{}
+	for _, name := range strings.Split(tags["h"], ":") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		var instances []string
+		for _, field := range fields[1:] {
+			if n, _, _ := strings.Cut(field, ":"); strings.ToLower(strings.TrimSpace(n)) == name {
+				instances = append(instances, field)
+			}
+		}
+		if used[name] < len(instances) {
+			data.WriteString(testRelaxedHeader(instances[len(instances)-1-used[name]]))
+		}
+		used[name]++
+	}
+	unsigned := testSignatureValue.ReplaceAllString(sigField, "$1")
+	data.WriteString(strings.TrimSuffix(testRelaxedHeader(unsigned), "\r\n"))
+
+	sig, err := base64.StdEncoding.DecodeString(tags["b"])
+	if err != nil {
+		return tags, err
+	}
+	digest := sha256.Sum256(data.Bytes())
+	switch k := public.(type) {
+	case *rsa.PublicKey:
+		if tags["a"] != "rsa-sha256" {
+			return tags, fmt.Errorf("algorithm %q for an RSA key", tags["a"])
+		}
+		return tags, rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], sig)
+	case ed25519.PublicKey:
+		if tags["a"] != "ed25519-sha256" {
+			return tags, fmt.Errorf("algorithm %q for an Ed25519 key", tags["a"])
+		}
+		if !ed25519.Verify(k, digest[:], sig) {
+			return tags, errors.New("bad signature")
+		}
+		return tags, nil
+	}
+	return tags, fmt.Errorf("unsupported key %T", public)
+}
+
+var (
+	testFieldStart     = regexp.MustCompile(`(?m)^[^ \t\r\n]`)
+	testFolding        = regexp.MustCompile(`\r\n([ \t])`)
+	testWhitespace     = regexp.MustCompile(`[ \t]+`)
+	testFieldName      = regexp.MustCompile(`^([^:]*?) ?: ?`)
+	testSignatureValue = regexp.MustCompile(`(;[ \t\r\n]*b[ \t\r\n]*=)[^;]*`)
+)
+
+// testHeaderFields splits a header into fields, each with its folded lines
+// and ending CRLF.
+func testHeaderFields(header string) []string {
+	starts := testFieldStart.FindAllStringIndex(header, -1)
+	fields := make([]string, len(starts))
+	for i, start := range starts {
+		end := len(header)
+		if i+1 < len(starts) {
+			end = starts[i+1][0]
+		}
+		fields[i] = header[start[0]:end]
+	}
+	return fields
+}
+
+// testRelaxedHeader is the relaxed header canonicalization of RFC 6376
+// section 3.4.2.
+func testRelaxedHeader(field string) string {
+	field = testFolding.ReplaceAllString(field, "$1")
+	field = strings.TrimSuffix(field, "\r\n")
+	field = testWhitespace.ReplaceAllString(field, " ")
+	m := testFieldName.FindStringSubmatchIndex(field)
+	return strings.ToLower(field[:m[3]]) + ":" + strings.TrimSuffix(field[m[1]:], " ") + "\r\n"
+}
+
+// testRelaxedBody is the relaxed body canonicalization of RFC 6376 section
+// 3.4.4.
+func testRelaxedBody(body string) []byte {
+	lines := strings.Split(body, "\r\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSuffix(testWhitespace.ReplaceAllString(line, " "), " ")
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+	return []byte(strings.Join(lines, "\r\n") + "\r\n")
+}
@@ -0,0 +1,130 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Driver hands a finished message over for delivery.
+type Driver interface {
+	Deliver(ctx context.Context, from string, to []string, msg []byte) error
+}
+
+// Drivers, picked with MAIL_DRIVER.
+const (
+	DriverSMTP = "smtp"
+	// DriverFile writes every message to an .eml file in MAIL_DIR.
+	DriverFile = "file"
+	// DriverLog writes every message to the log. It is only for
+	// development: messages carry sign-in links, such as password resets,
+	// that must not end up wherever the log is kept.
+	DriverLog = "log"
+)
+
+const defaultMailDir = "tmp/mail"
+
+// NewDriver sets up the driver chosen with MAIL_DRIVER. It defaults to SMTP
+// when SMTP_HOST is set and to files in MAIL_DIR otherwise, so development
+// never sends real mail by accident. Messages are DKIM signed when DKIM_KEY is
+// set.
+func NewDriver() (Driver, error) {
+	driver, err := newDriver()
+	if err != nil {
+		return nil, err
+	}
+	signer, err := NewDKIMSignerFromEnv()
+	if err != nil || signer == nil {
+		return driver, err
+	}
+	logDKIM(signer)
+	return &SigningDriver{Driver: driver, Signer: signer}, nil
+}
+
+func newDriver() (Driver, error) {
+	driver := os.Getenv("MAIL_DRIVER")
+	if driver == "" {
+		driver = DriverFile
+		if os.Getenv("SMTP_HOST") != "" {
+			driver = DriverSMTP
+		}
+	}
+
+	switch driver {
+	case DriverSMTP:
+		host := os.Getenv("SMTP_HOST")
+		if host == "" {
+			return nil, errors.New("MAIL_DRIVER=smtp needs SMTP_HOST")
+		}
+		return NewMailClient(host, os.Getenv("SMTP_PORT"), os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD")), nil
+	case DriverFile:
+		dir := os.Getenv("MAIL_DIR")
+		if dir == "" {
+			dir = defaultMailDir
+		}
+		return NewFileDriver(dir)
+	case DriverLog:
+		return LogDriver{}, nil
+	}
+	return nil, fmt.Errorf("unknown MAIL_DRIVER %q", driver)
+}
+
+// FileDriver writes each message to its own file, named so they sort in the
+// order they were sent.
+type FileDriver struct {
+	dir string
+	seq atomic.Uint64
+}
+
+func NewFileDriver(dir string) (*FileDriver, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileDriver{dir: dir}, nil
+}
+
+func (d *FileDriver) Deliver(_ context.Context, _ string, _ []string, msg []byte) error {
+	name := fmt.Sprintf("%s-%04d.eml", time.Now().UTC().Format("20060102T150405.000000000"), d.seq.Add(1)%10000)
+	return os.WriteFile(filepath.Join(d.dir, name), msg, 0o644)
+}
+
+// LogDriver writes messages to the standard logger.
+type LogDriver struct{}
+
+func (LogDriver) Deliver(_ context.Context, from string, to []string, msg []byte) error {
+	log.Printf("Mail from %s to %s:\n%s\n", from, strings.Join(to, ", "), msg)
+	return nil
+}
+
+// PermanentError marks a failure that retrying won't fix, like a rejected
+// recipient.
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string {
+	return "permanent: " + e.Err.Error()
+}
+
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+// Permanent wraps err as a PermanentError. It returns nil for nil.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &PermanentError{Err: err}
+}
+
+func IsPermanent(err error) bool {
+	var permanent *PermanentError
+	return errors.As(err, &permanent)
+}
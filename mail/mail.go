@@ -1,7 +1,13 @@
 package mail
 
-import "net/smtp"
+import (
+	"context"
+	"errors"
+	"net/smtp"
+	"net/textproto"
+)
 
+// MailClient delivers over SMTP.
 type MailClient struct {
 	smtpHost string
 	smtpPort string
@@ -19,7 +25,18 @@ func NewMailClient(smtpHost, smtpPort, username, password string) *MailClient {
 	}
 }
 
-func (mc *MailClient) SendMail(to []string, subject, body string) error {
-	msg := []byte("Subject: " + subject + "\n\n" + body)
-	return smtp.SendMail(mc.smtpHost+":"+mc.smtpPort, mc.auth, mc.from, to, msg)
+// From is the address the server logs in as, the default sender.
+func (mc *MailClient) From() string {
+	return mc.from
+}
+
+// Deliver hands msg to the SMTP server. Replies in the 5xx range are
+// permanent failures.
+func (mc *MailClient) Deliver(_ context.Context, from string, to []string, msg []byte) error {
+	err := smtp.SendMail(mc.smtpHost+":"+mc.smtpPort, mc.auth, from, to, msg)
+	var protoErr *textproto.Error
+	if errors.As(err, &protoErr) && protoErr.Code >= 500 {
+		return Permanent(err)
+	}
+	return err
 }
@@ -0,0 +1,46 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileDriver(t *testing.T) {
+	dir := t.TempDir()
+	d, err := NewFileDriver(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := range 3 {
+		if err := d.Deliver(context.Background(), "blog@example.com", []string{"ann@example.com"}, fmt.Appendf(nil, "message %d", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if err != nil || len(files) != 3 {
+		t.Fatalf("files = %v, %v", files, err)
+	}
+	last, _ := os.ReadFile(files[2])
+	if string(last) != "message 2" {
+		t.Errorf("files are not in order, last is %q", last)
+	}
+}
+
+func TestPermanent(t *testing.T) {
+	err := fmt.Errorf("delivering: %w", Permanent(errors.New("550 no such user")))
+	if !IsPermanent(err) {
+		t.Error("wrapped permanent error not recognised")
+	}
+	if IsPermanent(errors.New("timeout")) {
+		t.Error("plain error is permanent")
+	}
+	if Permanent(nil) != nil {
+		t.Error("Permanent(nil) is not nil")
+	}
+}
@@ -0,0 +1,186 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/a-h/templ"
+)
+
+// maxLineLength is the line length RFC 5322 asks headers to be folded at.
+const maxLineLength = 78
+
+// Message is an email with a plain text body and, optionally, an HTML one.
+// Addresses may carry display names, as in "Blog <blog@example.com>"; names
+// and the subject can be in any language.
+type Message struct {
+	From    string
+	To      []string
+	ReplyTo string
+	Subject string
+	Text    string
+	HTML    string
+	// ListUnsubscribe is a URL that unsubscribes the recipient with a single
+	// POST, as described in RFC 8058.
+	ListUnsubscribe string
+	// Date and MessageID are filled in when left empty.
+	Date      time.Time
+	MessageID string
+}
+
+// RenderHTML renders an email template for Message.HTML.
+func RenderHTML(ctx context.Context, c templ.Component) (string, error) {
+	var b strings.Builder
+	if err := c.Render(ctx, &b); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// Bytes renders the message as it goes over the wire, with CRLF line
+// endings. A message with an HTML body is sent as multipart/alternative
+// with the plain text first.
+func (m Message) Bytes() ([]byte, error) {
+	from, err := mail.ParseAddress(m.From)
+	if err != nil {
+		return nil, fmt.Errorf("from address %q: %w", m.From, err)
+	}
+	to, err := formatAddressList(m.To)
+	if err != nil {
+		return nil, err
+	}
+	if len(m.To) == 0 {
+		return nil, fmt.Errorf("message %q has no recipients", m.Subject)
+	}
+
+	date := m.Date
+	if date.IsZero() {
+		date = time.Now()
+	}
+	messageID := m.MessageID
+	if messageID == "" {
+		messageID = newMessageID(from.Address)
+	}
+
+	var b bytes.Buffer
+	writeHeader(&b, "From", from.String())
+	writeHeader(&b, "To", to)
+	if m.ReplyTo != "" {
+		replyTo, err := mail.ParseAddress(m.ReplyTo)
+		if err != nil {
+			return nil, fmt.Errorf("reply-to address %q: %w", m.ReplyTo, err)
+		}
+		writeHeader(&b, "Reply-To", replyTo.String())
+	}
+	writeHeader(&b, "Subject", mime.QEncoding.Encode("utf-8", m.Subject))
+	writeHeader(&b, "Date", date.Format(time.RFC1123Z))
+	writeHeader(&b, "Message-ID", messageID)
+	if m.ListUnsubscribe != "" {
+		writeHeader(&b, "List-Unsubscribe", "<"+m.ListUnsubscribe+">")
+		writeHeader(&b, "List-Unsubscribe-Post", "List-Unsubscribe=One-Click")
+	}
+	writeHeader(&b, "MIME-Version", "1.0")
+
+	if m.HTML == "" {
+		writeHeader(&b, "Content-Type", "text/plain; charset=utf-8")
+		writeHeader(&b, "Content-Transfer-Encoding", "quoted-printable")
+		b.WriteString("\r\n")
+		if err := writeQuotedPrintable(&b, m.Text); err != nil {
+			return nil, err
+		}
+		return b.Bytes(), nil
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	if err := mw.SetBoundary(m.boundary()); err != nil {
+		return nil, err
+	}
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain; charset=utf-8", m.Text},
+		{"text/html; charset=utf-8", m.HTML},
+	} {
+		w, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := writeQuotedPrintable(w, part.content); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+
+	writeHeader(&b, "Content-Type", `multipart/alternative; boundary="`+mw.Boundary()+`"`)
+	b.WriteString("\r\n")
+	b.Write(body.Bytes())
+	return b.Bytes(), nil
+}
+
+// boundary is derived from the content so the same message always renders
+// the same way, which keeps golden files stable.
+func (m Message) boundary() string {
+	sum := sha256.Sum256([]byte(m.Text + "\x00" + m.HTML))
+	return "=_" + hex.EncodeToString(sum[:12])
+}
+
+func formatAddressList(addresses []string) (string, error) {
+	formatted := make([]string, len(addresses))
+	for i, a := range addresses {
+		addr, err := mail.ParseAddress(a)
+		if err != nil {
+			return "", fmt.Errorf("recipient %q: %w", a, err)
+		}
+		formatted[i] = addr.String()
+	}
+	return strings.Join(formatted, ", "), nil
+}
+
+func writeQuotedPrintable(w interface{ Write([]byte) (int, error) }, s string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := qp.Write([]byte(strings.ReplaceAll(s, "\r\n", "\n"))); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+// writeHeader writes a header, folding it at spaces so lines stay within
+// maxLineLength where the value allows it. Encoded words from mime are
+// separated by spaces, so long subjects fold cleanly.
+func writeHeader(b *bytes.Buffer, name, value string) {
+	line := name + ":"
+	for i, word := range strings.Split(value, " ") {
+		if i > 0 && len(line)+1+len(word) > maxLineLength {
+			b.WriteString(line + "\r\n")
+			line = ""
+		}
+		line += " " + word
+	}
+	b.WriteString(line + "\r\n")
+}
+
+// newMessageID makes a unique Message-ID in the sender's domain.
+func newMessageID(from string) string {
+	domain := "localhost"
+	if i := strings.LastIndex(from, "@"); i >= 0 {
+		domain = from[i+1:]
+	}
+	id := make([]byte, 16)
+	rand.Read(id)
+	return "<" + hex.EncodeToString(id) + "@" + domain + ">"
+}
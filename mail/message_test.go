@@ -0,0 +1,108 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/a-h/templ"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files")
+
+var testDate = time.Date(2026, time.October, 18, 12, 0, 0, 0, time.FixedZone("CDT", -5*60*60))
+
+func TestMessageGolden(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  Message
+	}{
+		{
+			name: "plain",
+			msg: Message{
+				From:      "blog@example.com",
+				To:        []string{"ann@example.com"},
+				Subject:   "New comment",
+				Text:      "Hello,\nsomeone commented.\n",
+				Date:      testDate,
+				MessageID: "<1@example.com>",
+			},
+		},
+		{
+			name: "alternative",
+			msg: Message{
+				From:            "Zoë's Blog <blog@example.com>",
+				To:              []string{"Ann <ann@example.com>", "José Núñez <jose@example.com>"},
+				ReplyTo:         "Zoë <zoe@example.com>",
+				Subject:         "José replied to your comment on \"Ünïcödé in Go strings, runes and bytes explained\"",
+				Text:            "José wrote:\n> Très bien! " + strings.Repeat("long line ", 12) + "\n",
+				HTML:            "<p>José wrote:</p>\n<blockquote>Très bien!</blockquote>\n",
+				ListUnsubscribe: "https://blog.example.com/unsubscribe?token=42.replies.sig",
+				Date:            testDate,
+				MessageID:       "<2@example.com>",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.msg.Bytes()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			path := filepath.Join("testdata", tt.name+".golden")
+			if *update {
+				if err := os.WriteFile(path, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("message does not match %s, run with -update if that is expected:\n%s", path, got)
+			}
+
+			for i, line := range strings.Split(string(got), "\r\n") {
+				if len(line) > 998 {
+					t.Errorf("line %d is %d long", i, len(line))
+				}
+				if strings.Contains(line, "\n") {
+					t.Errorf("line %d has a bare LF", i)
+				}
+			}
+		})
+	}
+}
+
+func TestMessageErrors(t *testing.T) {
+	for _, msg := range []Message{
+		{From: "not an address", To: []string{"ann@example.com"}},
+		{From: "blog@example.com", To: []string{"ann"}},
+		{From: "blog@example.com"},
+		{From: "blog@example.com", To: []string{"ann@example.com"}, ReplyTo: "nope"},
+	} {
+		if _, err := msg.Bytes(); err == nil {
+			t.Errorf("%+v rendered", msg)
+		}
+	}
+}
+
+func TestRenderHTML(t *testing.T) {
+	c := templ.ComponentFunc(func(ctx context.Context, w io.Writer) error {
+		_, err := io.WriteString(w, "<p>hi</p>")
+		return err
+	})
+	html, err := RenderHTML(context.Background(), c)
+	if err != nil || html != "<p>hi</p>" {
+		t.Errorf("got %q, %v", html, err)
+	}
+}
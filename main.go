@@ -7,8 +7,11 @@ import (
 	"log"
 	"os"
 	"runtime"
+	"strings"
 	"time"
 
+	"blog.simoni.dev/mail"
+	"blog.simoni.dev/permalink"
 	"blog.simoni.dev/server"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/jackc/pgx/v5/stdlib"
@@ -41,6 +44,11 @@ func main() {
 		time.Local = loc
 	}
 
+	legacyPermalinks := strings.Split(os.Getenv("PERMALINK_LEGACY_PATTERNS"), ",")
+	if err := permalink.Configure(os.Getenv("PERMALINK_PATTERN"), legacyPermalinks); err != nil {
+		log.Fatal("invalid PERMALINK_PATTERN: ", err)
+	}
+
 	pool, err := pgxpool.New(context.Background(), os.Getenv("DSN"))
 	if err != nil {
 		log.Fatal("failed to open db connection: ", err)
@@ -54,6 +62,16 @@ func main() {
 		log.Fatal("migration failed: ", err)
 	}
 
+	go server.RunPublisher(context.Background(), pool, time.Minute)
+
+	mailDriver, err := mail.NewDriver()
+	if err != nil {
+		log.Fatal("invalid mail settings: ", err)
+	}
+	go server.RunNotifier(context.Background(), pool, time.Minute)
+	go server.RunNewsletter(context.Background(), pool, time.Minute)
+	go server.RunOutbox(context.Background(), pool, mailDriver, time.Minute)
+
 	engine, err := server.NewServer(pool)
 	if err != nil {
 		log.Fatal("failed to create server: ", err)
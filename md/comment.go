@@ -0,0 +1,86 @@
+package md
+
+import (
+	"html"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/gomarkdown/markdown"
+	"github.com/gomarkdown/markdown/ast"
+	mdhtml "github.com/gomarkdown/markdown/html"
+	"github.com/gomarkdown/markdown/parser"
+)
+
+const commentExtensions = parser.FencedCode | parser.Autolink | parser.Strikethrough |
+	parser.NoIntraEmphasis | parser.NoEmptyLineBeforeBlock
+
+// RenderComment renders a comment with the subset of markdown readers may
+// use: emphasis, links, inline and fenced code, lists and quotes. Raw HTML is
+// shown escaped, images are reduced to their alt text, headings become plain
+// paragraphs and the ::wasm loader is not available. Links are marked as
+// user generated so they pass on no ranking.
+func RenderComment(source []byte) []byte {
+	p := parser.NewWithExtensions(commentExtensions)
+	doc := p.Parse(source)
+	return markdown.Render(doc, newCommentRenderer())
+}
+
+func newCommentRenderer() *mdhtml.Renderer {
+	initHighlighting()
+	opts := mdhtml.RendererOptions{
+		Flags:          mdhtml.SkipHTML | mdhtml.SkipImages,
+		RenderNodeHook: commentRenderHook,
+	}
+	return mdhtml.NewRenderer(opts)
+}
+
+func commentRenderHook(w io.Writer, node ast.Node, entering bool) (ast.WalkStatus, bool) {
+	switch n := node.(type) {
+	case *ast.CodeBlock:
+		renderCodeBlock(w, n)
+		return ast.GoToNext, true
+	case *ast.HTMLBlock:
+		io.WriteString(w, "<p>"+html.EscapeString(string(n.Literal))+"</p>\n")
+		return ast.GoToNext, true
+	case *ast.HTMLSpan:
+		io.WriteString(w, html.EscapeString(string(n.Literal)))
+		return ast.GoToNext, true
+	case *ast.Heading:
+		if entering {
+			io.WriteString(w, "<p>")
+		} else {
+			io.WriteString(w, "</p>\n")
+		}
+		return ast.GoToNext, true
+	case *ast.Image:
+		// Skip the tag but keep walking so the alt text is still shown.
+		return ast.GoToNext, true
+	case *ast.Link:
+		if !isSafeCommentLink(n.Destination) {
+			return ast.GoToNext, true
+		}
+		if entering {
+			io.WriteString(w, `<a href="`+html.EscapeString(string(n.Destination))+`" rel="nofollow ugc noopener" target="_blank">`)
+		} else {
+			io.WriteString(w, "</a>")
+		}
+		return ast.GoToNext, true
+	}
+	return ast.GoToNext, false
+}
+
+// isSafeCommentLink allows web and mail links, and links within the site.
+func isSafeCommentLink(dest []byte) bool {
+	u, err := url.Parse(strings.TrimSpace(string(dest)))
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https", "mailto":
+		return true
+	case "":
+		return u.Host == "" && (strings.HasPrefix(u.Path, "/") || u.Path == "" && u.Fragment != "")
+	}
+	return false
+}
@@ -0,0 +1,93 @@
+package md
+
+import (
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestRenderComment(t *testing.T) {
+	tests := []struct {
+		name        string
+		source      string
+		contains    []string
+		notContains []string
+	}{
+		{
+			name:     "emphasis and inline code",
+			source:   "some *emphasis*, **strong** and `code`",
+			contains: []string{"<em>emphasis</em>", "<strong>strong</strong>", "<code>code</code>"},
+		},
+		{
+			name:     "links are nofollow",
+			source:   "[site](https://example.com) and https://example.org",
+			contains: []string{`<a href="https://example.com" rel="nofollow ugc noopener" target="_blank">site</a>`, `href="https://example.org" rel="nofollow ugc noopener"`},
+		},
+		{
+			name:        "unsafe links lose their anchor",
+			source:      "[click](javascript:alert(1))",
+			contains:    []string{"click"},
+			notContains: []string{"<a", "javascript:"},
+		},
+		{
+			name:        "raw html is escaped",
+			source:      "<script>alert(1)</script>\n\nhi <b>there</b>",
+			contains:    []string{"&lt;script&gt;", "&lt;b&gt;"},
+			notContains: []string{"<script>", "<b>"},
+		},
+		{
+			name:        "images become alt text",
+			source:      "![a cat](https://example.com/cat.png)",
+			contains:    []string{"a cat"},
+			notContains: []string{"<img", "cat.png"},
+		},
+		{
+			name:        "no wasm loader",
+			source:      "::wasm[go](https://example.com/app.wasm)",
+			notContains: []string{"<iframe"},
+		},
+		{
+			name:        "headings are flattened",
+			source:      "# Big",
+			contains:    []string{"<p>Big</p>"},
+			notContains: []string{"<h1"},
+		},
+		{
+			name:     "fenced code is highlighted",
+			source:   "```go\nfunc main() {}\n```",
+			contains: []string{"code-block-wrapper", "chroma"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := string(RenderComment([]byte(tt.source)))
+			for _, s := range tt.contains {
+				if !strings.Contains(out, s) {
+					t.Errorf("output does not contain %q:\n%s", s, out)
+				}
+			}
+			for _, s := range tt.notContains {
+				if strings.Contains(out, s) {
+					t.Errorf("output contains %q:\n%s", s, out)
+				}
+			}
+		})
+	}
+}
+
+// TestRenderCommentConcurrently is for go test -race: the highlighter is set
+// up by whichever render comes first.
+func TestRenderCommentConcurrently(t *testing.T) {
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if out := string(RenderComment([]byte("```go\nfunc main() {}\n```"))); !strings.Contains(out, "main") {
+				t.Errorf("code missing from %q", out)
+			}
+		}()
+	}
+	wg.Wait()
+}
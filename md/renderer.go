@@ -5,6 +5,7 @@ import (
 	"encoding/base64"
 	"fmt"
 	"io"
+	"sync"
 
 	"blog.simoni.dev/templates/components"
 	"github.com/alecthomas/chroma"
@@ -17,8 +18,9 @@ import (
 )
 
 var (
-	htmlFormatter  *html.Formatter
-	highlightStyle *chroma.Style
+	htmlFormatter    *html.Formatter
+	highlightStyle   *chroma.Style
+	highlightingOnce sync.Once
 )
 
 func htmlHighlight(w io.Writer, source, lang, defaultLang string) error {
@@ -55,15 +57,20 @@ func renderWasmLoader(w io.Writer, wasm *WasmLoader) {
 	io.WriteString(w, fmt.Sprintf("<iframe src=\"/wasm/%s?url=%s\" onload=\"resizeIframe(this)\" style=\"width: 100%%;\"></iframe>", wasm.Type, wasm.WasmURL))
 }
 
+// renderCodeBlock writes a highlighted code block with a copy button.
+func renderCodeBlock(w io.Writer, code *ast.CodeBlock) {
+	b64Data := base64.StdEncoding.EncodeToString(code.Literal)
+	io.WriteString(w, "<div class=\"code-block-wrapper\">")
+	copyId := uuid.New().String()
+	copyButton := components.CopyButton(b64Data, "copyBtn-"+copyId)
+	copyButton.Render(context.TODO(), w)
+	renderCode(w, code, true)
+	io.WriteString(w, "</div>")
+}
+
 func renderHook(w io.Writer, node ast.Node, entering bool) (ast.WalkStatus, bool) {
 	if code, ok := node.(*ast.CodeBlock); ok {
-		b64Data := base64.StdEncoding.EncodeToString(code.Literal)
-		io.WriteString(w, "<div class=\"code-block-wrapper\">")
-		copyId := uuid.New().String()
-		copyButton := components.CopyButton(b64Data, "copyBtn-"+copyId)
-		copyButton.Render(context.TODO(), w)
-		renderCode(w, code, entering)
-		io.WriteString(w, "</div>")
+		renderCodeBlock(w, code)
 		return ast.GoToNext, true
 	}
 
@@ -75,21 +82,25 @@ func renderHook(w io.Writer, node ast.Node, entering bool) (ast.WalkStatus, bool
 	return ast.GoToNext, false
 }
 
-func NewRenderer() *mdhtml.Renderer {
-	// init formatter and style
-	if htmlFormatter == nil {
+// initHighlighting sets up the formatter and style the first time it is
+// called. Posts and comments are rendered concurrently, so it has to be safe
+// to call from several goroutines at once.
+func initHighlighting() {
+	highlightingOnce.Do(func() {
 		htmlFormatter = html.New(html.TabWidth(4), html.WithClasses(true), html.WithLineNumbers(true))
 		if htmlFormatter == nil {
 			panic("couldn't create html formatter")
 		}
-	}
-	if highlightStyle == nil {
 		styleName := "monokai"
 		highlightStyle = styles.Get(styleName)
 		if highlightStyle == nil {
 			panic("couldn't get highlight style")
 		}
-	}
+	})
+}
+
+func NewRenderer() *mdhtml.Renderer {
+	initHighlighting()
 	opts := mdhtml.RendererOptions{
 		Flags:          mdhtml.CommonFlags | mdhtml.HrefTargetBlank,
 		RenderNodeHook: renderHook,
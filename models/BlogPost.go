@@ -2,9 +2,15 @@ package models
 
 import (
 	"fmt"
+	"net/url"
 	"time"
+
+	"blog.simoni.dev/permalink"
 )
 
+// ScheduleInputLayout is the format of a datetime-local form input.
+const ScheduleInputLayout = "2006-01-02T15:04"
+
 type BlogPost struct {
 	ID          int64
 	CreatedAt   time.Time
@@ -17,12 +23,31 @@ type BlogPost struct {
 	Tags        []Tag
 	Draft       bool
 	PublishedAt *time.Time
+	ScheduledAt *time.Time
+}
+
+// Permalink is the public URL path of the post. Posts that were never
+// published use their creation time in place of a publish date.
+func (p *BlogPost) Permalink() string {
+	publishedAt := p.CreatedAt
+	if p.PublishedAt != nil {
+		publishedAt = *p.PublishedAt
+	}
+	return permalink.For(permalink.Post{ID: p.ID, Slug: p.Slug, PublishedAt: publishedAt})
 }
 
 func (p *BlogPost) GetEditLink(adminRoute string) string {
 	return fmt.Sprintf("%s/edit/%d", adminRoute, p.ID)
 }
 
+func (p *BlogPost) GetSlugPreviewLink(adminRoute string) string {
+	return fmt.Sprintf("%s/slug-preview?id=%d", adminRoute, p.ID)
+}
+
+func (p *BlogPost) GetRevisionsLink(adminRoute string) string {
+	return fmt.Sprintf("%s/post/%d/revisions", adminRoute, p.ID)
+}
+
 func (p *BlogPost) GetCommentPostLink() string {
 	return fmt.Sprintf("/comment/%d", p.ID)
 }
@@ -35,3 +60,25 @@ func (p *BlogPost) Publish() {
 	now := time.Now()
 	p.PublishedAt = &now
 }
+
+// IsScheduled reports whether the post is a draft waiting to be published at
+// ScheduledAt.
+func (p *BlogPost) IsScheduled() bool {
+	return p.Draft && p.ScheduledAt != nil
+}
+
+// ScheduledInputValue formats ScheduledAt for a datetime-local input.
+func (p *BlogPost) ScheduledInputValue() string {
+	if p.ScheduledAt == nil {
+		return ""
+	}
+	return p.ScheduledAt.Local().Format(ScheduleInputLayout)
+}
+
+// EditableSlug is the slug as shown in the editor, without URL escaping.
+func (p *BlogPost) EditableSlug() string {
+	if s, err := url.QueryUnescape(p.Slug); err == nil {
+		return s
+	}
+	return p.Slug
+}
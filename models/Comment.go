@@ -2,17 +2,91 @@ package models
 
 import (
 	"fmt"
+	"net/url"
 	"time"
 )
 
+// Comment statuses. Only approved comments are shown publicly.
+const (
+	CommentPending  = "pending"
+	CommentApproved = "approved"
+	CommentSpam     = "spam"
+)
+
+var CommentStatuses = []string{CommentPending, CommentApproved, CommentSpam}
+
+// CommentDelete is the moderation action that deletes comments. Deleting
+// isn't a status: it sets deleted_at, as it does everywhere else.
+const CommentDelete = "delete"
+
 type Comment struct {
 	ID         int64
 	CreatedAt  time.Time
 	BlogPostId int64
-	Author     string
-	Comment    string
+	ParentId   *int64
+	UserId     *int64
+	// Author is the name given by a guest, or the username at the time a
+	// user commented. Username is the commenter's current username.
+	Author   string
+	Username string
+	Comment  string
+	// CommentHtml is the rendered comment. It is not set in the moderation
+	// queue, which shows comments as written.
+	CommentHtml string
+	Status      string
+	// SpamReason is why the spam filter held the comment, if it did.
+	SpamReason string
+	EditedAt   *time.Time
+	// EditableUntil is when the author can no longer edit or delete the
+	// comment. Admins can at any time.
+	EditableUntil time.Time
+	// Deleted comments are only kept to hold their replies in place. Their
+	// content is never loaded.
+	Deleted bool
+	// PostTitle is only loaded for the moderation queue.
+	PostTitle string
+	Depth     int
+	Replies   []Comment
+	// MoreReplies is set when the thread goes deeper than is shown inline.
+	MoreReplies bool
 }
 
 func (c *Comment) GetHtmlId() string {
 	return fmt.Sprintf("comment-%d", c.ID)
 }
+
+// IsGuest reports whether the comment was left without an account.
+func (c *Comment) IsGuest() bool {
+	return c.UserId == nil
+}
+
+func (c *Comment) DisplayName() string {
+	if c.Username != "" {
+		return c.Username
+	}
+	return c.Author
+}
+
+func (c *Comment) GetUserLink() string {
+	return "/user/" + url.PathEscape(c.DisplayName())
+}
+
+func (c *Comment) GetEditLink() string {
+	return fmt.Sprintf("/comment/%d/%d/edit", c.BlogPostId, c.ID)
+}
+
+func (c *Comment) GetDeleteLink() string {
+	return fmt.Sprintf("/comment/%d/%d", c.BlogPostId, c.ID)
+}
+
+func (c *Comment) GetRepliesHtmlId() string {
+	return fmt.Sprintf("comment-%d-replies", c.ID)
+}
+
+func (c *Comment) GetReplyLink() string {
+	return fmt.Sprintf("/comment/%d?parent=%d", c.BlogPostId, c.ID)
+}
+
+func (c *Comment) GetThreadLink() string {
+	return fmt.Sprintf("/comment/%d/thread/%d", c.BlogPostId, c.ID)
+}
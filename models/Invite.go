@@ -0,0 +1,23 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+type Invite struct {
+	ID        int64
+	CreatedAt time.Time
+	Code      string
+	// Note says who the invite is for.
+	Note   string
+	UsedAt *time.Time
+}
+
+func (i *Invite) GetDeleteLink(adminRoute string) string {
+	return fmt.Sprintf("%s/invites/%d", adminRoute, i.ID)
+}
+
+func (i *Invite) GetHtmlId() string {
+	return fmt.Sprintf("invite-%d", i.ID)
+}
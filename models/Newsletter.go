@@ -0,0 +1,47 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// SubscriberStats counts newsletter subscribers. Active subscribers are
+// confirmed, still subscribed and haven't bounced too often.
+type SubscriberStats struct {
+	Active       int64
+	AllPosts     int64
+	Pending      int64
+	Unsubscribed int64
+	Bounced      int64
+	ByTag        []TagCount
+}
+
+type TagCount struct {
+	Name  string
+	Count int64
+}
+
+type BouncedSubscriber struct {
+	ID           int64
+	Email        string
+	Bounces      int
+	LastBounceAt *time.Time
+	LastBounce   string
+}
+
+func (s *BouncedSubscriber) GetResetLink(adminRoute string) string {
+	return fmt.Sprintf("%s/newsletter/subscribers/%d/reset", adminRoute, s.ID)
+}
+
+func (s *BouncedSubscriber) GetHtmlId() string {
+	return fmt.Sprintf("subscriber-%d", s.ID)
+}
+
+// NewsletterIssue is a post being, or already, sent to subscribers.
+type NewsletterIssue struct {
+	PostID     int64
+	Title      string
+	CreatedAt  time.Time
+	Recipients int
+	SentAt     *time.Time
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// Outbox statuses. Queued mail is retried until it is sent or fails for good.
+const (
+	OutboxQueued = "queued"
+	OutboxSent   = "sent"
+	OutboxFailed = "failed"
+)
+
+var OutboxStatuses = []string{OutboxQueued, OutboxSent, OutboxFailed}
+
+type OutboxMail struct {
+	ID            int64
+	CreatedAt     time.Time
+	Sender        string
+	Recipients    []string
+	Subject       string
+	Status        string
+	Attempts      int
+	NextAttemptAt time.Time
+	LastError     string
+	SentAt        *time.Time
+	// Message is the raw message. It is only loaded when a single mail is
+	// looked at.
+	Message string
+}
+
+func (m *OutboxMail) GetLink(adminRoute string) string {
+	return fmt.Sprintf("%s/outbox/%d", adminRoute, m.ID)
+}
+
+func (m *OutboxMail) GetResendLink(adminRoute string) string {
+	return fmt.Sprintf("%s/outbox/%d/resend", adminRoute, m.ID)
+}
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// Pagination describes the position of a page in a keyset paginated list.
+// Cursors are opaque strings understood by the handler serving Path.
+type Pagination struct {
+	Path       string
+	Page       int
+	NextCursor string
+	PrevCursor string
+}
+
+func (p *Pagination) HasNext() bool {
+	return p.NextCursor != ""
+}
+
+func (p *Pagination) HasPrev() bool {
+	return p.PrevCursor != ""
+}
+
+func (p *Pagination) NextLink() string {
+	return fmt.Sprintf("%s?after=%s&page=%d", p.Path, url.QueryEscape(p.NextCursor), p.Page+1)
+}
+
+func (p *Pagination) PrevLink() string {
+	return fmt.Sprintf("%s?before=%s&page=%d", p.Path, url.QueryEscape(p.PrevCursor), p.Page-1)
+}
+
+// NextPartialLink returns the link used by infinite scroll to fetch only the
+// posts of the next page.
+func (p *Pagination) NextPartialLink() string {
+	return p.NextLink() + "&partial=true"
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+type PostRevision struct {
+	ID         int64
+	CreatedAt  time.Time
+	BlogPostId int64
+	Author     string
+	Title      string
+	Content    string
+}
+
+func (r *PostRevision) GetRestoreLink(adminRoute string) string {
+	return fmt.Sprintf("%s/post/%d/revisions/%d/restore", adminRoute, r.BlogPostId, r.ID)
+}
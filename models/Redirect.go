@@ -0,0 +1,24 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+type Redirect struct {
+	ID         int64
+	CreatedAt  time.Time
+	SourcePath string
+	TargetPath string
+	StatusCode int
+	Hits       int64
+	LastHitAt  *time.Time
+}
+
+func (r *Redirect) GetDeleteLink(adminRoute string) string {
+	return fmt.Sprintf("%s/redirects/%d", adminRoute, r.ID)
+}
+
+func (r *Redirect) GetHtmlId() string {
+	return fmt.Sprintf("redirect-%d", r.ID)
+}
@@ -0,0 +1,9 @@
+package models
+
+// SearchResult is a post matching a search query along with a highlighted
+// excerpt of its content.
+type SearchResult struct {
+	Post        BlogPost
+	Rank        float32
+	SnippetHtml string
+}
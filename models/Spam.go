@@ -0,0 +1,28 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+type BlocklistEntry struct {
+	ID        int64
+	CreatedAt time.Time
+	Kind      string
+	Value     string
+}
+
+func (b *BlocklistEntry) GetDeleteLink(adminRoute string) string {
+	return fmt.Sprintf("%s/spam/blocklist/%d", adminRoute, b.ID)
+}
+
+func (b *BlocklistEntry) GetHtmlId() string {
+	return fmt.Sprintf("blocklist-%d", b.ID)
+}
+
+// SpamStats is what the classifier has learnt from moderation decisions.
+type SpamStats struct {
+	TrainedHam  int64
+	TrainedSpam int64
+	Tokens      int64
+}
@@ -14,6 +14,30 @@ type User struct {
 	Password  string
 	Admin     bool
 	Theme     string
+	// Email is where notifications go. Users without one get none.
+	Email string
+	// EmailVerifiedAt is when the user followed the link mailed to Email.
+	// Accounts made before registration existed count as verified.
+	EmailVerifiedAt *time.Time
+	NotifyComments  bool
+	NotifyReplies   bool
+	// SessionVersion goes up whenever the password changes, signing the
+	// user out everywhere.
+	SessionVersion int32
+	// TOTPSecret is the authenticator app secret. It is set while enrolling
+	// and is only in use once TOTPEnabledAt is.
+	TOTPSecret    string
+	TOTPEnabledAt *time.Time
+	// TOTPLastStep is the time step of the last code accepted.
+	TOTPLastStep int64
+}
+
+func (u *User) EmailVerified() bool {
+	return u.EmailVerifiedAt != nil
+}
+
+func (u *User) TwoFactorEnabled() bool {
+	return u.TOTPEnabledAt != nil
 }
 
 func (u *User) IsAdmin() bool {
@@ -26,10 +50,12 @@ func (u *User) VerifyPassword(password string) (bool, error) {
 
 func (u *User) NewAuthTokens(ctx *gin.Context) (*auth.JwtPayload, error) {
 	payload := &auth.JwtPayload{
-		Username: u.Username,
-		Admin:    u.Admin,
-		UserId:   uint(u.ID),
-		Theme:    u.Theme,
+		Username:       u.Username,
+		Admin:          u.Admin,
+		UserId:         uint(u.ID),
+		Theme:          u.Theme,
+		SessionVersion: u.SessionVersion,
+		TwoFactor:      u.TwoFactorEnabled(),
 	}
 
 	jwtToken, refreshToken, err := auth.GenerateTokens(payload)
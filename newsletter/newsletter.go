@@ -0,0 +1,55 @@
+// Package newsletter writes the emails subscribers get when a post is
+// published, and signs the links that confirm and cancel subscriptions.
+package newsletter
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Post is the published post an email is about.
+type Post struct {
+	Title       string
+	Description string
+	Author      string
+	// Content is the post's markdown, which reads fine as plain text.
+	Content string
+	Tags    []string
+	URL     string
+}
+
+// Links are the absolute URLs every email ends with.
+type Links struct {
+	// Manage is where the subscriber picks which tags they hear about.
+	Manage      string
+	Unsubscribe string
+}
+
+type Message struct {
+	Subject string
+	Text    string
+}
+
+// Compose writes the plain text email for post.
+func Compose(post Post, links Links) Message {
+	var body strings.Builder
+	body.WriteString(post.Title + "\n")
+	body.WriteString(strings.Repeat("=", min(len([]rune(post.Title)), 72)) + "\n\n")
+	if post.Author != "" {
+		fmt.Fprintf(&body, "By %s", post.Author)
+		if len(post.Tags) > 0 {
+			fmt.Fprintf(&body, " in %s", strings.Join(post.Tags, ", "))
+		}
+		body.WriteString("\n\n")
+	}
+	if post.Description != "" {
+		body.WriteString(post.Description + "\n\n")
+	}
+	fmt.Fprintf(&body, "Read it on the blog: %s\n\n", post.URL)
+	body.WriteString(strings.TrimSpace(strings.ReplaceAll(post.Content, "\r\n", "\n")) + "\n\n")
+
+	body.WriteString("-- \n")
+	fmt.Fprintf(&body, "Choose which posts you get: %s\n", links.Manage)
+	fmt.Fprintf(&body, "Unsubscribe: %s\n", links.Unsubscribe)
+	return Message{Subject: post.Title, Text: body.String()}
+}
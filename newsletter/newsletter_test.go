@@ -0,0 +1,113 @@
+package newsletter
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"blog.simoni.dev/auth"
+	"github.com/golang-jwt/jwt"
+)
+
+var secret = []byte("test secret")
+
+func TestCompose(t *testing.T) {
+	msg := Compose(Post{
+		Title:       "Hello world",
+		Description: "The first post.",
+		Author:      "ann",
+		Content:     "# Hi\r\n\r\nSome *markdown*.\r\n",
+		Tags:        []string{"go", "web"},
+		URL:         "https://example.com/hello-world",
+	}, Links{Manage: "https://example.com/newsletter", Unsubscribe: "https://example.com/newsletter/unsubscribe?token=x"})
+
+	if msg.Subject != "Hello world" {
+		t.Errorf("subject = %q", msg.Subject)
+	}
+	for _, want := range []string{
+		"Hello world\n===========\n",
+		"By ann in go, web\n",
+		"The first post.\n",
+		"Read it on the blog: https://example.com/hello-world\n",
+		"# Hi\n\nSome *markdown*.\n",
+		"Unsubscribe: https://example.com/newsletter/unsubscribe?token=x\n",
+	} {
+		if !strings.Contains(msg.Text, want) {
+			t.Errorf("body is missing %q:\n%s", want, msg.Text)
+		}
+	}
+	if strings.Contains(msg.Text, "\r") {
+		t.Error("body has CRs")
+	}
+}
+
+func TestConfirmToken(t *testing.T) {
+	now := time.Date(2026, time.October, 18, 12, 0, 0, 0, time.UTC)
+	want := Subscription{Email: "ann@example.com", Tags: []int64{3, 7}}
+	token, err := ConfirmToken(secret, want, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ParseConfirmToken(secret, token, now.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Email != want.Email || got.AllPosts || !slices.Equal(got.Tags, want.Tags) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	if _, err := ParseConfirmToken(secret, token, now.Add(ConfirmTTL+time.Second)); !errors.Is(err, ErrExpiredToken) {
+		t.Errorf("expired token: %v", err)
+	}
+	if _, err := ParseConfirmToken([]byte("other secret"), token, now); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("wrong secret: %v", err)
+	}
+	if _, err := ParseConfirmToken(secret, token[:len(token)-2], now); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("truncated token: %v", err)
+	}
+
+	all, _ := ConfirmToken(secret, Subscription{Email: "bob@example.com", AllPosts: true}, now)
+	if got, err := ParseConfirmToken(secret, all, now); err != nil || !got.AllPosts || len(got.Tags) != 0 {
+		t.Errorf("all posts = %+v, %v", got, err)
+	}
+}
+
+func TestUnsubscribeToken(t *testing.T) {
+	token, err := UnsubscribeToken(secret, 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, err := ParseUnsubscribeToken(secret, token); err != nil || id != 42 {
+		t.Errorf("got %d, %v", id, err)
+	}
+
+	// Tokens for one purpose don't work for the other.
+	confirm, _ := ConfirmToken(secret, Subscription{Email: "ann@example.com", AllPosts: true}, time.Now())
+	if _, err := ParseUnsubscribeToken(secret, confirm); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("confirm token unsubscribed: %v", err)
+	}
+	if _, err := ParseConfirmToken(secret, token, time.Now()); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("unsubscribe token confirmed: %v", err)
+	}
+}
+
+func TestTokenKeys(t *testing.T) {
+	// Both kinds of token come from the same secret, but each is signed with
+	// its own key, and neither with the secret itself.
+	token, err := UnsubscribeToken(secret, 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := jwt.Parse(token, func(*jwt.Token) (any, error) { return secret, nil }); err == nil {
+		t.Error("token signed with the bare secret")
+	}
+	forged, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"purpose": purposeUnsubscribe, "subscriber": 42,
+	}).SignedString(auth.PurposeKey(secret, purposeConfirm))
+	if _, err := ParseUnsubscribeToken(secret, forged); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("token signed for another purpose: %v", err)
+	}
+}
@@ -0,0 +1,109 @@
+package newsletter
+
+import (
+	"errors"
+	"net/mail"
+	"time"
+
+	"blog.simoni.dev/auth"
+	"github.com/golang-jwt/jwt"
+)
+
+// ConfirmTTL is how long a confirmation link works.
+const ConfirmTTL = 7 * 24 * time.Hour
+
+// Token purposes, so a token for one can't be used for the other.
+const (
+	purposeConfirm     = "newsletter-confirm"
+	purposeUnsubscribe = "newsletter-unsubscribe"
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid newsletter token")
+	ErrExpiredToken = errors.New("expired newsletter token")
+)
+
+// Subscription is what a reader asked for on the subscribe form. It is
+// carried in the confirmation link and only stored once confirmed.
+type Subscription struct {
+	Email string
+	// AllPosts subscribes to every post. Otherwise only posts with one of
+	// Tags are sent.
+	AllPosts bool
+	Tags     []int64
+}
+
+// ConfirmToken signs a subscription into the link that confirms it.
+func ConfirmToken(secret []byte, s Subscription, now time.Time) (string, error) {
+	tags := make([]any, len(s.Tags))
+	for i, tag := range s.Tags {
+		tags[i] = tag
+	}
+	return auth.SignPurposeToken(secret, purposeConfirm, jwt.MapClaims{
+		"email": s.Email,
+		"all":   s.AllPosts,
+		"tags":  tags,
+		"exp":   now.Add(ConfirmTTL).Unix(),
+	})
+}
+
+// ParseConfirmToken returns the subscription in a genuine token that
+// hasn't expired.
+func ParseConfirmToken(secret []byte, token string, now time.Time) (Subscription, error) {
+	claims, err := parse(secret, token, purposeConfirm, now)
+	if err != nil {
+		return Subscription{}, err
+	}
+	if _, ok := claims["exp"]; !ok {
+		return Subscription{}, ErrInvalidToken
+	}
+
+	email, _ := claims["email"].(string)
+	if addr, err := mail.ParseAddress(email); err != nil || addr.Address != email {
+		return Subscription{}, ErrInvalidToken
+	}
+	all, _ := claims["all"].(bool)
+	s := Subscription{Email: email, AllPosts: all}
+	rawTags, _ := claims["tags"].([]any)
+	for _, t := range rawTags {
+		id, ok := t.(float64)
+		if !ok {
+			return Subscription{}, ErrInvalidToken
+		}
+		s.Tags = append(s.Tags, int64(id))
+	}
+	return s, nil
+}
+
+// UnsubscribeToken signs the link that ends a subscription. It doesn't
+// expire: an old email should still unsubscribe.
+func UnsubscribeToken(secret []byte, subscriberId int64) (string, error) {
+	return auth.SignPurposeToken(secret, purposeUnsubscribe, jwt.MapClaims{
+		"subscriber": subscriberId,
+	})
+}
+
+// ParseUnsubscribeToken returns the subscriber of a genuine token.
+func ParseUnsubscribeToken(secret []byte, token string) (int64, error) {
+	claims, err := parse(secret, token, purposeUnsubscribe, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	id, ok := claims["subscriber"].(float64)
+	if !ok {
+		return 0, ErrInvalidToken
+	}
+	return int64(id), nil
+}
+
+// parse is auth.ParsePurposeToken with the errors of this package.
+func parse(secret []byte, token, purpose string, now time.Time) (jwt.MapClaims, error) {
+	claims, err := auth.ParsePurposeToken(secret, token, purpose, now)
+	switch {
+	case errors.Is(err, auth.ErrTokenExpired):
+		return nil, ErrExpiredToken
+	case err != nil:
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
+}
@@ -0,0 +1,125 @@
+// Package notify writes the emails that tell people about new comments, and
+// signs the links that unsubscribe them.
+package notify
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Notification kinds, stored in notifications.kind.
+const (
+	// KindComment is a comment on a post the recipient wrote.
+	KindComment = "comment"
+	// KindReply is a reply to a comment the recipient wrote.
+	KindReply = "reply"
+)
+
+// excerptLength is how much of each comment is quoted in an email.
+const excerptLength = 300
+
+// Item is one comment to tell the recipient about.
+type Item struct {
+	Kind      string
+	Author    string
+	Comment   string
+	PostTitle string
+	// URL links straight to the comment.
+	URL string
+}
+
+// Links are the absolute URLs every email ends with.
+type Links struct {
+	// Settings is where notification preferences are changed.
+	Settings string
+	// Unsubscribe stops emails of the kind this one is about. A digest with
+	// both kinds unsubscribes from both.
+	Unsubscribe string
+}
+
+type Message struct {
+	Subject string
+	Text    string
+}
+
+// Compose writes the email for items. A single item gets an email of its
+// own; several are gathered into a digest.
+func Compose(items []Item, links Links) Message {
+	var subject string
+	var body strings.Builder
+
+	if len(items) == 1 {
+		item := items[0]
+		subject = itemHeadline(item)
+		body.WriteString(subject + ":\n\n")
+		writeItem(&body, item)
+	} else {
+		subject = digestSubject(items)
+		body.WriteString(subject + " since you last heard from us.\n\n")
+		for _, item := range items {
+			body.WriteString(itemHeadline(item) + ":\n\n")
+			writeItem(&body, item)
+		}
+	}
+
+	body.WriteString("-- \n")
+	fmt.Fprintf(&body, "Change which emails you get: %s\n", links.Settings)
+	fmt.Fprintf(&body, "Unsubscribe: %s\n", links.Unsubscribe)
+	return Message{Subject: subject, Text: body.String()}
+}
+
+func itemHeadline(item Item) string {
+	if item.Kind == KindReply {
+		return fmt.Sprintf("%s replied to your comment on %q", item.Author, item.PostTitle)
+	}
+	return fmt.Sprintf("%s commented on %q", item.Author, item.PostTitle)
+}
+
+func digestSubject(items []Item) string {
+	var comments, replies int
+	for _, item := range items {
+		if item.Kind == KindReply {
+			replies++
+		} else {
+			comments++
+		}
+	}
+
+	var parts []string
+	if comments > 0 {
+		parts = append(parts, plural(comments, "new comment", "new comments"))
+	}
+	if replies > 0 {
+		parts = append(parts, plural(replies, "reply to you", "replies to you"))
+	}
+	return strings.Join(parts, " and ")
+}
+
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return "1 " + one
+	}
+	return fmt.Sprintf("%d %s", n, many)
+}
+
+func writeItem(w *strings.Builder, item Item) {
+	for _, line := range strings.Split(Excerpt(item.Comment), "\n") {
+		w.WriteString("> " + line + "\n")
+	}
+	w.WriteString("\n" + item.URL + "\n\n")
+}
+
+// Excerpt shortens a comment to about excerptLength characters, cutting at a
+// space where it can.
+func Excerpt(s string) string {
+	s = strings.TrimSpace(strings.ReplaceAll(s, "\r\n", "\n"))
+	if utf8.RuneCountInString(s) <= excerptLength {
+		return s
+	}
+	cut := string([]rune(s)[:excerptLength])
+	if i := strings.LastIndexAny(cut, " \n"); i > excerptLength/2 {
+		cut = cut[:i]
+	}
+	return strings.TrimSpace(cut) + "…"
+}
@@ -0,0 +1,89 @@
+package notify
+
+import (
+	"strings"
+	"testing"
+)
+
+var links = Links{Settings: "https://blog.example/settings/notifications", Unsubscribe: "https://blog.example/unsubscribe?token=t"}
+
+func TestComposeSingle(t *testing.T) {
+	msg := Compose([]Item{{
+		Kind:      KindReply,
+		Author:    "ann",
+		Comment:   "I agree.\nMostly.",
+		PostTitle: "Generics",
+		URL:       "https://blog.example/post/generics#comment-3",
+	}}, links)
+
+	if msg.Subject != `ann replied to your comment on "Generics"` {
+		t.Errorf("subject = %q", msg.Subject)
+	}
+	for _, want := range []string{"> I agree.\n> Mostly.\n", "#comment-3", links.Unsubscribe, links.Settings} {
+		if !strings.Contains(msg.Text, want) {
+			t.Errorf("body does not contain %q:\n%s", want, msg.Text)
+		}
+	}
+}
+
+func TestComposeDigest(t *testing.T) {
+	msg := Compose([]Item{
+		{Kind: KindComment, Author: "ann", Comment: "one", PostTitle: "A", URL: "u1"},
+		{Kind: KindComment, Author: "bob", Comment: "two", PostTitle: "A", URL: "u2"},
+		{Kind: KindReply, Author: "cat", Comment: "three", PostTitle: "B", URL: "u3"},
+	}, links)
+
+	if msg.Subject != "2 new comments and 1 reply to you" {
+		t.Errorf("subject = %q", msg.Subject)
+	}
+	for _, want := range []string{`ann commented on "A"`, `bob commented on "A"`, `cat replied to your comment on "B"`, "u3"} {
+		if !strings.Contains(msg.Text, want) {
+			t.Errorf("body does not contain %q:\n%s", want, msg.Text)
+		}
+	}
+}
+
+func TestExcerpt(t *testing.T) {
+	long := strings.Repeat("word ", 100)
+	got := Excerpt(long)
+	if !strings.HasSuffix(got, "word…") || len([]rune(got)) > excerptLength+1 {
+		t.Errorf("excerpt = %q", got)
+	}
+	if got := Excerpt("  short  "); got != "short" {
+		t.Errorf("excerpt = %q", got)
+	}
+}
+
+func TestUnsubscribeToken(t *testing.T) {
+	secret := []byte("secret")
+	token := UnsubscribeToken(secret, 42, ListReplies)
+
+	id, list, ok := ParseUnsubscribeToken(secret, token)
+	if !ok || id != 42 || list != ListReplies {
+		t.Errorf("got %d %q %v", id, list, ok)
+	}
+
+	for _, bad := range []string{
+		"",
+		UnsubscribeToken([]byte("other"), 42, ListReplies),
+		strings.Replace(token, "42.", "43.", 1),
+		UnsubscribeToken(secret, 42, "newsletter"),
+	} {
+		if _, _, ok := ParseUnsubscribeToken(secret, bad); ok {
+			t.Errorf("%q was accepted", bad)
+		}
+	}
+}
+
+func TestListFor(t *testing.T) {
+	comment, reply := Item{Kind: KindComment}, Item{Kind: KindReply}
+	if l := ListFor([]Item{comment, comment}); l != ListComments {
+		t.Errorf("comments only = %s", l)
+	}
+	if l := ListFor([]Item{reply}); l != ListReplies {
+		t.Errorf("replies only = %s", l)
+	}
+	if l := ListFor([]Item{comment, reply}); l != ListAll {
+		t.Errorf("mixed = %s", l)
+	}
+}
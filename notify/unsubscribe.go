@@ -0,0 +1,65 @@
+package notify
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Lists a user can unsubscribe from.
+const (
+	ListComments = "comments"
+	ListReplies  = "replies"
+	ListAll      = "all"
+)
+
+var Lists = []string{ListComments, ListReplies, ListAll}
+
+// ListFor is the list an email about items belongs to.
+func ListFor(items []Item) string {
+	list := ""
+	for _, item := range items {
+		l := ListComments
+		if item.Kind == KindReply {
+			l = ListReplies
+		}
+		if list != "" && list != l {
+			return ListAll
+		}
+		list = l
+	}
+	return list
+}
+
+// UnsubscribeToken signs an unsubscribe request so the link works without
+// logging in. Tokens don't expire: an old email should still unsubscribe.
+func UnsubscribeToken(secret []byte, userId int64, list string) string {
+	payload := strconv.FormatInt(userId, 10) + "." + list
+	return payload + "." + signUnsubscribe(secret, payload)
+}
+
+func signUnsubscribe(secret []byte, payload string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("unsubscribe:" + payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// ParseUnsubscribeToken returns the user and list of a genuine token.
+func ParseUnsubscribeToken(secret []byte, token string) (userId int64, list string, ok bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return 0, "", false
+	}
+	payload := parts[0] + "." + parts[1]
+	if !hmac.Equal([]byte(parts[2]), []byte(signUnsubscribe(secret, payload))) {
+		return 0, "", false
+	}
+	userId, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || !slices.Contains(Lists, parts[1]) {
+		return 0, "", false
+	}
+	return userId, parts[1], true
+}
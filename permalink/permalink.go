@@ -0,0 +1,226 @@
+package permalink
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultPattern is the permalink the blog has always used.
+const DefaultPattern = "/post/:month/:day/:year/:slug"
+
+const (
+	tokenYear  = ":year"
+	tokenMonth = ":month"
+	tokenDay   = ":day"
+	tokenSlug  = ":slug"
+	tokenId    = ":id"
+)
+
+var tokens = []string{tokenYear, tokenMonth, tokenDay, tokenSlug, tokenId}
+
+// Post holds the fields a permalink can be built from.
+type Post struct {
+	ID          int64
+	Slug        string
+	PublishedAt time.Time
+}
+
+// Match is what could be read back out of a path. Fields whose token is not
+// part of the pattern are left zero.
+type Match struct {
+	ID    int64
+	Slug  string
+	Year  int
+	Month int
+	Day   int
+}
+
+// Pattern is a permalink shape such as /:year/:month/:slug. Every segment is
+// either literal text or exactly one token, which keeps patterns usable as
+// gin routes as they are.
+type Pattern struct {
+	raw      string
+	segments []string
+}
+
+func Parse(s string) (*Pattern, error) {
+	if !strings.HasPrefix(s, "/") {
+		return nil, fmt.Errorf("permalink pattern %q must start with /", s)
+	}
+	segments := strings.Split(strings.TrimSuffix(s[1:], "/"), "/")
+
+	seen := map[string]bool{}
+	for _, segment := range segments {
+		if segment == "" {
+			return nil, fmt.Errorf("permalink pattern %q has an empty segment", s)
+		}
+		if !strings.HasPrefix(segment, ":") {
+			continue
+		}
+		if !isToken(segment) {
+			return nil, fmt.Errorf("permalink pattern %q has unknown token %s", s, segment)
+		}
+		if seen[segment] {
+			return nil, fmt.Errorf("permalink pattern %q repeats %s", s, segment)
+		}
+		seen[segment] = true
+	}
+	if !seen[tokenSlug] && !seen[tokenId] {
+		return nil, errors.New("permalink pattern must contain :slug or :id")
+	}
+
+	return &Pattern{raw: "/" + strings.Join(segments, "/"), segments: segments}, nil
+}
+
+func MustParse(s string) *Pattern {
+	p, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+func isToken(s string) bool {
+	for _, t := range tokens {
+		if s == t {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns the pattern, which doubles as its gin route.
+func (p *Pattern) String() string {
+	return p.raw
+}
+
+// Build returns the permalink of post. Dates are in local time.
+func (p *Pattern) Build(post Post) string {
+	t := post.PublishedAt.Local()
+
+	var sb strings.Builder
+	for _, segment := range p.segments {
+		sb.WriteByte('/')
+		switch segment {
+		case tokenYear:
+			sb.WriteString(strconv.Itoa(t.Year()))
+		case tokenMonth:
+			fmt.Fprintf(&sb, "%02d", t.Month())
+		case tokenDay:
+			fmt.Fprintf(&sb, "%02d", t.Day())
+		case tokenSlug:
+			sb.WriteString(post.Slug)
+		case tokenId:
+			sb.WriteString(strconv.FormatInt(post.ID, 10))
+		default:
+			sb.WriteString(segment)
+		}
+	}
+	return sb.String()
+}
+
+// Match reads the tokens out of path if it has the shape of the pattern.
+func (p *Pattern) Match(path string) (Match, bool) {
+	var m Match
+	if !strings.HasPrefix(path, "/") {
+		return m, false
+	}
+	parts := strings.Split(strings.TrimSuffix(path[1:], "/"), "/")
+	if len(parts) != len(p.segments) {
+		return m, false
+	}
+
+	for i, segment := range p.segments {
+		part := parts[i]
+		if part == "" {
+			return m, false
+		}
+
+		var err error
+		switch segment {
+		case tokenYear:
+			m.Year, err = strconv.Atoi(part)
+		case tokenMonth:
+			m.Month, err = strconv.Atoi(part)
+			if err == nil && (m.Month < 1 || m.Month > 12) {
+				return m, false
+			}
+		case tokenDay:
+			m.Day, err = strconv.Atoi(part)
+			if err == nil && (m.Day < 1 || m.Day > 31) {
+				return m, false
+			}
+		case tokenSlug:
+			m.Slug = part
+		case tokenId:
+			m.ID, err = strconv.ParseInt(part, 10, 64)
+		default:
+			if part != segment {
+				return m, false
+			}
+		}
+		if err != nil {
+			return m, false
+		}
+	}
+	return m, true
+}
+
+var (
+	current = MustParse(DefaultPattern)
+	legacy  []*Pattern
+)
+
+// Configure sets the pattern used for every permalink on the site. Links in
+// any of the legacy patterns are still recognised so they can be redirected.
+// The default pattern is always treated as legacy once it is replaced. It
+// must be called before the server starts.
+func Configure(pattern string, legacyPatterns []string) error {
+	if pattern == "" {
+		pattern = DefaultPattern
+	}
+	p, err := Parse(pattern)
+	if err != nil {
+		return err
+	}
+
+	if pattern != DefaultPattern {
+		legacyPatterns = append(legacyPatterns, DefaultPattern)
+	}
+	var old []*Pattern
+	for _, s := range legacyPatterns {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		lp, err := Parse(s)
+		if err != nil {
+			return err
+		}
+		if lp.raw != p.raw {
+			old = append(old, lp)
+		}
+	}
+
+	current = p
+	legacy = old
+	return nil
+}
+
+// Current is the configured permalink pattern.
+func Current() *Pattern {
+	return current
+}
+
+// Legacy lists the patterns previously used for permalinks.
+func Legacy() []*Pattern {
+	return legacy
+}
+
+// For builds the permalink of post with the configured pattern.
+func For(post Post) string {
+	return current.Build(post)
+}
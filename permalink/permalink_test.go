@@ -0,0 +1,89 @@
+package permalink
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBuild(t *testing.T) {
+	post := Post{ID: 42, Slug: "hello-world", PublishedAt: time.Date(2026, time.March, 7, 12, 0, 0, 0, time.Local)}
+
+	tests := map[string]string{
+		DefaultPattern:           "/post/03/07/2026/hello-world",
+		"/:year/:month/:slug":    "/2026/03/hello-world",
+		"/posts/:slug":           "/posts/hello-world",
+		"/p/:id":                 "/p/42",
+		"/:year/:id/:slug/":      "/2026/42/hello-world",
+		"/blog/:year/:day/:slug": "/blog/2026/07/hello-world",
+	}
+	for pattern, want := range tests {
+		if got := MustParse(pattern).Build(post); got != want {
+			t.Errorf("%s: got %q, want %q", pattern, got, want)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	p := MustParse("/:year/:month/:slug")
+
+	m, ok := p.Match("/2026/03/hello-world")
+	if !ok {
+		t.Fatal("expected a match")
+	}
+	if m.Year != 2026 || m.Month != 3 || m.Slug != "hello-world" {
+		t.Errorf("got %+v", m)
+	}
+
+	for _, path := range []string{"/2026/13/hello", "/tag/03/hello", "/2026/03", "/2026/03/hello/more", "/2026//hello"} {
+		if _, ok := p.Match(path); ok {
+			t.Errorf("%s should not match", path)
+		}
+	}
+
+	m, ok = MustParse("/p/:id").Match("/p/42/")
+	if !ok || m.ID != 42 {
+		t.Errorf("id match = %+v, %v", m, ok)
+	}
+	if _, ok := MustParse("/p/:id").Match("/p/abc"); ok {
+		t.Error("non-numeric id should not match")
+	}
+}
+
+func TestMatchRoundTrip(t *testing.T) {
+	post := Post{ID: 7, Slug: "what%27s-new", PublishedAt: time.Date(2025, time.December, 31, 23, 30, 0, 0, time.Local)}
+	p := MustParse(DefaultPattern)
+
+	m, ok := p.Match(p.Build(post))
+	if !ok {
+		t.Fatal("built permalink does not match its own pattern")
+	}
+	if m.Slug != post.Slug || m.Year != 2025 || m.Month != 12 || m.Day != 31 {
+		t.Errorf("got %+v", m)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, pattern := range []string{"post/:slug", "/:year/:month", "/:slug/:slug", "/:title", "/a//:slug"} {
+		if _, err := Parse(pattern); err == nil {
+			t.Errorf("%q should not parse", pattern)
+		}
+	}
+}
+
+func TestConfigure(t *testing.T) {
+	defer Configure("", nil)
+
+	if err := Configure("/posts/:slug", []string{"/p/:id"}); err != nil {
+		t.Fatal(err)
+	}
+	if Current().String() != "/posts/:slug" {
+		t.Errorf("current = %s", Current())
+	}
+	if len(Legacy()) != 2 || Legacy()[1].String() != DefaultPattern {
+		t.Errorf("legacy = %v", Legacy())
+	}
+
+	if err := Configure("/:bogus", nil); err == nil {
+		t.Error("invalid pattern was accepted")
+	}
+}
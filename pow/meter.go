@@ -0,0 +1,36 @@
+package pow
+
+import "time"
+
+// meterBuckets splits the last minute into one-second buckets.
+const meterBuckets = 60
+
+// meter counts events over a sliding minute.
+type meter struct {
+	counts [meterBuckets]int
+	// seconds holds the unix second each bucket was last counted in, so
+	// stale buckets can be told apart from current ones.
+	seconds [meterBuckets]int64
+}
+
+func (m *meter) add(now time.Time) {
+	sec := now.Unix()
+	i := sec % meterBuckets
+	if m.seconds[i] != sec {
+		m.seconds[i] = sec
+		m.counts[i] = 0
+	}
+	m.counts[i]++
+}
+
+// rate is the number of events in the minute up to now.
+func (m *meter) rate(now time.Time) int {
+	sec := now.Unix()
+	total := 0
+	for i, s := range m.seconds {
+		if sec-s < meterBuckets {
+			total += m.counts[i]
+		}
+	}
+	return total
+}
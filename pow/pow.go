@@ -0,0 +1,198 @@
+// Package pow implements a hashcash style proof-of-work challenge for forms
+// that anyone can post. The server hands out a signed challenge with a
+// difficulty, the browser looks for a solution such that
+//
+//	sha256(challenge + solution)
+//
+// starts with at least difficulty zero bits, and the server checks the
+// solution when the form comes back. Difficulty goes up while a form is
+// being flooded.
+package pow
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrInvalid  = errors.New("pow: invalid challenge")
+	ErrExpired  = errors.New("pow: challenge expired")
+	ErrSpent    = errors.New("pow: challenge already used")
+	ErrUnsolved = errors.New("pow: solution does not meet the difficulty")
+)
+
+// maxSolutionLength keeps clients from making the server hash long inputs.
+const maxSolutionLength = 32
+
+// Challenge is what the browser has to solve. Token is passed back with the
+// solution as it is.
+type Challenge struct {
+	Token      string `json:"challenge"`
+	Difficulty int    `json:"difficulty"`
+}
+
+// Guard issues and verifies challenges for one kind of form. Its zero value
+// is not usable; fill in at least Purpose, Secret and Base.
+type Guard struct {
+	// Purpose names the form, such as "login". Challenges for one form can't
+	// be used on another.
+	Purpose string
+	Secret  []byte
+	// Base is the difficulty in bits while things are quiet. Max caps how
+	// far it is raised.
+	Base int
+	Max  int
+	// Threshold is how many submissions a minute are normal. Every doubling
+	// past it adds a bit of difficulty.
+	Threshold int
+	// TTL is how long a challenge can be used for.
+	TTL time.Duration
+	// Now defaults to time.Now.
+	Now func() time.Time
+
+	mu    sync.Mutex
+	spent map[string]time.Time
+	meter meter
+}
+
+func (g *Guard) now() time.Time {
+	if g.Now != nil {
+		return g.Now()
+	}
+	return time.Now()
+}
+
+// Difficulty is the number of zero bits new challenges ask for.
+func (g *Guard) Difficulty() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.difficulty(g.now())
+}
+
+func (g *Guard) difficulty(now time.Time) int {
+	d := g.Base
+	if g.Threshold > 0 {
+		for rate := g.meter.rate(now); rate > g.Threshold && d < g.Max; rate /= 2 {
+			d++
+		}
+	}
+	return d
+}
+
+// Issue returns a new challenge at the current difficulty.
+func (g *Guard) Issue() (Challenge, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return Challenge{}, err
+	}
+
+	now := g.now()
+	g.mu.Lock()
+	difficulty := g.difficulty(now)
+	g.mu.Unlock()
+
+	payload := fmt.Sprintf("%s.%d.%d.%s", g.Purpose, difficulty, now.Unix(), base64.RawURLEncoding.EncodeToString(nonce))
+	return Challenge{Token: payload + "." + g.sign(payload), Difficulty: difficulty}, nil
+}
+
+func (g *Guard) sign(payload string) string {
+	mac := hmac.New(sha256.New, g.Secret)
+	mac.Write([]byte("pow:" + payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a solution to a challenge issued by g. Each challenge can be
+// used once. Every call counts towards the submission rate, so a flood of
+// bad solutions raises the difficulty as well.
+func (g *Guard) Verify(token, solution string) error {
+	now := g.now()
+	g.mu.Lock()
+	g.meter.add(now)
+	g.mu.Unlock()
+
+	payload, sig, ok := cutLast(token, ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(g.sign(payload))) {
+		return ErrInvalid
+	}
+	parts := strings.Split(payload, ".")
+	if len(parts) != 4 || parts[0] != g.Purpose {
+		return ErrInvalid
+	}
+	difficulty, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return ErrInvalid
+	}
+	issued, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return ErrInvalid
+	}
+	expires := time.Unix(issued, 0).Add(g.TTL)
+	if now.After(expires) {
+		return ErrExpired
+	}
+
+	if len(solution) == 0 || len(solution) > maxSolutionLength || LeadingZeroBits(token, solution) < difficulty {
+		return ErrUnsolved
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.pruneSpent(now)
+	if _, ok := g.spent[token]; ok {
+		return ErrSpent
+	}
+	if g.spent == nil {
+		g.spent = make(map[string]time.Time)
+	}
+	g.spent[token] = expires
+	return nil
+}
+
+// pruneSpent forgets challenges that have expired anyway. g.mu must be held.
+func (g *Guard) pruneSpent(now time.Time) {
+	for token, expires := range g.spent {
+		if now.After(expires) {
+			delete(g.spent, token)
+		}
+	}
+}
+
+// LeadingZeroBits counts the zero bits the hash of a solution starts with.
+func LeadingZeroBits(token, solution string) int {
+	sum := sha256.Sum256([]byte(token + solution))
+	n := 0
+	for _, b := range sum {
+		if b != 0 {
+			return n + bits.LeadingZeros8(b)
+		}
+		n += 8
+	}
+	return n
+}
+
+// Solve finds a solution by brute force. The browser does this in JavaScript;
+// Solve is here for tests and tools.
+func Solve(c Challenge) string {
+	for i := 0; ; i++ {
+		solution := strconv.Itoa(i)
+		if LeadingZeroBits(c.Token, solution) >= c.Difficulty {
+			return solution
+		}
+	}
+}
+
+func cutLast(s, sep string) (before, after string, found bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
@@ -0,0 +1,109 @@
+package pow
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func newTestGuard(now *time.Time) *Guard {
+	return &Guard{
+		Purpose:   "comment",
+		Secret:    []byte("secret"),
+		Base:      8,
+		Max:       12,
+		Threshold: 10,
+		TTL:       10 * time.Minute,
+		Now:       func() time.Time { return *now },
+	}
+}
+
+func TestVerify(t *testing.T) {
+	now := time.Date(2026, time.October, 18, 12, 0, 0, 0, time.UTC)
+	g := newTestGuard(&now)
+
+	c, err := g.Issue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Difficulty != 8 {
+		t.Errorf("difficulty = %d", c.Difficulty)
+	}
+	solution := Solve(c)
+
+	if err := g.Verify(c.Token, solution); err != nil {
+		t.Fatalf("valid solution: %v", err)
+	}
+	if err := g.Verify(c.Token, solution); !errors.Is(err, ErrSpent) {
+		t.Errorf("reused solution: %v", err)
+	}
+}
+
+func TestVerifyRejects(t *testing.T) {
+	now := time.Date(2026, time.October, 18, 12, 0, 0, 0, time.UTC)
+	g := newTestGuard(&now)
+	c, _ := g.Issue()
+	solution := Solve(c)
+
+	other := newTestGuard(&now)
+	other.Purpose = "login"
+	forged := newTestGuard(&now)
+	forged.Secret = []byte("other")
+	fc, _ := forged.Issue()
+
+	// Find a string that does not solve the challenge.
+	wrong := "x"
+	for LeadingZeroBits(c.Token, wrong) >= c.Difficulty {
+		wrong += "x"
+	}
+
+	tests := []struct {
+		name     string
+		guard    *Guard
+		token    string
+		solution string
+		want     error
+	}{
+		{"unsolved", g, c.Token, wrong, ErrUnsolved},
+		{"empty solution", g, c.Token, "", ErrUnsolved},
+		{"other form", other, c.Token, solution, ErrInvalid},
+		{"forged", g, fc.Token, Solve(fc), ErrInvalid},
+		{"tampered difficulty", g, "comment.0" + c.Token[len("comment.8"):], solution, ErrInvalid},
+		{"garbage", g, "nonsense", "1", ErrInvalid},
+	}
+	for _, tt := range tests {
+		if err := tt.guard.Verify(tt.token, tt.solution); !errors.Is(err, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, err, tt.want)
+		}
+	}
+
+	now = now.Add(11 * time.Minute)
+	if err := g.Verify(c.Token, solution); !errors.Is(err, ErrExpired) {
+		t.Errorf("expired: got %v", err)
+	}
+}
+
+func TestDifficultyRisesUnderAttack(t *testing.T) {
+	now := time.Date(2026, time.October, 18, 12, 0, 0, 0, time.UTC)
+	g := newTestGuard(&now)
+
+	for range 45 {
+		g.Verify("nonsense", "1")
+	}
+	// 45 a minute against a threshold of 10 is a bit over two doublings.
+	if d := g.Difficulty(); d != 11 {
+		t.Errorf("difficulty under attack = %d", d)
+	}
+
+	for range 1000 {
+		g.Verify("nonsense", "1")
+	}
+	if d := g.Difficulty(); d != g.Max {
+		t.Errorf("difficulty is not capped: %d", d)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if d := g.Difficulty(); d != g.Base {
+		t.Errorf("difficulty after the attack = %d", d)
+	}
+}
@@ -0,0 +1,172 @@
+package qr
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestReedSolomon(t *testing.T) {
+	// HELLO WORLD at 1-M, from the thonky.com QR code tutorial.
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+	want := []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}
+	if got := rsRemainder(data, rsGenerator(10)); !bytes.Equal(got, want) {
+		t.Errorf("error correction = %v, want %v", got, want)
+	}
+}
+
+func TestFormatAndVersionBits(t *testing.T) {
+	for mask, want := range map[int]int{0: 0b101010000010010, 1: 0b101000100100101} {
+		if got := formatBits(mask); got != want {
+			t.Errorf("format bits for mask %d = %015b, want %015b", mask, got, want)
+		}
+	}
+	if got, want := versionBits(7), 0b000111110010010100; got != want {
+		t.Errorf("version 7 bits = %018b, want %018b", got, want)
+	}
+}
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		text    string
+		version int
+	}{
+		{"hello", 1},
+		{strings.Repeat("a", 14), 1},
+		{strings.Repeat("a", 15), 2},
+		{"otpauth://totp/My%20Blog:ann?algorithm=SHA1&digits=6&issuer=My+Blog&period=30&secret=JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP", 7},
+		{strings.Repeat("z", 213), 10},
+	}
+	for _, tt := range tests {
+		c, err := Encode(tt.text)
+		if err != nil {
+			t.Fatalf("Encode(%q): %v", tt.text, err)
+		}
+		if c.Version != tt.version || c.Size != 17+4*tt.version {
+			t.Errorf("Encode(%q) is version %d size %d, want version %d", tt.text, c.Version, c.Size, tt.version)
+		}
+		if got, err := decode(c); err != "" || got != tt.text {
+			t.Errorf("Encode(%q) decodes to %q: %s", tt.text, got, err)
+		}
+	}
+
+	if _, err := Encode(strings.Repeat("z", 214)); err != ErrTooLong {
+		t.Errorf("214 bytes: %v", err)
+	}
+}
+
+func TestSVG(t *testing.T) {
+	c, _ := Encode("hello")
+	svg := c.SVG()
+	if !strings.HasPrefix(svg, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 29 29"`) {
+		t.Errorf("svg = %.80s", svg)
+	}
+	// The top left module of the finder is dark, past the quiet zone.
+	if !strings.Contains(svg, "M4,4h1v1h-1z") {
+		t.Error("finder missing")
+	}
+}
+
+// decode reads c back the way a scanner would, checking the error
+// correction of every block, and returns the text.
+func decode(c *Code) (string, string) {
+	format := 0
+	for i := 0; i <= 5; i++ {
+		format |= b2i(c.Dark(8, i)) << i
+	}
+	format |= b2i(c.Dark(8, 7))<<6 | b2i(c.Dark(8, 8))<<7 | b2i(c.Dark(7, 8))<<8
+	for i := 9; i < 15; i++ {
+		format |= b2i(c.Dark(14-i, 8)) << i
+	}
+	mask := -1
+	for m := range 8 {
+		if formatBits(m) == format {
+			mask = m
+		}
+	}
+	if mask < 0 {
+		return "", "unreadable format bits"
+	}
+
+	var raw []byte
+	var cur, n int
+	for right := c.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		upward := (right+1)&2 == 0
+		for vert := range c.Size {
+			y := vert
+			if upward {
+				y = c.Size - 1 - vert
+			}
+			for j := range 2 {
+				x := right - j
+				if c.isFunction[y][x] {
+					continue
+				}
+				cur = cur<<1 | b2i(c.Dark(x, y) != masked(mask, x, y))
+				if n++; n%8 == 0 {
+					raw = append(raw, byte(cur))
+					cur = 0
+				}
+			}
+		}
+	}
+
+	v := versions[c.Version]
+	blocks := make([][]byte, len(v.blocks))
+	i := 0
+	for col := range v.blocks[len(v.blocks)-1] {
+		for b, size := range v.blocks {
+			if col < size {
+				blocks[b] = append(blocks[b], raw[i])
+				i++
+			}
+		}
+	}
+	var data []byte
+	generator := rsGenerator(v.ecPerBlock)
+	for b, block := range blocks {
+		ec := make([]byte, v.ecPerBlock)
+		for j := range ec {
+			ec[j] = raw[i+j*len(blocks)+b]
+		}
+		if !bytes.Equal(rsRemainder(block, generator), ec) {
+			return "", "error correction mismatch"
+		}
+		data = append(data, block...)
+	}
+
+	var bits bitBuffer
+	for _, b := range data {
+		bits.append(int(b), 8)
+	}
+	read := func(n int) int {
+		v := 0
+		for _, bit := range bits[:n] {
+			v = v<<1 | b2i(bit)
+		}
+		bits = bits[n:]
+		return v
+	}
+	if read(4) != 0b0100 {
+		return "", "not byte mode"
+	}
+	countBits := 8
+	if c.Version >= 10 {
+		countBits = 16
+	}
+	text := make([]byte, read(countBits))
+	for j := range text {
+		text[j] = byte(read(8))
+	}
+	return string(text), ""
+}
+
+func b2i(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,90 @@
+// Package ratelimit counts attempts per key, such as a client IP, over a
+// sliding window. Counts are kept in memory, so each instance of the server
+// limits on its own.
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+type Limiter struct {
+	// Limit is how many attempts a key gets in each Window.
+	Limit  int
+	Window time.Duration
+	Now    func() time.Time
+
+	mu        sync.Mutex
+	attempts  map[string][]time.Time
+	lastSweep time.Time
+}
+
+func New(limit int, window time.Duration) *Limiter {
+	return &Limiter{Limit: limit, Window: window}
+}
+
+// Allow records an attempt for key and reports whether it is within the
+// limit. Refused attempts aren't recorded, so a key that stops trying gets
+// going again once its earlier attempts leave the window.
+func (l *Limiter) Allow(key string) bool {
+	now := l.now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.attempts == nil {
+		l.attempts = make(map[string][]time.Time)
+	}
+	l.sweep(now)
+
+	recent := l.recent(key, now)
+	if len(recent) >= l.Limit {
+		l.attempts[key] = recent
+		return false
+	}
+	l.attempts[key] = append(recent, now)
+	return true
+}
+
+// RetryAfter is how long until key may try again, zero if it may now.
+func (l *Limiter) RetryAfter(key string) time.Duration {
+	now := l.now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	recent := l.recent(key, now)
+	if len(recent) < l.Limit {
+		return 0
+	}
+	return recent[len(recent)-l.Limit].Add(l.Window).Sub(now)
+}
+
+func (l *Limiter) now() time.Time {
+	if l.Now != nil {
+		return l.Now()
+	}
+	return time.Now()
+}
+
+// recent is key's attempts still inside the window, oldest first.
+func (l *Limiter) recent(key string, now time.Time) []time.Time {
+	attempts := l.attempts[key]
+	i := 0
+	for i < len(attempts) && !attempts[i].After(now.Add(-l.Window)) {
+		i++
+	}
+	return attempts[i:]
+}
+
+// sweep forgets keys without recent attempts once per window, so the map
+// doesn't grow with every client ever seen.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.Window {
+		return
+	}
+	l.lastSweep = now
+	for key := range l.attempts {
+		if len(l.recent(key, now)) == 0 {
+			delete(l.attempts, key)
+		}
+	}
+}
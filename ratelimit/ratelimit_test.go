@@ -0,0 +1,54 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiter(t *testing.T) {
+	now := time.Date(2026, time.October, 18, 12, 0, 0, 0, time.UTC)
+	l := New(3, time.Hour)
+	l.Now = func() time.Time { return now }
+
+	for i := range 3 {
+		if !l.Allow("1.2.3.4") {
+			t.Fatalf("attempt %d refused", i+1)
+		}
+		now = now.Add(10 * time.Minute)
+	}
+	if l.Allow("1.2.3.4") {
+		t.Error("fourth attempt allowed")
+	}
+	if !l.Allow("5.6.7.8") {
+		t.Error("other key refused")
+	}
+	if got := l.RetryAfter("1.2.3.4"); got != 30*time.Minute {
+		t.Errorf("retry after = %v, want 30m", got)
+	}
+
+	// The first attempt leaves the window, making room for one more.
+	now = now.Add(30 * time.Minute)
+	if l.RetryAfter("1.2.3.4") != 0 {
+		t.Error("still limited after the first attempt expired")
+	}
+	if !l.Allow("1.2.3.4") {
+		t.Error("attempt refused after the first one expired")
+	}
+	if l.Allow("1.2.3.4") {
+		t.Error("attempt allowed over the limit")
+	}
+}
+
+func TestLimiterSweep(t *testing.T) {
+	now := time.Date(2026, time.October, 18, 12, 0, 0, 0, time.UTC)
+	l := New(1, time.Minute)
+	l.Now = func() time.Time { return now }
+
+	l.Allow("a")
+	l.Allow("b")
+	now = now.Add(2 * time.Minute)
+	l.Allow("c")
+	if len(l.attempts) != 1 {
+		t.Errorf("idle keys kept: %v", l.attempts)
+	}
+}
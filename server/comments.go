@@ -0,0 +1,445 @@
+package server
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"blog.simoni.dev/auth"
+	db "blog.simoni.dev/db/generated"
+	"blog.simoni.dev/md"
+	"blog.simoni.dev/models"
+	"blog.simoni.dev/spam"
+	"blog.simoni.dev/templates/components"
+	"blog.simoni.dev/templates/pages"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultCommentMaxDepth   = 5
+	defaultCommentEditWindow = 15 * time.Minute
+	maxGuestNameLength       = 50
+)
+
+// commentEditWindow is how long authors can edit or delete their comments,
+// set with COMMENT_EDIT_WINDOW as a duration such as 30m.
+func commentEditWindow() time.Duration {
+	if window, err := time.ParseDuration(os.Getenv("COMMENT_EDIT_WINDOW")); err == nil && window >= 0 {
+		return window
+	}
+	return defaultCommentEditWindow
+}
+
+// guestCommentsAllowed reports whether people without an account may comment,
+// set with COMMENT_GUESTS=allow. Guest comments always go through moderation
+// unless moderation is switched off.
+func guestCommentsAllowed() bool {
+	return os.Getenv("COMMENT_GUESTS") == "allow"
+}
+
+// commentMaxDepth is how many levels of a thread are shown inline before
+// readers have to open the rest of it on its own.
+func commentMaxDepth() int {
+	if depth, err := strconv.Atoi(os.Getenv("COMMENT_MAX_DEPTH")); err == nil && depth > 0 {
+		return depth
+	}
+	return defaultCommentMaxDepth
+}
+
+// threadComments arranges comments, oldest first, into a tree of replies
+// below root, or below the post itself when root is nil. Top-level comments
+// are returned newest first, replies oldest first. Branches deeper than
+// commentMaxDepth are cut off and marked with MoreReplies. Deleted comments
+// without live replies are dropped.
+func threadComments(comments []models.Comment, root *int64) []models.Comment {
+	ids := make(map[int64]bool, len(comments))
+	for _, c := range comments {
+		ids[c.ID] = true
+	}
+
+	children := make(map[int64][]models.Comment)
+	var top []models.Comment
+	for _, c := range comments {
+		switch {
+		case root == nil && (c.ParentId == nil || !ids[*c.ParentId]):
+			// Replies whose parent is gone are shown at the top level.
+			top = append(top, c)
+		case c.ParentId != nil:
+			children[*c.ParentId] = append(children[*c.ParentId], c)
+		}
+	}
+	if root != nil {
+		top = children[*root]
+	}
+
+	// A deleted comment is only worth showing when it holds up live replies.
+	var visible func(c models.Comment) bool
+	visible = func(c models.Comment) bool {
+		return !c.Deleted || slices.ContainsFunc(children[c.ID], visible)
+	}
+
+	maxDepth := commentMaxDepth()
+	var build func(c models.Comment, depth int) models.Comment
+	build = func(c models.Comment, depth int) models.Comment {
+		c.Depth = depth
+		var replies []models.Comment
+		for _, reply := range children[c.ID] {
+			if visible(reply) {
+				replies = append(replies, reply)
+			}
+		}
+		if len(replies) == 0 {
+			return c
+		}
+		if depth+1 >= maxDepth {
+			c.MoreReplies = true
+			return c
+		}
+		c.Replies = make([]models.Comment, len(replies))
+		for i, reply := range replies {
+			c.Replies[i] = build(reply, depth+1)
+		}
+		return c
+	}
+
+	var thread []models.Comment
+	for _, c := range top {
+		if visible(c) {
+			thread = append(thread, build(c, 0))
+		}
+	}
+	if root == nil {
+		slices.Reverse(thread)
+	}
+	return thread
+}
+
+func (r *Router) HandleComment(ctx *gin.Context) {
+	var claims *auth.JwtPayload
+	if t, ok := ctx.Get("authToken"); ok && t != nil {
+		claims = t.(*auth.JwtPayload)
+	}
+	if claims == nil && !guestCommentsAllowed() {
+		r.HandleError(ctx, "You must be logged in to comment", nil, nil)
+		return
+	}
+
+	// Accounts made through registration have to verify their address
+	// before they can comment.
+	if claims != nil && !hasAdminPowers(claims) {
+		row, err := r.Queries.GetUserByID(ctx.Request.Context(), int64(claims.UserId))
+		if err != nil {
+			r.HandleError(ctx, "Failed to load your account", nil, err)
+			return
+		}
+		if user := mapUser(row); !user.EmailVerified() {
+			r.HandleError(ctx, "Verify your email address before commenting. You can get a new link from your notification settings.", nil, nil)
+			return
+		}
+	}
+
+	// Signed in users have already proven themselves at login.
+	if claims == nil {
+		if ok, message := r.checkPow(ctx, powComment); !ok {
+			r.HandleError(ctx, message, nil, nil)
+			return
+		}
+	}
+
+	postIdStr := ctx.Param("postId")
+	comment := ctx.PostForm("comment")
+
+	// Signed in users always comment as themselves; only guests pick a name.
+	var author string
+	var userId *int64
+	if claims != nil {
+		author = claims.Username
+		id := int64(claims.UserId)
+		userId = &id
+	} else {
+		author = strings.TrimSpace(ctx.PostForm("name"))
+		if len(author) > maxGuestNameLength {
+			r.HandleError(ctx, "That name is too long", nil, nil)
+			return
+		}
+	}
+
+	if len(comment) == 0 || len(author) == 0 {
+		r.HandleError(ctx, "Name and comment cannot be empty", nil, nil)
+		return
+	}
+
+	pid, err := strconv.ParseInt(postIdStr, 10, 64)
+	if err != nil {
+		r.HandleError(ctx, "Invalid post ID", nil, err)
+		return
+	}
+
+	// Drafts and scheduled posts can't be commented on, even by guessing
+	// their ID.
+	if _, err := r.Queries.GetPublishedPostByID(ctx.Request.Context(), pid); err != nil {
+		r.HandleNotFound(ctx)
+		return
+	}
+
+	var parentId *int64
+	if parentStr := ctx.Query("parent"); parentStr != "" {
+		id, err := strconv.ParseInt(parentStr, 10, 64)
+		if err != nil {
+			r.HandleError(ctx, "Invalid parent comment", nil, err)
+			return
+		}
+		parent, err := r.Queries.GetCommentByID(ctx.Request.Context(), id)
+		if err != nil || parent.BlogPostID != pid {
+			r.HandleError(ctx, "The comment you replied to no longer exists", nil, err)
+			return
+		}
+		parentId = &parent.ID
+	}
+
+	// Rejected comments get the same reply as held ones so bots learn
+	// nothing from it.
+	const heldMessage = "Thanks! Your comment will show up once it has been approved."
+	verdict := r.checkSpam(ctx, author, comment)
+	if verdict.Verdict == spam.Reject {
+		if !r.HandleToast(ctx, heldMessage) {
+			ctx.Status(http.StatusAccepted)
+		}
+		return
+	}
+
+	status, err := r.newCommentStatus(ctx.Request.Context(), claims)
+	if err != nil {
+		r.HandleError(ctx, "Failed to create comment", nil, err)
+		return
+	}
+	var spamReason *string
+	if verdict.Verdict == spam.Hold {
+		status = models.CommentSpam
+		spamReason = &verdict.Reason
+	}
+
+	created, err := r.Queries.CreateComment(ctx.Request.Context(), db.CreateCommentParams{
+		BlogPostID: pid,
+		ParentID:   parentId,
+		UserID:     userId,
+		Author:     author,
+		Comment:    comment,
+		Status:     status,
+		SpamReason: spamReason,
+	})
+	if err != nil {
+		r.HandleError(ctx, "Failed to create comment", nil, err)
+		return
+	}
+	if status == models.CommentApproved {
+		if err := queueNotifications(ctx.Request.Context(), r.Queries, []int64{created.ID}); err != nil {
+			log.Println("Failed to queue comment notifications:", err)
+		}
+	}
+
+	if status != models.CommentApproved && r.HandleToast(ctx, heldMessage) {
+		return
+	}
+
+	r.renderPostComments(ctx, pid)
+}
+
+// renderPostComments responds with the post's comment section, swapped in
+// over the current one.
+func (r *Router) renderPostComments(ctx *gin.Context, postId int64) {
+	dbComments, err := r.Queries.GetCommentsByPostID(ctx.Request.Context(), postId)
+	if err != nil {
+		r.HandleError(ctx, "Failed to load comments", nil, err)
+		return
+	}
+
+	post := models.BlogPost{ID: postId}
+	ctx.Header("HX-Retarget", "#"+post.GetCommentsHtmlId())
+	ctx.Header("HX-Reswap", "innerHTML")
+	ctx.Status(http.StatusOK)
+	components.CommentsComponent(threadComments(mapComments(dbComments), nil)).Render(withCommentForm(createContext(ctx, "")), ctx.Writer)
+}
+
+// canModifyComment reports whether the signed in user may edit or delete c:
+// admins always can, authors only within the edit window.
+func canModifyComment(claims *auth.JwtPayload, c db.Comment) bool {
+	if claims == nil {
+		return false
+	}
+	if hasAdminPowers(claims) {
+		return true
+	}
+	if c.UserID == nil || *c.UserID != int64(claims.UserId) {
+		return false
+	}
+	return time.Since(pgTimeToTime(c.CreatedAt)) < commentEditWindow()
+}
+
+// loadOwnComment loads the comment addressed by the request if the user may
+// change it, writing an error response otherwise.
+func (r *Router) loadOwnComment(ctx *gin.Context) (db.Comment, bool) {
+	postId, err := strconv.ParseInt(ctx.Param("postId"), 10, 64)
+	if err != nil {
+		r.HandleError(ctx, "Invalid post ID", nil, err)
+		return db.Comment{}, false
+	}
+	commentId, err := strconv.ParseInt(ctx.Param("commentId"), 10, 64)
+	if err != nil {
+		r.HandleError(ctx, "Invalid comment ID", nil, err)
+		return db.Comment{}, false
+	}
+
+	var claims *auth.JwtPayload
+	if t, ok := ctx.Get("authToken"); ok && t != nil {
+		claims = t.(*auth.JwtPayload)
+	}
+
+	c, err := r.Queries.GetOwnCommentByID(ctx.Request.Context(), db.GetOwnCommentByIDParams{
+		ID:         commentId,
+		BlogPostID: postId,
+	})
+	if err != nil {
+		r.HandleError(ctx, "That comment no longer exists", nil, err)
+		return c, false
+	}
+	if !canModifyComment(claims, c) {
+		r.HandleError(ctx, "You can no longer change this comment", nil, nil)
+		return c, false
+	}
+	return c, true
+}
+
+// HandleCommentEdit changes the text of a comment. Edits go through the spam
+// filter like new comments, and an approved comment edited by someone other
+// than an admin goes back to moderation if the policy would hold it as new.
+func (r *Router) HandleCommentEdit(ctx *gin.Context) {
+	c, ok := r.loadOwnComment(ctx)
+	if !ok {
+		return
+	}
+
+	comment := strings.TrimSpace(ctx.PostForm("comment"))
+	if len(comment) == 0 {
+		r.HandleError(ctx, "Delete the comment instead", nil, nil)
+		return
+	}
+
+	const heldMessage = "Thanks! Your edit will show up once it has been approved."
+	verdict := r.checkSpam(ctx, c.Author, comment)
+	if verdict.Verdict == spam.Reject {
+		if !r.HandleToast(ctx, heldMessage) {
+			ctx.Status(http.StatusAccepted)
+		}
+		return
+	}
+
+	status, spamReason := c.Status, c.SpamReason
+	claims := ctx.MustGet("authToken").(*auth.JwtPayload)
+	if !hasAdminPowers(claims) && status == models.CommentApproved {
+		var err error
+		status, err = r.newCommentStatus(ctx.Request.Context(), claims)
+		if err != nil {
+			r.HandleError(ctx, "Failed to edit comment", nil, err)
+			return
+		}
+	}
+	if verdict.Verdict == spam.Hold {
+		status = models.CommentSpam
+		spamReason = &verdict.Reason
+	}
+
+	if err := r.Queries.UpdateCommentText(ctx.Request.Context(), db.UpdateCommentTextParams{
+		ID:         c.ID,
+		Comment:    comment,
+		Status:     status,
+		SpamReason: spamReason,
+	}); err != nil {
+		r.HandleError(ctx, "Failed to edit comment", nil, err)
+		return
+	}
+
+	if status != models.CommentApproved && r.HandleToast(ctx, heldMessage) {
+		return
+	}
+
+	r.renderPostComments(ctx, c.BlogPostID)
+}
+
+func (r *Router) HandleCommentDelete(ctx *gin.Context) {
+	c, ok := r.loadOwnComment(ctx)
+	if !ok {
+		return
+	}
+
+	if err := r.Queries.SoftDeleteComment(ctx.Request.Context(), c.ID); err != nil {
+		r.HandleError(ctx, "Failed to delete comment", nil, err)
+		return
+	}
+
+	r.renderPostComments(ctx, c.BlogPostID)
+}
+
+// HandleCommentPreview renders a comment being written the way it will look
+// once posted.
+func (r *Router) HandleCommentPreview(ctx *gin.Context) {
+	_, authed := ctx.Get("authed")
+	if !authed && !guestCommentsAllowed() {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	comment := strings.TrimSpace(ctx.PostForm("comment"))
+	ctx.Status(http.StatusOK)
+	components.CommentPreview(string(md.RenderComment([]byte(comment)))).Render(ctx.Request.Context(), ctx.Writer)
+}
+
+// HandleCommentThread shows the part of a thread below a comment that was too
+// deep to show inline. htmx requests get just the replies to swap in place;
+// everyone else gets a page with the comment and its replies.
+func (r *Router) HandleCommentThread(ctx *gin.Context) {
+	postId, err := strconv.ParseInt(ctx.Param("postId"), 10, 64)
+	if err != nil {
+		r.HandleNotFound(ctx)
+		return
+	}
+	commentId, err := strconv.ParseInt(ctx.Param("commentId"), 10, 64)
+	if err != nil {
+		r.HandleNotFound(ctx)
+		return
+	}
+
+	row, err := r.Queries.GetPublishedPostByID(ctx.Request.Context(), postId)
+	if err != nil {
+		r.HandleNotFound(ctx)
+		return
+	}
+	dbComments, err := r.Queries.GetCommentsByPostID(ctx.Request.Context(), postId)
+	if err != nil {
+		r.HandleInternalServerError(ctx)
+		return
+	}
+	comments := mapComments(dbComments)
+
+	// The root may be deleted and still hold up the thread, so it is looked
+	// up among all of the post's comments.
+	i := slices.IndexFunc(comments, func(c models.Comment) bool { return c.ID == commentId })
+	if i < 0 {
+		r.HandleNotFound(ctx)
+		return
+	}
+	root := comments[i]
+	root.Replies = threadComments(comments, &root.ID)
+
+	ctx.Status(http.StatusOK)
+	if hxRequest, exists := ctx.Get("isHXRequest"); exists && hxRequest.(bool) {
+		components.CommentReplies(root).Render(withCommentForm(createContext(ctx, "")), ctx.Writer)
+		return
+	}
+	post := mapPost(row, nil)
+	pages.CommentThreadPage(post, root).Render(withCommentForm(createContext(ctx, "Thread on "+post.Title)), ctx.Writer)
+}
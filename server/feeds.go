@@ -0,0 +1,190 @@
+package server
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"net/url"
+
+	db "blog.simoni.dev/db/generated"
+	"blog.simoni.dev/feed"
+	"blog.simoni.dev/models"
+	"github.com/gin-gonic/gin"
+)
+
+const feedPostLimit = 20
+
+type feedFormat int
+
+const (
+	feedFormatRss feedFormat = iota
+	feedFormatAtom
+	feedFormatJson
+)
+
+func (r *Router) HandleFeed(ctx *gin.Context) {
+	r.handleIndexFeed(ctx, feedFormatRss)
+}
+
+func (r *Router) HandleAtomFeed(ctx *gin.Context) {
+	r.handleIndexFeed(ctx, feedFormatAtom)
+}
+
+func (r *Router) HandleJsonFeed(ctx *gin.Context) {
+	r.handleIndexFeed(ctx, feedFormatJson)
+}
+
+func (r *Router) HandleTagFeed(ctx *gin.Context) {
+	r.handleTagFeed(ctx, feedFormatRss)
+}
+
+func (r *Router) HandleTagJsonFeed(ctx *gin.Context) {
+	r.handleTagFeed(ctx, feedFormatJson)
+}
+
+func (r *Router) HandleUserFeed(ctx *gin.Context) {
+	r.handleUserFeed(ctx, feedFormatRss)
+}
+
+func (r *Router) HandleUserJsonFeed(ctx *gin.Context) {
+	r.handleUserFeed(ctx, feedFormatJson)
+}
+
+func (r *Router) handleIndexFeed(ctx *gin.Context, format feedFormat) {
+	rows, err := r.Queries.GetPublishedPosts(ctx.Request.Context(), db.GetPublishedPostsParams{
+		CursorTime: postCursor{}.PgTime(),
+		PageSize:   feedPostLimit,
+	})
+	if err != nil {
+		log.Println("Feed failed to get posts:", err)
+		ctx.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	r.writeFeed(ctx, format, rows, "mrchip53's blog", "Latest posts from mrchip53's blog", "/")
+}
+
+func (r *Router) handleTagFeed(ctx *gin.Context, format feedFormat) {
+	tag := ctx.Param("tag")
+
+	rows, err := r.Queries.GetPublishedPostsByTag(ctx.Request.Context(), db.GetPublishedPostsByTagParams{
+		Name:       tag,
+		CursorTime: postCursor{}.PgTime(),
+		PageSize:   feedPostLimit,
+	})
+	if err != nil {
+		log.Println("Tag feed failed to get posts:", err)
+		ctx.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+	// A tag without published posts is unknown as far as readers can tell.
+	if len(rows) == 0 {
+		r.HandleNotFound(ctx)
+		return
+	}
+
+	r.writeFeed(ctx, format, rows, "Posts tagged with "+tag, "Latest posts tagged with "+tag, "/tag/"+url.PathEscape(tag))
+}
+
+func (r *Router) handleUserFeed(ctx *gin.Context, format feedFormat) {
+	username := ctx.Param("username")
+
+	rows, err := r.Queries.GetPostsByAuthor(ctx.Request.Context(), db.GetPostsByAuthorParams{
+		Author:     username,
+		CursorTime: postCursor{}.PgTime(),
+		PageSize:   feedPostLimit,
+	})
+	if err != nil {
+		log.Println("User feed failed to get posts:", err)
+		ctx.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+	if len(rows) == 0 {
+		r.HandleNotFound(ctx)
+		return
+	}
+
+	r.writeFeed(ctx, format, rows, username+"'s posts", "Latest posts by "+username, "/user/"+url.PathEscape(username))
+}
+
+// writeFeed renders rows as a feed for the page at path and serves it with
+// Last-Modified and ETag headers so unchanged feeds are answered with a 304.
+func (r *Router) writeFeed(ctx *gin.Context, format feedFormat, rows []db.BlogPost, title, description, path string) {
+	posts, err := r.loadPostsWithTags(ctx.Request.Context(), rows)
+	if err != nil {
+		log.Println("Feed failed to load tags:", err)
+		ctx.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	f := buildFeed(posts, title, description, path, ctx.Request.URL.EscapedPath())
+
+	var body []byte
+	var contentType string
+	switch format {
+	case feedFormatAtom:
+		body, err = f.Atom()
+		contentType = "application/atom+xml; charset=utf-8"
+	case feedFormatJson:
+		body, err = f.JSON()
+		contentType = "application/feed+json; charset=utf-8"
+	default:
+		body, err = f.RSS()
+		contentType = "application/rss+xml; charset=utf-8"
+	}
+	if err != nil {
+		log.Println("Feed failed to render:", err)
+		ctx.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	serveFeed(ctx, contentType, body, f)
+}
+
+func buildFeed(posts []models.BlogPost, title, description, path, feedPath string) *feed.Feed {
+	f := &feed.Feed{
+		Title:       title,
+		Description: description,
+		Link:        absoluteURL(path),
+		FeedLink:    absoluteURL(feedPath),
+		Items:       make([]feed.Item, len(posts)),
+	}
+
+	for i, post := range posts {
+		link := absoluteURL(post.Permalink())
+		published := post.CreatedAt
+		if post.PublishedAt != nil {
+			published = *post.PublishedAt
+		}
+		tags := make([]string, len(post.Tags))
+		for j, tag := range post.Tags {
+			tags[j] = tag.Name
+		}
+		f.Items[i] = feed.Item{
+			ID:          link,
+			Title:       post.Title,
+			Link:        link,
+			Description: post.Description,
+			ContentHtml: string(parseMarkdown([]byte(post.Content))),
+			Author:      post.Author,
+			AuthorLink:  absoluteURL("/user/" + url.PathEscape(post.Author)),
+			Tags:        tags,
+			Published:   published,
+			Updated:     post.UpdatedAt,
+		}
+	}
+
+	return f
+}
+
+func serveFeed(ctx *gin.Context, contentType string, body []byte, f *feed.Feed) {
+	sum := sha1.Sum(body)
+	ctx.Header("Content-Type", contentType)
+	ctx.Header("ETag", `"`+hex.EncodeToString(sum[:])+`"`)
+	ctx.Header("Cache-Control", "public, max-age=300")
+
+	// ServeContent takes care of If-None-Match and If-Modified-Since.
+	http.ServeContent(ctx.Writer, ctx.Request, "", f.Updated(), bytes.NewReader(body))
+}
@@ -2,11 +2,16 @@ package server
 
 import (
 	"bytes"
+	"context"
 	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"strings"
 	"time"
 
+	db "blog.simoni.dev/db/generated"
 	"blog.simoni.dev/md"
-	"blog.simoni.dev/models"
 	"github.com/gomarkdown/markdown"
 	"github.com/gomarkdown/markdown/ast"
 	"github.com/gomarkdown/markdown/parser"
@@ -31,6 +36,26 @@ func formatAsDateTime(t time.Time) string {
 	return timeString
 }
 
+// maxSlugLength matches the width of the blog_posts.slug column.
+const maxSlugLength = 100
+
+// postSlug derives the slug a post is published under from its title.
+func postSlug(title string) string {
+	return url.QueryEscape(strings.ToLower(strings.ReplaceAll(title, " ", "-")))
+}
+
+// normalizeSlug turns a slug typed into the editor into the form it is
+// stored in. The editor shows slugs unescaped, see BlogPost.EditableSlug.
+func normalizeSlug(slug string) string {
+	return postSlug(strings.Trim(strings.TrimSpace(slug), "/"))
+}
+
+// postPermalink is the permalink of a post row.
+func postPermalink(p db.BlogPost) string {
+	post := mapPost(p, nil)
+	return post.Permalink()
+}
+
 func parseMarkdown(bytes []byte) []byte {
 	extensions := parser.CommonExtensions | parser.AutoHeadingIDs | parser.NoEmptyLineBeforeBlock
 	p := parser.NewWithExtensions(extensions)
@@ -88,9 +113,17 @@ func parseWasmLoader(p *parser.Parser, data []byte, offset int) (int, ast.Node)
 	return i - offset, node
 }
 
-func getSlug(post models.BlogPost) string {
-	t := post.CreatedAt.Local()
-	return fmt.Sprintf("/post/%02d/%02d/%d/%s", t.Month(), t.Day(), t.Year(), post.Slug)
+// siteURL is the public base URL of the blog, used for links that leave the
+// site such as feeds.
+func siteURL() string {
+	if u := os.Getenv("SITE_URL"); u != "" {
+		return strings.TrimRight(u, "/")
+	}
+	return "https://blog.simoni.dev"
+}
+
+func absoluteURL(path string) string {
+	return siteURL() + path
 }
 
 func truncateString(s string, max int) string {
@@ -100,3 +133,29 @@ func truncateString(s string, max int) string {
 
 	return s
 }
+
+// runEvery calls fn straight away and then every interval until ctx is
+// cancelled, logging what it did under name. done is the format of the
+// log line for a run that did something, given how many things it did.
+//
+// The background jobs run on every instance of the blog. Each claims its
+// rows with FOR UPDATE SKIP LOCKED, so instances never work on the same
+// rows and running several of them is safe.
+func runEvery(ctx context.Context, interval time.Duration, name, done string, fn func(context.Context) (int, error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if n, err := fn(ctx); err != nil {
+			log.Println(name, "failed:", err)
+		} else if n > 0 {
+			log.Printf(name+" "+done+"\n", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	db "blog.simoni.dev/db/generated"
+	"blog.simoni.dev/md"
 	"blog.simoni.dev/models"
 	"github.com/jackc/pgx/v5/pgtype"
 )
@@ -23,6 +24,13 @@ func pgTimeToTimePtr(t pgtype.Timestamptz) *time.Time {
 	return &t.Time
 }
 
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
 func mapTag(t db.Tag) models.Tag {
 	return models.Tag{
 		ID:        t.ID,
@@ -51,21 +59,40 @@ func mapPost(p db.BlogPost, tags []models.Tag) models.BlogPost {
 		Description: p.Description,
 		Draft:       p.Draft,
 		PublishedAt: pgTimeToTimePtr(p.PublishedAt),
+		ScheduledAt: pgTimeToTimePtr(p.ScheduledAt),
 		Tags:        tags,
 	}
 }
 
-func mapComment(c db.Comment) models.Comment {
+func mapComment(c db.GetCommentsByPostIDRow) models.Comment {
+	createdAt := pgTimeToTime(c.CreatedAt)
+	if c.DeletedAt.Valid {
+		return models.Comment{
+			ID:         c.ID,
+			CreatedAt:  createdAt,
+			BlogPostId: c.BlogPostID,
+			ParentId:   c.ParentID,
+			Status:     c.Status,
+			Deleted:    true,
+		}
+	}
 	return models.Comment{
-		ID:         c.ID,
-		CreatedAt:  pgTimeToTime(c.CreatedAt),
-		BlogPostId: c.BlogPostID,
-		Author:     c.Author,
-		Comment:    c.Comment,
+		ID:            c.ID,
+		CreatedAt:     createdAt,
+		BlogPostId:    c.BlogPostID,
+		ParentId:      c.ParentID,
+		UserId:        c.UserID,
+		Author:        c.Author,
+		Username:      derefString(c.Username),
+		Comment:       c.Comment,
+		CommentHtml:   string(md.RenderComment([]byte(c.Comment))),
+		Status:        c.Status,
+		EditedAt:      pgTimeToTimePtr(c.EditedAt),
+		EditableUntil: createdAt.Add(commentEditWindow()),
 	}
 }
 
-func mapComments(comments []db.Comment) []models.Comment {
+func mapComments(comments []db.GetCommentsByPostIDRow) []models.Comment {
 	result := make([]models.Comment, len(comments))
 	for i, c := range comments {
 		result[i] = mapComment(c)
@@ -73,13 +100,60 @@ func mapComments(comments []db.Comment) []models.Comment {
 	return result
 }
 
+func mapQueuedComments(comments []db.GetCommentsByStatusRow) []models.Comment {
+	result := make([]models.Comment, len(comments))
+	for i, c := range comments {
+		result[i] = models.Comment{
+			ID:         c.ID,
+			CreatedAt:  pgTimeToTime(c.CreatedAt),
+			BlogPostId: c.BlogPostID,
+			ParentId:   c.ParentID,
+			UserId:     c.UserID,
+			Author:     c.Author,
+			Username:   derefString(c.Username),
+			Comment:    c.Comment,
+			Status:     c.Status,
+			SpamReason: derefString(c.SpamReason),
+			PostTitle:  c.PostTitle,
+		}
+	}
+	return result
+}
+
+func mapRevision(r db.BlogPostRevision) models.PostRevision {
+	return models.PostRevision{
+		ID:         r.ID,
+		CreatedAt:  pgTimeToTime(r.CreatedAt),
+		BlogPostId: r.BlogPostID,
+		Author:     r.Author,
+		Title:      r.Title,
+		Content:    r.Content,
+	}
+}
+
+func mapRevisions(revisions []db.BlogPostRevision) []models.PostRevision {
+	result := make([]models.PostRevision, len(revisions))
+	for i, r := range revisions {
+		result[i] = mapRevision(r)
+	}
+	return result
+}
+
 func mapUser(u db.User) models.User {
 	return models.User{
-		ID:       u.ID,
-		Username: u.Username,
-		Password: u.Password,
-		Admin:    u.Admin,
-		Theme:    u.Theme,
+		ID:              u.ID,
+		Username:        u.Username,
+		Password:        u.Password,
+		Admin:           u.Admin,
+		Theme:           u.Theme,
+		Email:           derefString(u.Email),
+		EmailVerifiedAt: pgTimeToTimePtr(u.EmailVerifiedAt),
+		NotifyComments:  u.NotifyComments,
+		NotifyReplies:   u.NotifyReplies,
+		SessionVersion:  u.SessionVersion,
+		TOTPSecret:      derefString(u.TotpSecret),
+		TOTPEnabledAt:   pgTimeToTimePtr(u.TotpEnabledAt),
+		TOTPLastStep:    u.TotpLastStep,
 	}
 }
 
@@ -94,3 +168,138 @@ func (r *Router) loadPostsWithTags(ctx context.Context, posts []db.BlogPost) ([]
 	}
 	return result, nil
 }
+
+func mapRedirect(r db.Redirect) models.Redirect {
+	return models.Redirect{
+		ID:         r.ID,
+		CreatedAt:  pgTimeToTime(r.CreatedAt),
+		SourcePath: r.SourcePath,
+		TargetPath: r.TargetPath,
+		StatusCode: int(r.StatusCode),
+		Hits:       r.Hits,
+		LastHitAt:  pgTimeToTimePtr(r.LastHitAt),
+	}
+}
+
+func mapBlocklistEntry(b db.SpamBlocklist) models.BlocklistEntry {
+	return models.BlocklistEntry{
+		ID:        b.ID,
+		CreatedAt: pgTimeToTime(b.CreatedAt),
+		Kind:      b.Kind,
+		Value:     b.Value,
+	}
+}
+
+func mapBlocklist(entries []db.SpamBlocklist) []models.BlocklistEntry {
+	result := make([]models.BlocklistEntry, len(entries))
+	for i, b := range entries {
+		result[i] = mapBlocklistEntry(b)
+	}
+	return result
+}
+
+func mapOutboxRow(m db.GetOutboxByStatusRow) models.OutboxMail {
+	return models.OutboxMail{
+		ID:            m.ID,
+		CreatedAt:     pgTimeToTime(m.CreatedAt),
+		Sender:        m.Sender,
+		Recipients:    m.Recipients,
+		Subject:       m.Subject,
+		Status:        m.Status,
+		Attempts:      int(m.Attempts),
+		NextAttemptAt: pgTimeToTime(m.NextAttemptAt),
+		LastError:     derefString(m.LastError),
+		SentAt:        pgTimeToTimePtr(m.SentAt),
+	}
+}
+
+func mapOutbox(mails []db.GetOutboxByStatusRow) []models.OutboxMail {
+	result := make([]models.OutboxMail, len(mails))
+	for i, m := range mails {
+		result[i] = mapOutboxRow(m)
+	}
+	return result
+}
+
+func mapOutboxMail(m db.Outbox) models.OutboxMail {
+	mail := mapOutboxRow(db.GetOutboxByStatusRow{
+		ID:            m.ID,
+		CreatedAt:     m.CreatedAt,
+		UpdatedAt:     m.UpdatedAt,
+		Sender:        m.Sender,
+		Recipients:    m.Recipients,
+		Subject:       m.Subject,
+		Status:        m.Status,
+		Attempts:      m.Attempts,
+		NextAttemptAt: m.NextAttemptAt,
+		LastError:     m.LastError,
+		SentAt:        m.SentAt,
+	})
+	mail.Message = string(m.Message)
+	return mail
+}
+
+func mapRedirects(redirects []db.Redirect) []models.Redirect {
+	result := make([]models.Redirect, len(redirects))
+	for i, r := range redirects {
+		result[i] = mapRedirect(r)
+	}
+	return result
+}
+
+func mapSubscriberStats(counts db.CountSubscribersRow, byTag []db.CountSubscribersByTagRow) models.SubscriberStats {
+	stats := models.SubscriberStats{
+		Active:       counts.Active,
+		AllPosts:     counts.AllPosts,
+		Pending:      counts.Pending,
+		Unsubscribed: counts.Unsubscribed,
+		Bounced:      counts.Bounced,
+		ByTag:        make([]models.TagCount, len(byTag)),
+	}
+	for i, t := range byTag {
+		stats.ByTag[i] = models.TagCount{Name: t.Name, Count: t.Count}
+	}
+	return stats
+}
+
+func mapBouncedSubscribers(rows []db.GetBouncedSubscribersRow) []models.BouncedSubscriber {
+	result := make([]models.BouncedSubscriber, len(rows))
+	for i, s := range rows {
+		result[i] = models.BouncedSubscriber{
+			ID:           s.ID,
+			Email:        s.Email,
+			Bounces:      int(s.Bounces),
+			LastBounceAt: pgTimeToTimePtr(s.LastBounceAt),
+			LastBounce:   derefString(s.LastBounce),
+		}
+	}
+	return result
+}
+
+func mapNewsletterIssues(rows []db.GetNewsletterIssuesRow) []models.NewsletterIssue {
+	result := make([]models.NewsletterIssue, len(rows))
+	for i, n := range rows {
+		result[i] = models.NewsletterIssue{
+			PostID:     n.BlogPostID,
+			Title:      n.Title,
+			CreatedAt:  pgTimeToTime(n.CreatedAt),
+			Recipients: int(n.Recipients),
+			SentAt:     pgTimeToTimePtr(n.SentAt),
+		}
+	}
+	return result
+}
+
+func mapInvites(invites []db.Invite) []models.Invite {
+	result := make([]models.Invite, len(invites))
+	for i, inv := range invites {
+		result[i] = models.Invite{
+			ID:        inv.ID,
+			CreatedAt: pgTimeToTime(inv.CreatedAt),
+			Code:      inv.Code,
+			Note:      inv.Note,
+			UsedAt:    pgTimeToTimePtr(inv.UsedAt),
+		}
+	}
+	return result
+}
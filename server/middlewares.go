@@ -1,9 +1,15 @@
 package server
 
 import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
 	"blog.simoni.dev/auth"
+	db "blog.simoni.dev/db/generated"
 	"github.com/gin-gonic/gin"
-	"log"
+	"github.com/jackc/pgx/v5"
 )
 
 func IsHXRequest() gin.HandlerFunc {
@@ -17,14 +23,27 @@ func AddJwtPayloadToCtx(ctx *gin.Context, jwtPayload *auth.JwtPayload) {
 	ctx.Set("authToken", jwtPayload)
 	ctx.Set("authed", true)
 	ctx.Set("theme", jwtPayload.Theme)
-	ctx.Set("isAdmin", jwtPayload.Admin)
+	ctx.Set("isAdmin", hasAdminPowers(jwtPayload))
 	ctx.Set("username", jwtPayload.Username)
 	ctx.Set("userId", jwtPayload.UserId)
 }
 
-func ExtractAuth() gin.HandlerFunc {
+// hasAdminPowers reports whether claims may use an admin's privileges. When
+// REQUIRE_ADMIN_2FA is set, an admin who signed in without a second factor
+// has none of them, here or on the admin pages, until they turn it on.
+func hasAdminPowers(claims *auth.JwtPayload) bool {
+	return claims != nil && claims.Admin && (claims.TwoFactor || !requireAdminTwoFactor())
+}
+
+// ExtractAuth signs in the user whose auth cookies came with the request.
+// Tokens from before the user's session version last changed, or for users
+// that are gone, are thrown away.
+func ExtractAuth(queries *db.Queries) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		authToken, err := auth.ExtractAuth(ctx)
+		if err == nil {
+			err = checkSessionVersion(ctx, queries, authToken)
+		}
 		if err != nil {
 			log.Printf("Failed to extract auth: %v\n", err)
 			pathLength := len(ctx.Request.URL.Path)
@@ -37,6 +56,18 @@ func ExtractAuth() gin.HandlerFunc {
 
 		AddJwtPayloadToCtx(ctx, authToken)
 
+		// Anyone can have an account once registration is open, so being
+		// signed in isn't enough for the admin pages.
+		if strings.HasPrefix(ctx.Request.URL.Path, adminRoute) && !authToken.Admin {
+			ctx.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+		if strings.HasPrefix(ctx.Request.URL.Path, adminRoute) && !authToken.TwoFactor && requireAdminTwoFactor() {
+			ctx.Redirect(http.StatusFound, twoFactorRoute)
+			ctx.Abort()
+			return
+		}
+
 		if ctx.Request.URL.Path == "/login" {
 			ctx.Redirect(302, "/")
 			ctx.Abort()
@@ -45,3 +76,14 @@ func ExtractAuth() gin.HandlerFunc {
 		ctx.Next()
 	}
 }
+
+var errSessionRevoked = errors.New("session revoked")
+
+func checkSessionVersion(ctx *gin.Context, queries *db.Queries, authToken *auth.JwtPayload) error {
+	version, err := queries.GetUserSessionVersion(ctx.Request.Context(), int64(authToken.UserId))
+	if errors.Is(err, pgx.ErrNoRows) || err == nil && version != authToken.SessionVersion {
+		auth.DeleteAuthCookies(ctx)
+		return errSessionRevoked
+	}
+	return err
+}
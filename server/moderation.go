@@ -0,0 +1,179 @@
+package server
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+
+	"blog.simoni.dev/auth"
+	db "blog.simoni.dev/db/generated"
+	"blog.simoni.dev/models"
+	"blog.simoni.dev/templates/admin"
+	"github.com/gin-gonic/gin"
+)
+
+const moderationPageSize = 100
+
+// Moderation policies, set with COMMENT_MODERATION. Comments by admins are
+// never held.
+const (
+	// moderateNone publishes every comment straight away.
+	moderateNone = "none"
+	// moderateFirstTime holds comments from users who have never had a
+	// comment approved.
+	moderateFirstTime = "first-time"
+	// moderateAll holds every comment.
+	moderateAll = "all"
+)
+
+func commentModeration() string {
+	switch policy := os.Getenv("COMMENT_MODERATION"); policy {
+	case moderateNone, moderateAll:
+		return policy
+	default:
+		return moderateFirstTime
+	}
+}
+
+// newCommentStatus decides whether a new comment by claims goes live or waits
+// in the moderation queue. claims is nil for guests.
+func (r *Router) newCommentStatus(ctx context.Context, claims *auth.JwtPayload) (string, error) {
+	if hasAdminPowers(claims) {
+		return models.CommentApproved, nil
+	}
+
+	switch commentModeration() {
+	case moderateNone:
+		return models.CommentApproved, nil
+	case moderateAll:
+		return models.CommentPending, nil
+	}
+	if claims == nil {
+		return models.CommentPending, nil
+	}
+
+	userId := int64(claims.UserId)
+	approved, err := r.Queries.CountApprovedCommentsByUser(ctx, &userId)
+	if err != nil {
+		return "", err
+	}
+	if approved > 0 {
+		return models.CommentApproved, nil
+	}
+	return models.CommentPending, nil
+}
+
+func (r *Router) HandleAdminComments(ctx *gin.Context) {
+	status := ctx.DefaultQuery("status", models.CommentPending)
+	if !slices.Contains(models.CommentStatuses, status) {
+		r.HandleNotFound(ctx)
+		return
+	}
+
+	comments, counts, err := r.loadModerationQueue(ctx.Request.Context(), status)
+	if err != nil {
+		log.Println("Comment queue failed:", err)
+		ctx.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+	admin.CommentsPage(status, comments, counts).Render(createContext(ctx, "Comments"), ctx.Writer)
+}
+
+// HandleAdminCommentsBulk moves the selected comments to another status, or
+// deletes them, and re-renders the queue they were selected from. Approving
+// or marking as spam also trains the spam classifier, and approving lets
+// people know about the new comments.
+func (r *Router) HandleAdminCommentsBulk(ctx *gin.Context) {
+	current := ctx.DefaultQuery("status", models.CommentPending)
+	if !slices.Contains(models.CommentStatuses, current) {
+		current = models.CommentPending
+	}
+	status := ctx.PostForm("action")
+	if status != models.CommentDelete && !slices.Contains(models.CommentStatuses, status) {
+		r.HandleError(ctx, "Unknown moderation action", nil, nil)
+		return
+	}
+
+	var ids []int64
+	for _, s := range ctx.PostFormArray("ids") {
+		id, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			r.HandleError(ctx, "Invalid comment ID", nil, err)
+			return
+		}
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		r.HandleError(ctx, "No comments selected", nil, nil)
+		return
+	}
+
+	tx, err := r.Pool.Begin(ctx.Request.Context())
+	if err != nil {
+		r.HandleError(ctx, "Failed to update comments", nil, err)
+		return
+	}
+	defer tx.Rollback(ctx.Request.Context())
+	qtx := r.Queries.WithTx(tx)
+
+	if status == models.CommentDelete {
+		err = qtx.SoftDeleteComments(ctx.Request.Context(), ids)
+	} else {
+		err = qtx.SetCommentsStatus(ctx.Request.Context(), db.SetCommentsStatusParams{
+			Status: status,
+			Ids:    ids,
+		})
+	}
+	if err != nil {
+		r.HandleError(ctx, "Failed to update comments", nil, err)
+		return
+	}
+	if err := trainSpamFilter(ctx.Request.Context(), qtx, ids, status); err != nil {
+		r.HandleError(ctx, "Failed to train the spam filter", nil, err)
+		return
+	}
+	if status == models.CommentApproved {
+		if err := queueNotifications(ctx.Request.Context(), qtx, ids); err != nil {
+			r.HandleError(ctx, "Failed to queue notifications", nil, err)
+			return
+		}
+	}
+	if err := tx.Commit(ctx.Request.Context()); err != nil {
+		r.HandleError(ctx, "Failed to update comments", nil, err)
+		return
+	}
+
+	comments, counts, err := r.loadModerationQueue(ctx.Request.Context(), current)
+	if err != nil {
+		r.HandleError(ctx, "Failed to load comments", nil, err)
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+	admin.CommentQueue(current, comments, counts).Render(ctx.Request.Context(), ctx.Writer)
+}
+
+func (r *Router) loadModerationQueue(ctx context.Context, status string) ([]models.Comment, map[string]int64, error) {
+	rows, err := r.Queries.GetCommentsByStatus(ctx, db.GetCommentsByStatusParams{
+		Status:   status,
+		PageSize: moderationPageSize,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	countRows, err := r.Queries.CountCommentsByStatus(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	counts := make(map[string]int64, len(countRows))
+	for _, row := range countRows {
+		counts[row.Status] = row.Count
+	}
+	return mapQueuedComments(rows), counts, nil
+}
@@ -0,0 +1,424 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	netmail "net/mail"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	db "blog.simoni.dev/db/generated"
+	"blog.simoni.dev/mail"
+	"blog.simoni.dev/models"
+	"blog.simoni.dev/newsletter"
+	"blog.simoni.dev/templates/admin"
+	"blog.simoni.dev/templates/email"
+	"blog.simoni.dev/templates/pages"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	newsletterRoute     = "/newsletter"
+	newsletterBatchSize = 50
+	newsletterPageSize  = 50
+	// newsletterMaxBounces is how many permanent delivery failures it takes
+	// to stop mailing a subscriber.
+	newsletterMaxBounces = 3
+
+	defaultNewsletterRateLimit = 5
+)
+
+// newsletterRateLimit is how many confirmation links an IP address, or an
+// email address, may ask for per hour. Set it with NEWSLETTER_RATE_LIMIT.
+func newsletterRateLimit() int {
+	if n, err := strconv.Atoi(os.Getenv("NEWSLETTER_RATE_LIMIT")); err == nil && n > 0 {
+		return n
+	}
+	return defaultNewsletterRateLimit
+}
+
+// queueNewsletter marks a post to be sent to subscribers by RunNewsletter.
+// Call it whenever a post is published; only the first time counts.
+func queueNewsletter(ctx context.Context, queries *db.Queries, postId int64) error {
+	return queries.QueueNewsletterIssue(ctx, postId)
+}
+
+// RunNewsletter turns published posts into emails for subscribers every
+// interval until ctx is cancelled.
+func RunNewsletter(ctx context.Context, pool *pgxpool.Pool, interval time.Duration) {
+	queries := db.New(pool)
+	runEvery(ctx, interval, "Newsletter", "queued %d emails", func(ctx context.Context) (int, error) {
+		total := 0
+		for ctx.Err() == nil {
+			n, more, err := sendNewsletterBatch(ctx, pool, queries)
+			total += n
+			if err != nil || !more {
+				return total, err
+			}
+		}
+		return total, nil
+	})
+}
+
+// sendNewsletterBatch puts the post for the oldest unsent issue in the
+// outbox for the next batch of subscribers. It reports whether there is
+// more to send. A post that was deleted or unpublished before it went out
+// is not sent any further.
+func sendNewsletterBatch(ctx context.Context, pool *pgxpool.Pool, queries *db.Queries) (int, bool, error) {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return 0, false, err
+	}
+	defer tx.Rollback(ctx)
+	qtx := queries.WithTx(tx)
+
+	issue, err := qtx.GetUnsentNewsletterIssue(ctx)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+
+	done := db.UpdateNewsletterIssueParams{
+		BlogPostID:       issue.BlogPostID,
+		LastSubscriberID: issue.LastSubscriberID,
+		Done:             true,
+	}
+	row, err := qtx.GetPostByID(ctx, issue.BlogPostID)
+	if errors.Is(err, pgx.ErrNoRows) || err == nil && row.Draft {
+		if err := qtx.UpdateNewsletterIssue(ctx, done); err != nil {
+			return 0, false, err
+		}
+		return 0, true, tx.Commit(ctx)
+	}
+	if err != nil {
+		return 0, false, err
+	}
+
+	recipients, err := qtx.GetNewsletterRecipients(ctx, db.GetNewsletterRecipientsParams{
+		MaxBounces: newsletterMaxBounces,
+		AfterID:    issue.LastSubscriberID,
+		PostID:     issue.BlogPostID,
+		BatchSize:  newsletterBatchSize,
+	})
+	if err != nil {
+		return 0, false, err
+	}
+
+	if len(recipients) > 0 {
+		dbTags, err := qtx.GetTagsForPost(ctx, row.ID)
+		if err != nil {
+			return 0, false, err
+		}
+		post := newsletterPost(mapPost(row, mapTags(dbTags)))
+		content := string(parseMarkdown([]byte(row.Content)))
+
+		for _, s := range recipients {
+			links, err := newsletterLinks(s.ID)
+			if err != nil {
+				return 0, false, err
+			}
+			msg := newsletter.Compose(post, links)
+			html, err := mail.RenderHTML(ctx, email.NewsletterEmail(post, content, links))
+			if err != nil {
+				return 0, false, err
+			}
+			if err := enqueueMail(ctx, qtx, mail.Message{
+				To:              []string{s.Email},
+				Subject:         msg.Subject,
+				Text:            msg.Text,
+				HTML:            html,
+				ListUnsubscribe: links.Unsubscribe,
+			}); err != nil {
+				return 0, false, err
+			}
+		}
+		done.LastSubscriberID = recipients[len(recipients)-1].ID
+	}
+
+	done.Sent = int32(len(recipients))
+	done.Done = len(recipients) < newsletterBatchSize
+	if err := qtx.UpdateNewsletterIssue(ctx, done); err != nil {
+		return 0, false, err
+	}
+	return len(recipients), true, tx.Commit(ctx)
+}
+
+func newsletterPost(post models.BlogPost) newsletter.Post {
+	tags := make([]string, len(post.Tags))
+	for i, t := range post.Tags {
+		tags[i] = t.Name
+	}
+	return newsletter.Post{
+		Title:       post.Title,
+		Description: post.Description,
+		Author:      post.Author,
+		Content:     post.Content,
+		Tags:        tags,
+		URL:         absoluteURL(post.Permalink()),
+	}
+}
+
+func newsletterLinks(subscriberId int64) (newsletter.Links, error) {
+	token, err := newsletter.UnsubscribeToken(unsubscribeSecret(), subscriberId)
+	if err != nil {
+		return newsletter.Links{}, err
+	}
+	return newsletter.Links{
+		Manage:      absoluteURL(newsletterRoute),
+		Unsubscribe: absoluteURL(newsletterRoute + "/unsubscribe?token=" + url.QueryEscape(token)),
+	}, nil
+}
+
+func (r *Router) HandleNewsletter(ctx *gin.Context) {
+	dbTags, err := r.Queries.GetPublishedTags(ctx.Request.Context())
+	if err != nil {
+		log.Println("Newsletter failed to load tags:", err)
+		ctx.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+	pages.NewsletterPage(mapTags(dbTags)).Render(withPowForms(createContext(ctx, "Newsletter")), ctx.Writer)
+}
+
+// HandleNewsletterSubscribe mails a link to confirm the subscription. The
+// answer is the same whether or not the address is already subscribed, so
+// the form can't be used to find out who is.
+func (r *Router) HandleNewsletterSubscribe(ctx *gin.Context) {
+	const sent = "Check your inbox for a link to confirm your subscription."
+
+	address, err := netmail.ParseAddress(ctx.PostForm("email"))
+	if err != nil || address.Address != ctx.PostForm("email") {
+		r.HandleError(ctx, "That doesn't look like an email address", nil, err)
+		return
+	}
+	sub := newsletter.Subscription{Email: address.Address, AllPosts: ctx.PostForm("posts") != "tags"}
+	if !sub.AllPosts {
+		for _, value := range ctx.PostFormArray("tags") {
+			id, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				r.HandleError(ctx, "Invalid tag", nil, err)
+				return
+			}
+			sub.Tags = append(sub.Tags, id)
+		}
+		if len(sub.Tags) == 0 {
+			r.HandleError(ctx, "Pick at least one tag, or subscribe to every post.", nil, nil)
+			return
+		}
+	}
+
+	// Bots get told the same as everyone else.
+	if ctx.PostForm(honeypotField) != "" {
+		r.newsletterDone(ctx, sent)
+		return
+	}
+	if ok, message := r.checkPow(ctx, powNewsletter); !ok {
+		r.HandleError(ctx, message, nil, nil)
+		return
+	}
+	// Limiting by address as well keeps anyone from flooding someone's
+	// inbox from many IP addresses.
+	if !r.Newsletter.Allow(ctx.ClientIP()) || !r.Newsletter.Allow("email:"+strings.ToLower(sub.Email)) {
+		r.HandleError(ctx, "Too many attempts. Try again later.", nil, nil)
+		return
+	}
+
+	token, err := newsletter.ConfirmToken(unsubscribeSecret(), sub, time.Now())
+	if err != nil {
+		r.HandleError(ctx, "Failed to subscribe", nil, err)
+		return
+	}
+	link := absoluteURL(newsletterRoute + "/confirm?token=" + url.QueryEscape(token))
+	html, err := mail.RenderHTML(ctx.Request.Context(), email.NewsletterConfirmEmail(link))
+	if err != nil {
+		r.HandleError(ctx, "Failed to subscribe", nil, err)
+		return
+	}
+
+	tx, err := r.Pool.Begin(ctx.Request.Context())
+	if err != nil {
+		r.HandleError(ctx, "Failed to subscribe", nil, err)
+		return
+	}
+	defer tx.Rollback(ctx.Request.Context())
+	qtx := r.Queries.WithTx(tx)
+
+	if err := qtx.CreateSubscriber(ctx.Request.Context(), sub.Email); err != nil {
+		r.HandleError(ctx, "Failed to subscribe", nil, err)
+		return
+	}
+	if err := enqueueMail(ctx.Request.Context(), qtx, mail.Message{
+		To:      []string{sub.Email},
+		Subject: "Confirm your subscription",
+		Text:    "Follow this link to start getting new posts by email:\n\n" + link + "\n\nIf you didn't ask for this, ignore this email and you won't hear from us again.\n",
+		HTML:    html,
+	}); err != nil {
+		r.HandleError(ctx, "Failed to subscribe", nil, err)
+		return
+	}
+	if err := tx.Commit(ctx.Request.Context()); err != nil {
+		r.HandleError(ctx, "Failed to subscribe", nil, err)
+		return
+	}
+
+	r.newsletterDone(ctx, sent)
+}
+
+// newsletterDone answers a newsletter form with message.
+func (r *Router) newsletterDone(ctx *gin.Context, message string) {
+	if r.HandleToast(ctx, message) {
+		return
+	}
+	ctx.Status(http.StatusOK)
+	pages.MessagePage("Newsletter", message).Render(createContext(ctx, "Newsletter"), ctx.Writer)
+}
+
+// HandleNewsletterConfirm asks to confirm a subscription link, so that mail
+// scanners following links don't subscribe anyone.
+func (r *Router) HandleNewsletterConfirm(ctx *gin.Context) {
+	token := ctx.Query("token")
+	if _, ok := r.parseConfirmToken(ctx, token); ok {
+		ctx.Status(http.StatusOK)
+		pages.NewsletterConfirmPage(token).Render(createContext(ctx, "Confirm subscription"), ctx.Writer)
+	}
+}
+
+func (r *Router) HandleNewsletterConfirmRequest(ctx *gin.Context) {
+	sub, ok := r.parseConfirmToken(ctx, ctx.Query("token"))
+	if !ok {
+		return
+	}
+
+	tx, err := r.Pool.Begin(ctx.Request.Context())
+	if err != nil {
+		r.HandleError(ctx, "Failed to confirm", nil, err)
+		return
+	}
+	defer tx.Rollback(ctx.Request.Context())
+	qtx := r.Queries.WithTx(tx)
+
+	id, err := qtx.ConfirmSubscriber(ctx.Request.Context(), db.ConfirmSubscriberParams{
+		Email:    sub.Email,
+		AllPosts: sub.AllPosts,
+	})
+	if err == nil {
+		err = qtx.ClearSubscriberTags(ctx.Request.Context(), id)
+	}
+	if err == nil && !sub.AllPosts {
+		err = qtx.AddSubscriberTags(ctx.Request.Context(), db.AddSubscriberTagsParams{SubscriberID: id, TagIds: sub.Tags})
+	}
+	if err == nil {
+		err = tx.Commit(ctx.Request.Context())
+	}
+	if err != nil {
+		r.HandleError(ctx, "Failed to confirm", nil, err)
+		return
+	}
+
+	message := "You're subscribed. New posts will be sent to " + sub.Email + "."
+	if !sub.AllPosts {
+		message = "You're subscribed. New posts with the tags you picked will be sent to " + sub.Email + "."
+	}
+	r.newsletterDone(ctx, message)
+}
+
+// parseConfirmToken writes the response itself when the token is no good.
+func (r *Router) parseConfirmToken(ctx *gin.Context, token string) (newsletter.Subscription, bool) {
+	sub, err := newsletter.ParseConfirmToken(unsubscribeSecret(), token, time.Now())
+	if errors.Is(err, newsletter.ErrExpiredToken) {
+		ctx.Status(http.StatusGone)
+		pages.MessagePage("Link expired", "That confirmation link has expired. Subscribe again to get a new one.").Render(createContext(ctx, "Newsletter"), ctx.Writer)
+		return sub, false
+	}
+	if err != nil {
+		r.HandleNotFound(ctx)
+		return sub, false
+	}
+	return sub, true
+}
+
+// HandleNewsletterUnsubscribe asks to confirm an unsubscribe link.
+func (r *Router) HandleNewsletterUnsubscribe(ctx *gin.Context) {
+	token := ctx.Query("token")
+	if _, err := newsletter.ParseUnsubscribeToken(unsubscribeSecret(), token); err != nil {
+		r.HandleNotFound(ctx)
+		return
+	}
+	ctx.Status(http.StatusOK)
+	pages.NewsletterUnsubscribePage(token).Render(createContext(ctx, "Unsubscribe"), ctx.Writer)
+}
+
+// HandleNewsletterUnsubscribeRequest ends a subscription. It also takes
+// one-click unsubscribes posted by mail clients.
+func (r *Router) HandleNewsletterUnsubscribeRequest(ctx *gin.Context) {
+	id, err := newsletter.ParseUnsubscribeToken(unsubscribeSecret(), ctx.Query("token"))
+	if err != nil {
+		r.HandleNotFound(ctx)
+		return
+	}
+	if err := r.Queries.UnsubscribeSubscriber(ctx.Request.Context(), id); err != nil {
+		r.HandleError(ctx, "Failed to unsubscribe", nil, err)
+		return
+	}
+	r.newsletterDone(ctx, "You're unsubscribed and won't get any more posts by email.")
+}
+
+func (r *Router) HandleAdminNewsletter(ctx *gin.Context) {
+	c := ctx.Request.Context()
+	counts, err := r.Queries.CountSubscribers(c, newsletterMaxBounces)
+	if err != nil {
+		log.Println("Newsletter failed to count subscribers:", err)
+		ctx.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+	byTag, err := r.Queries.CountSubscribersByTag(c, newsletterMaxBounces)
+	if err != nil {
+		log.Println("Newsletter failed to count subscribers by tag:", err)
+		ctx.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+	bounced, err := r.Queries.GetBouncedSubscribers(c, newsletterPageSize)
+	if err != nil {
+		log.Println("Newsletter failed to load bounces:", err)
+		ctx.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+	issues, err := r.Queries.GetNewsletterIssues(c, newsletterPageSize)
+	if err != nil {
+		log.Println("Newsletter failed to load issues:", err)
+		ctx.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+	admin.NewsletterPage(mapSubscriberStats(counts, byTag), mapBouncedSubscribers(bounced), mapNewsletterIssues(issues), newsletterMaxBounces).
+		Render(createContext(ctx, "Newsletter"), ctx.Writer)
+}
+
+// HandleAdminResetSubscriberBounces starts mailing a subscriber who bounced
+// again, for when the problem was on the receiving end and has been fixed.
+func (r *Router) HandleAdminResetSubscriberBounces(ctx *gin.Context) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		r.HandleError(ctx, "Invalid subscriber ID", nil, err)
+		return
+	}
+	if err := r.Queries.ResetSubscriberBounces(ctx.Request.Context(), id); err != nil {
+		r.HandleError(ctx, "Failed to reset bounces", nil, err)
+		return
+	}
+	if r.HandleToast(ctx, "Bounces cleared") {
+		return
+	}
+	ctx.Redirect(http.StatusFound, adminRoute+newsletterRoute)
+}
@@ -0,0 +1,280 @@
+package server
+
+import (
+	"context"
+	"log"
+	"net/http"
+	netmail "net/mail"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"blog.simoni.dev/auth"
+	db "blog.simoni.dev/db/generated"
+	"blog.simoni.dev/mail"
+	"blog.simoni.dev/models"
+	"blog.simoni.dev/notify"
+	"blog.simoni.dev/templates/email"
+	"blog.simoni.dev/templates/pages"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	notifierBatchSize         = 20
+	defaultNotifyDigestDelay  = 5 * time.Minute
+	notificationSettingsRoute = "/settings/notifications"
+)
+
+// notifyDigestDelay is how long a notification waits for others to be sent
+// along with it in a digest, set with NOTIFY_DIGEST_DELAY as a duration.
+func notifyDigestDelay() time.Duration {
+	if d, err := time.ParseDuration(os.Getenv("NOTIFY_DIGEST_DELAY")); err == nil && d >= 0 {
+		return d
+	}
+	return defaultNotifyDigestDelay
+}
+
+func unsubscribeSecret() []byte {
+	return []byte(os.Getenv("JWT_SECRET"))
+}
+
+// queueNotifications records who should hear about newly approved comments.
+// The emails are written later by RunNotifier so no request waits on them.
+// Replies are queued first so someone replied to on their own post hears
+// about it as a reply.
+func queueNotifications(ctx context.Context, queries *db.Queries, commentIds []int64) error {
+	if err := queries.QueueReplyNotifications(ctx, commentIds); err != nil {
+		return err
+	}
+	return queries.QueueCommentNotifications(ctx, commentIds)
+}
+
+// RunNotifier turns queued notifications into emails in the outbox every
+// interval until ctx is cancelled.
+func RunNotifier(ctx context.Context, pool *pgxpool.Pool, interval time.Duration) {
+	queries := db.New(pool)
+	runEvery(ctx, interval, "Notifier", "queued %d emails", func(ctx context.Context) (int, error) {
+		return sendDueNotifications(ctx, pool, queries)
+	})
+}
+
+// sendDueNotifications queues one email for each user whose notifications
+// have waited out the digest delay.
+func sendDueNotifications(ctx context.Context, pool *pgxpool.Pool, queries *db.Queries) (int, error) {
+	userIds, err := queries.GetDueNotificationUsers(ctx, db.GetDueNotificationUsersParams{
+		Cutoff:    pgtype.Timestamptz{Time: time.Now().Add(-notifyDigestDelay()), Valid: true},
+		BatchSize: notifierBatchSize,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, userId := range userIds {
+		ok, err := sendUserNotifications(ctx, pool, queries, userId)
+		if err != nil {
+			log.Printf("Notifier failed to notify user %d: %v\n", userId, err)
+			continue
+		}
+		if ok {
+			sent++
+		}
+	}
+	return sent, nil
+}
+
+// sendUserNotifications puts everything queued for a user in one email in
+// the outbox. Comments that were deleted in the meantime, and kinds the user
+// has since turned off, are dropped. It reports whether an email was queued.
+func sendUserNotifications(ctx context.Context, pool *pgxpool.Pool, queries *db.Queries, userId int64) (bool, error) {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+	qtx := queries.WithTx(tx)
+
+	rows, err := qtx.GetUnsentNotifications(ctx, userId)
+	if err != nil || len(rows) == 0 {
+		return false, err
+	}
+	row, err := qtx.GetUserByID(ctx, userId)
+	if err != nil {
+		return false, err
+	}
+	user := mapUser(row)
+
+	ids := make([]int64, len(rows))
+	var items []notify.Item
+	for i, n := range rows {
+		ids[i] = n.ID
+		if n.CommentStatus != models.CommentApproved || n.CommentDeletedAt.Valid {
+			continue
+		}
+		if n.Kind == notify.KindReply && !user.NotifyReplies || n.Kind == notify.KindComment && !user.NotifyComments {
+			continue
+		}
+		post := models.BlogPost{ID: n.PostID, Slug: n.PostSlug, CreatedAt: pgTimeToTime(n.PostCreatedAt), PublishedAt: pgTimeToTimePtr(n.PostPublishedAt)}
+		comment := models.Comment{ID: n.CommentID}
+		items = append(items, notify.Item{
+			Kind:      n.Kind,
+			Author:    n.CommentAuthor,
+			Comment:   n.CommentText,
+			PostTitle: n.PostTitle,
+			URL:       absoluteURL(post.Permalink() + "#" + comment.GetHtmlId()),
+		})
+	}
+
+	if len(items) > 0 && user.Email != "" && user.EmailVerified() {
+		list := notify.ListFor(items)
+		links := notificationLinks(user.ID, list)
+		msg := notify.Compose(items, links)
+		html, err := mail.RenderHTML(ctx, email.NotificationEmail(msg.Subject, items, links))
+		if err != nil {
+			return false, err
+		}
+		if err := enqueueMail(ctx, qtx, mail.Message{
+			To:              []string{user.Email},
+			Subject:         msg.Subject,
+			Text:            msg.Text,
+			HTML:            html,
+			ListUnsubscribe: links.Unsubscribe,
+		}); err != nil {
+			return false, err
+		}
+	} else {
+		items = nil
+	}
+
+	if err := qtx.MarkNotificationsSent(ctx, ids); err != nil {
+		return false, err
+	}
+	return len(items) > 0, tx.Commit(ctx)
+}
+
+func notificationLinks(userId int64, list string) notify.Links {
+	return notify.Links{
+		Settings:    absoluteURL(notificationSettingsRoute),
+		Unsubscribe: unsubscribeURL(userId, list),
+	}
+}
+
+func unsubscribeURL(userId int64, list string) string {
+	token := notify.UnsubscribeToken(unsubscribeSecret(), userId, list)
+	return absoluteURL("/unsubscribe?token=" + url.QueryEscape(token))
+}
+
+func (r *Router) HandleNotificationSettings(ctx *gin.Context) {
+	user, ok := r.loadSignedInUser(ctx)
+	if !ok {
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+	pages.NotificationSettingsPage(user).Render(createContext(ctx, "Notifications"), ctx.Writer)
+}
+
+func (r *Router) HandleNotificationSettingsUpdate(ctx *gin.Context) {
+	user, ok := r.loadSignedInUser(ctx)
+	if !ok {
+		return
+	}
+
+	var email *string
+	if address := strings.TrimSpace(ctx.PostForm("email")); address != "" {
+		parsed, err := netmail.ParseAddress(address)
+		if err != nil || parsed.Address != address {
+			r.HandleError(ctx, "That doesn't look like an email address", nil, err)
+			return
+		}
+		email = &parsed.Address
+	}
+
+	if err := r.Queries.UpdateUserNotifications(ctx.Request.Context(), db.UpdateUserNotificationsParams{
+		ID:             user.ID,
+		Email:          email,
+		NotifyComments: ctx.PostForm("notifyComments") == "on",
+		NotifyReplies:  ctx.PostForm("notifyReplies") == "on",
+	}); err != nil {
+		r.HandleError(ctx, "Failed to save. Is that email address used by another account?", nil, err)
+		return
+	}
+
+	if email != nil && !strings.EqualFold(*email, user.Email) {
+		user.Email = *email
+		if err := sendVerificationEmail(ctx.Request.Context(), r.Queries, user); err != nil {
+			r.HandleError(ctx, "Saved, but the verification link couldn't be sent", nil, err)
+			return
+		}
+		if r.HandleToast(ctx, "Saved. We've sent a link to "+user.Email+" to verify it") {
+			return
+		}
+		ctx.Redirect(http.StatusFound, notificationSettingsRoute)
+		return
+	}
+
+	if r.HandleToast(ctx, "Notification settings saved") {
+		return
+	}
+	ctx.Redirect(http.StatusFound, notificationSettingsRoute)
+}
+
+// loadSignedInUser loads the user making the request, sending visitors who
+// aren't signed in to the login page.
+func (r *Router) loadSignedInUser(ctx *gin.Context) (models.User, bool) {
+	t, ok := ctx.Get("authToken")
+	if !ok || t == nil {
+		ctx.Redirect(http.StatusFound, "/login?redirect="+ctx.Request.URL.Path)
+		return models.User{}, false
+	}
+	claims := t.(*auth.JwtPayload)
+
+	row, err := r.Queries.GetUserByID(ctx.Request.Context(), int64(claims.UserId))
+	if err != nil {
+		r.HandleError(ctx, "Failed to load your account", nil, err)
+		return models.User{}, false
+	}
+	return mapUser(row), true
+}
+
+// HandleUnsubscribe asks to confirm an unsubscribe link, so that mail
+// scanners following links don't unsubscribe anyone.
+func (r *Router) HandleUnsubscribe(ctx *gin.Context) {
+	token := ctx.Query("token")
+	if _, list, ok := notify.ParseUnsubscribeToken(unsubscribeSecret(), token); ok {
+		ctx.Status(http.StatusOK)
+		pages.UnsubscribePage(token, list, false).Render(createContext(ctx, "Unsubscribe"), ctx.Writer)
+		return
+	}
+	r.HandleNotFound(ctx)
+}
+
+// HandleUnsubscribeRequest unsubscribes from the list in a signed token. It
+// also takes one-click unsubscribes posted by mail clients.
+func (r *Router) HandleUnsubscribeRequest(ctx *gin.Context) {
+	token := ctx.Query("token")
+	userId, list, ok := notify.ParseUnsubscribeToken(unsubscribeSecret(), token)
+	if !ok {
+		r.HandleNotFound(ctx)
+		return
+	}
+
+	c := ctx.Request.Context()
+	var err error
+	if list == notify.ListComments || list == notify.ListAll {
+		err = r.Queries.UnsubscribeUserComments(c, userId)
+	}
+	if err == nil && (list == notify.ListReplies || list == notify.ListAll) {
+		err = r.Queries.UnsubscribeUserReplies(c, userId)
+	}
+	if err != nil {
+		r.HandleError(ctx, "Failed to unsubscribe", nil, err)
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+	pages.UnsubscribePage(token, list, true).Render(createContext(ctx, "Unsubscribed"), ctx.Writer)
+}
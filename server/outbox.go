@@ -0,0 +1,242 @@
+package server
+
+import (
+	"context"
+	"log"
+	"net/http"
+	netmail "net/mail"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+
+	db "blog.simoni.dev/db/generated"
+	"blog.simoni.dev/mail"
+	"blog.simoni.dev/models"
+	"blog.simoni.dev/templates/admin"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	outboxBatchSize = 20
+	// outboxLease is how long a worker has to deliver the mail it claimed
+	// before another may try it.
+	outboxLease       = 15 * time.Minute
+	outboxPageSize    = 100
+	outboxMaxAttempts = 10
+	// The first retry waits outboxBaseDelay, and every one after that twice
+	// as long as the last, up to outboxMaxDelay.
+	outboxBaseDelay = time.Minute
+	outboxMaxDelay  = 12 * time.Hour
+)
+
+// mailFrom is the sender of every email, set with MAIL_FROM. It falls back
+// to the SMTP login and then to an address at the site's domain.
+func mailFrom() string {
+	if from := os.Getenv("MAIL_FROM"); from != "" {
+		return from
+	}
+	if from := os.Getenv("SMTP_USERNAME"); from != "" {
+		return from
+	}
+	host := "localhost"
+	if u, err := url.Parse(siteURL()); err == nil && u.Hostname() != "" {
+		host = u.Hostname()
+	}
+	return "blog@" + host
+}
+
+// mailReplyTo is where replies to the blog's emails go, set with
+// MAIL_REPLY_TO. Without it replies go to the sender.
+func mailReplyTo() string {
+	return os.Getenv("MAIL_REPLY_TO")
+}
+
+// enqueueMail puts an email in the outbox. The sender and reply-to address
+// are filled in unless msg has its own. Pass a transaction's queries to have
+// it sent only if the transaction commits.
+func enqueueMail(ctx context.Context, queries *db.Queries, msg mail.Message) error {
+	if msg.From == "" {
+		msg.From = mailFrom()
+	}
+	if msg.ReplyTo == "" {
+		msg.ReplyTo = mailReplyTo()
+	}
+	raw, err := msg.Bytes()
+	if err != nil {
+		return err
+	}
+
+	// The envelope sender is the bare address.
+	sender := msg.From
+	if addr, err := netmail.ParseAddress(msg.From); err == nil {
+		sender = addr.Address
+	}
+	_, err = queries.EnqueueMail(ctx, db.EnqueueMailParams{
+		Sender:     sender,
+		Recipients: msg.To,
+		Subject:    msg.Subject,
+		Message:    raw,
+	})
+	return err
+}
+
+// outboxBackoff is how long to wait before the next try after attempts
+// failed ones.
+func outboxBackoff(attempts int) time.Duration {
+	delay := outboxBaseDelay
+	for i := 1; i < attempts && delay < outboxMaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, outboxMaxDelay)
+}
+
+// RunOutbox delivers queued mail every interval until ctx is cancelled.
+func RunOutbox(ctx context.Context, pool *pgxpool.Pool, driver mail.Driver, interval time.Duration) {
+	queries := db.New(pool)
+	runEvery(ctx, interval, "Outbox", "delivered %d emails", func(ctx context.Context) (int, error) {
+		return deliverDueMail(ctx, pool, queries, driver)
+	})
+}
+
+// deliverDueMail tries one batch of due mail. Failures are scheduled for
+// another try with exponential backoff, or marked as failed when the mail
+// server refused the message for good or the tries have run out.
+//
+// The batch is claimed for outboxLease rather than locked, so no transaction
+// is held open while talking to the mail server, and each message is marked
+// as soon as it has been tried. Mail that was delivered is never sent again
+// because a later one couldn't be marked.
+func deliverDueMail(ctx context.Context, pool *pgxpool.Pool, queries *db.Queries, driver mail.Driver) (int, error) {
+	due, err := queries.ClaimDueMail(ctx, db.ClaimDueMailParams{
+		LeaseUntil: pgtype.Timestamptz{Time: time.Now().Add(outboxLease), Valid: true},
+		BatchSize:  outboxBatchSize,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	delivered := 0
+	for _, m := range due {
+		err := driver.Deliver(ctx, m.Sender, m.Recipients, m.Message)
+		if err == nil {
+			if err := queries.MarkMailSent(ctx, m.ID); err != nil {
+				return delivered, err
+			}
+			delivered++
+			continue
+		}
+
+		lastError := err.Error()
+		attempts := int(m.Attempts) + 1
+		if mail.IsPermanent(err) {
+			// The receiving server refused it, which counts against
+			// newsletter subscribers.
+			log.Printf("Outbox mail %d was refused: %v\n", m.ID, err)
+			err = markMailRefused(ctx, pool, queries, m, lastError)
+		} else if attempts >= outboxMaxAttempts {
+			log.Printf("Outbox gave up on mail %d after %d attempts: %v\n", m.ID, attempts, err)
+			err = queries.MarkMailFailed(ctx, db.MarkMailFailedParams{ID: m.ID, LastError: &lastError})
+		} else {
+			log.Printf("Outbox failed to deliver mail %d, attempt %d: %v\n", m.ID, attempts, err)
+			err = queries.MarkMailRetry(ctx, db.MarkMailRetryParams{
+				ID:            m.ID,
+				LastError:     &lastError,
+				NextAttemptAt: pgtype.Timestamptz{Time: time.Now().Add(outboxBackoff(attempts)), Valid: true},
+			})
+		}
+		if err != nil {
+			return delivered, err
+		}
+	}
+
+	return delivered, nil
+}
+
+// markMailRefused marks m as failed and counts a bounce against its
+// recipients, both or neither.
+func markMailRefused(ctx context.Context, pool *pgxpool.Pool, queries *db.Queries, m db.Outbox, lastError string) error {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	qtx := queries.WithTx(tx)
+
+	if err := qtx.MarkMailFailed(ctx, db.MarkMailFailedParams{ID: m.ID, LastError: &lastError}); err != nil {
+		return err
+	}
+	if err := qtx.RecordSubscriberBounce(ctx, db.RecordSubscriberBounceParams{Emails: m.Recipients, LastBounce: &lastError}); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *Router) HandleAdminOutbox(ctx *gin.Context) {
+	status := ctx.DefaultQuery("status", models.OutboxQueued)
+	if !slices.Contains(models.OutboxStatuses, status) {
+		r.HandleNotFound(ctx)
+		return
+	}
+
+	rows, err := r.Queries.GetOutboxByStatus(ctx.Request.Context(), db.GetOutboxByStatusParams{
+		Status:   status,
+		PageSize: outboxPageSize,
+	})
+	if err != nil {
+		log.Println("Outbox failed:", err)
+		ctx.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+	countRows, err := r.Queries.CountOutboxByStatus(ctx.Request.Context())
+	if err != nil {
+		log.Println("Outbox failed to count mail:", err)
+		ctx.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+	counts := make(map[string]int64, len(countRows))
+	for _, row := range countRows {
+		counts[row.Status] = row.Count
+	}
+
+	ctx.Status(http.StatusOK)
+	admin.OutboxPage(status, mapOutbox(rows), counts).Render(createContext(ctx, "Outbox"), ctx.Writer)
+}
+
+func (r *Router) HandleAdminOutboxMail(ctx *gin.Context) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		r.HandleNotFound(ctx)
+		return
+	}
+	row, err := r.Queries.GetMailByID(ctx.Request.Context(), id)
+	if err != nil {
+		r.HandleNotFound(ctx)
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+	admin.OutboxMailPage(mapOutboxMail(row)).Render(createContext(ctx, row.Subject), ctx.Writer)
+}
+
+// HandleAdminOutboxResend queues a mail again, whether it failed or was
+// already sent.
+func (r *Router) HandleAdminOutboxResend(ctx *gin.Context) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		r.HandleError(ctx, "Invalid mail ID", nil, err)
+		return
+	}
+	if err := r.Queries.ResendMail(ctx.Request.Context(), id); err != nil {
+		r.HandleError(ctx, "Failed to queue the mail", nil, err)
+		return
+	}
+
+	if r.HandleToast(ctx, "Queued to be sent again") {
+		return
+	}
+	ctx.Redirect(http.StatusFound, adminRoute+"/outbox")
+}
@@ -0,0 +1,140 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	db "blog.simoni.dev/db/generated"
+	"blog.simoni.dev/models"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const postsPageSize = 10
+
+// postCursor is a keyset position in a post listing: the sort time of a post
+// and its id as a tiebreaker.
+type postCursor struct {
+	Time time.Time
+	ID   int64
+}
+
+func (c postCursor) String() string {
+	return fmt.Sprintf("%d-%d", c.Time.UnixMicro(), c.ID)
+}
+
+// PgTime returns the cursor time, or infinity for the start of a listing.
+func (c postCursor) PgTime() pgtype.Timestamptz {
+	if c.Time.IsZero() {
+		return pgtype.Timestamptz{InfinityModifier: pgtype.Infinity, Valid: true}
+	}
+	return pgtype.Timestamptz{Time: c.Time, Valid: true}
+}
+
+func parsePostCursor(s string) (postCursor, bool) {
+	micros, id, ok := strings.Cut(s, "-")
+	if !ok {
+		return postCursor{}, false
+	}
+	m, err := strconv.ParseInt(micros, 10, 64)
+	if err != nil {
+		return postCursor{}, false
+	}
+	i, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return postCursor{}, false
+	}
+	return postCursor{Time: time.UnixMicro(m), ID: i}, true
+}
+
+type pageRequest struct {
+	Cursor postCursor
+	Before bool
+	Page   int
+}
+
+func parsePageRequest(ctx *gin.Context) pageRequest {
+	req := pageRequest{Page: 1}
+	if c, ok := parsePostCursor(ctx.Query("after")); ok {
+		req.Cursor = c
+		req.Page = 2
+	} else if c, ok := parsePostCursor(ctx.Query("before")); ok {
+		req.Cursor = c
+		req.Before = true
+	}
+	if page, err := strconv.Atoi(ctx.Query("page")); err == nil && page > 0 {
+		req.Page = page
+	}
+	return req
+}
+
+func isPartialRequest(ctx *gin.Context) bool {
+	hxRequest, exists := ctx.Get("isHXRequest")
+	return exists && hxRequest.(bool) && ctx.Query("partial") == "true"
+}
+
+// publishedSortTime is the sort key of public listings.
+func publishedSortTime(p db.BlogPost) time.Time {
+	return pgTimeToTime(p.PublishedAt)
+}
+
+// adminSortTime is the sort key of admin listings, which include drafts that
+// have never been published.
+func adminSortTime(p db.BlogPost) time.Time {
+	if p.PublishedAt.Valid {
+		return p.PublishedAt.Time
+	}
+	return pgTimeToTime(p.CreatedAt)
+}
+
+type postPageQuery func(ctx context.Context, cursor postCursor, pageSize int32) ([]db.BlogPost, error)
+
+// loadPostPage loads the page of posts described by the request's query
+// string. after fetches posts older than a cursor, newest first; before
+// fetches posts newer than a cursor, oldest first. One extra row is requested
+// to find out whether another page exists in that direction.
+func (r *Router) loadPostPage(ctx *gin.Context, path string, sortTime func(db.BlogPost) time.Time, after, before postPageQuery) ([]models.BlogPost, models.Pagination, error) {
+	req := parsePageRequest(ctx)
+	pagination := models.Pagination{Path: path, Page: req.Page}
+
+	query := after
+	if req.Before {
+		query = before
+	}
+	rows, err := query(ctx.Request.Context(), req.Cursor, postsPageSize+1)
+	if err != nil {
+		return nil, pagination, err
+	}
+
+	more := len(rows) > postsPageSize
+	if more {
+		rows = rows[:postsPageSize]
+	}
+	if req.Before {
+		if !more {
+			pagination.Page = 1
+		}
+		for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+			rows[i], rows[j] = rows[j], rows[i]
+		}
+	}
+
+	if len(rows) > 0 {
+		first := rows[0]
+		last := rows[len(rows)-1]
+		hasNext := more || req.Before
+		hasPrev := (req.Before && more) || (!req.Before && !req.Cursor.Time.IsZero())
+		if hasNext {
+			pagination.NextCursor = postCursor{Time: sortTime(last), ID: last.ID}.String()
+		}
+		if hasPrev {
+			pagination.PrevCursor = postCursor{Time: sortTime(first), ID: first.ID}.String()
+		}
+	}
+
+	posts, err := r.loadPostsWithTags(ctx.Request.Context(), rows)
+	return posts, pagination, err
+}
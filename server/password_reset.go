@@ -0,0 +1,213 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"blog.simoni.dev/auth"
+	db "blog.simoni.dev/db/generated"
+	"blog.simoni.dev/mail"
+	"blog.simoni.dev/models"
+	"blog.simoni.dev/templates/email"
+	"blog.simoni.dev/templates/pages"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const defaultPasswordResetRateLimit = 5
+
+// passwordResetRateLimit is how many reset links an IP address, or an email
+// address, may ask for per hour. Set it with PASSWORD_RESET_RATE_LIMIT.
+func passwordResetRateLimit() int {
+	if n, err := strconv.Atoi(os.Getenv("PASSWORD_RESET_RATE_LIMIT")); err == nil && n > 0 {
+		return n
+	}
+	return defaultPasswordResetRateLimit
+}
+
+func (r *Router) HandleForgotPassword(ctx *gin.Context) {
+	ctx.Status(http.StatusOK)
+	pages.ForgotPasswordPage("").Render(createContext(ctx, "Forgot password"), ctx.Writer)
+}
+
+// HandleForgotPasswordRequest mails a reset link to the account using the
+// address given. The reply is the same whether or not there is one, so the
+// form can't be used to find out who has an account.
+func (r *Router) HandleForgotPasswordRequest(ctx *gin.Context) {
+	address := strings.TrimSpace(ctx.PostForm("email"))
+	if address == "" {
+		r.HandleError(ctx, "Enter your email address", func(ctx *gin.Context) {
+			pages.ForgotPasswordPage("Enter your email address").Render(createContext(ctx, "Forgot password"), ctx.Writer)
+		}, nil)
+		return
+	}
+
+	// Limiting by address as well keeps anyone from flooding someone's
+	// inbox from many IP addresses.
+	if !r.PasswordReset.Allow(ctx.ClientIP()) || !r.PasswordReset.Allow("email:"+strings.ToLower(address)) {
+		const message = "Too many attempts. Try again later."
+		r.HandleError(ctx, message, func(ctx *gin.Context) {
+			pages.ForgotPasswordPage(message).Render(createContext(ctx, "Forgot password"), ctx.Writer)
+		}, nil)
+		return
+	}
+
+	if err := r.sendPasswordReset(ctx.Request.Context(), address); err != nil {
+		log.Println("Forgot password failed:", err)
+	}
+
+	ctx.Status(http.StatusOK)
+	pages.MessagePage("Check your email", "If an account uses "+address+", we've sent it a link to reset the password. The link works for an hour.").
+		Render(createContext(ctx, "Forgot password"), ctx.Writer)
+}
+
+// sendPasswordReset stores a new reset token for the account using address
+// and mails it the link. Addresses that haven't been verified get nothing,
+// since they might not belong to the account holder.
+func (r *Router) sendPasswordReset(ctx context.Context, address string) error {
+	row, err := r.Queries.GetUserByEmail(ctx, address)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	user := mapUser(row)
+	if !user.EmailVerified() {
+		return nil
+	}
+
+	token, hash, err := auth.NewPasswordResetToken()
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	qtx := r.Queries.WithTx(tx)
+
+	if err := qtx.CreatePasswordReset(ctx, db.CreatePasswordResetParams{
+		UserID:    user.ID,
+		TokenHash: hash,
+		ExpiresAt: pgtype.Timestamptz{Time: time.Now().Add(auth.PasswordResetTTL), Valid: true},
+	}); err != nil {
+		return err
+	}
+	if err := sendPasswordResetEmail(ctx, qtx, user, token); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func sendPasswordResetEmail(ctx context.Context, queries *db.Queries, user models.User, token string) error {
+	link := absoluteURL("/reset-password?token=" + url.QueryEscape(token))
+	html, err := mail.RenderHTML(ctx, email.PasswordResetEmail(user.Username, link))
+	if err != nil {
+		return err
+	}
+	return enqueueMail(ctx, queries, mail.Message{
+		To:      []string{user.Email},
+		Subject: "Reset your password",
+		Text:    "Hi " + user.Username + ",\n\nSomeone asked to reset the password for your account. Follow this link to pick a new one:\n\n" + link + "\n\nThe link works for an hour, and only once. If it wasn't you, ignore this email and your password stays the same.\n",
+		HTML:    html,
+	})
+}
+
+// HandleResetPassword shows the form for a new password. The token is only
+// used up once the form is sent, so mail scanners following the link don't
+// spend it.
+func (r *Router) HandleResetPassword(ctx *gin.Context) {
+	token := ctx.Query("token")
+	valid, err := r.Queries.IsPasswordResetValid(ctx.Request.Context(), auth.HashPasswordResetToken(token))
+	if err != nil {
+		log.Println("Reset password failed to check the token:", err)
+		ctx.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+	if !valid {
+		r.renderResetLinkExpired(ctx)
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+	pages.ResetPasswordPage(token, "").Render(createContext(ctx, "Reset password"), ctx.Writer)
+}
+
+// HandleResetPasswordRequest sets the new password, uses up the token and
+// signs the user out everywhere.
+func (r *Router) HandleResetPasswordRequest(ctx *gin.Context) {
+	token := ctx.PostForm("token")
+	password := ctx.PostForm("password")
+
+	fail := func(message string, err error) {
+		if err != nil {
+			log.Println("Reset password failed:", err)
+		}
+		r.HandleError(ctx, message, func(ctx *gin.Context) {
+			pages.ResetPasswordPage(token, message).Render(createContext(ctx, "Reset password"), ctx.Writer)
+		}, nil)
+	}
+
+	if len(password) < minPasswordLength {
+		fail("Password must be at least 8 characters.", nil)
+		return
+	}
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		fail("Failed to change your password.", err)
+		return
+	}
+
+	c := ctx.Request.Context()
+	tx, err := r.Pool.Begin(c)
+	if err != nil {
+		fail("Failed to change your password.", err)
+		return
+	}
+	defer tx.Rollback(c)
+	qtx := r.Queries.WithTx(tx)
+
+	userId, err := qtx.UsePasswordReset(c, auth.HashPasswordResetToken(token))
+	if errors.Is(err, pgx.ErrNoRows) {
+		r.renderResetLinkExpired(ctx)
+		return
+	}
+	if err != nil {
+		fail("Failed to change your password.", err)
+		return
+	}
+	if _, err := qtx.UpdateUserPassword(c, db.UpdateUserPasswordParams{ID: userId, Password: hash}); err != nil {
+		fail("Failed to change your password.", err)
+		return
+	}
+	if err := qtx.DeletePasswordResets(c, userId); err != nil {
+		fail("Failed to change your password.", err)
+		return
+	}
+	if err := tx.Commit(c); err != nil {
+		fail("Failed to change your password.", err)
+		return
+	}
+
+	auth.DeleteAuthCookies(ctx)
+	ctx.Status(http.StatusOK)
+	pages.MessagePage("Password changed", "You've been signed out everywhere. Log in with your new password.").
+		Render(createContext(ctx, "Reset password"), ctx.Writer)
+}
+
+func (r *Router) renderResetLinkExpired(ctx *gin.Context) {
+	ctx.Status(http.StatusGone)
+	pages.MessagePage("Link expired", "That reset link has expired or has already been used. You can ask for a new one.").
+		Render(createContext(ctx, "Reset password"), ctx.Writer)
+}
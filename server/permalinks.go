@@ -0,0 +1,74 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+
+	db "blog.simoni.dev/db/generated"
+	"blog.simoni.dev/permalink"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// resolvePermalink finds the published post a permalink refers to. Slugs the
+// post was renamed from resolve too. Dates are not checked here: callers
+// compare the request against the post's canonical permalink instead.
+func (r *Router) resolvePermalink(ctx context.Context, match permalink.Match) (db.BlogPost, error) {
+	if match.ID != 0 {
+		return r.Queries.GetPublishedPostByID(ctx, match.ID)
+	}
+
+	row, err := r.Queries.GetPublishedPostBySlug(ctx, match.Slug)
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return row, err
+	}
+	postId, err := r.Queries.GetPostIDByOldSlug(ctx, match.Slug)
+	if err != nil {
+		return db.BlogPost{}, err
+	}
+	return r.Queries.GetPublishedPostByID(ctx, postId)
+}
+
+// isPermalinkGone reports whether a permalink belonged to a deleted post.
+func (r *Router) isPermalinkGone(ctx context.Context, match permalink.Match) bool {
+	var deleted bool
+	var err error
+	if match.ID != 0 {
+		deleted, err = r.Queries.IsPostIDDeleted(ctx, match.ID)
+	} else {
+		deleted, err = r.Queries.IsPostSlugDeleted(ctx, match.Slug)
+	}
+	if err != nil {
+		log.Println("Failed to check for deleted post:", err)
+	}
+	return deleted
+}
+
+// serveLegacyPermalink redirects links in a permalink pattern the site no
+// longer uses to the post's current permalink. It reports whether a response
+// was written.
+func (r *Router) serveLegacyPermalink(ctx *gin.Context) bool {
+	if ctx.Request.Method != http.MethodGet && ctx.Request.Method != http.MethodHead {
+		return false
+	}
+
+	path := ctx.Request.URL.EscapedPath()
+	for _, pattern := range permalink.Legacy() {
+		match, ok := pattern.Match(path)
+		if !ok {
+			continue
+		}
+		row, err := r.resolvePermalink(ctx.Request.Context(), match)
+		if err != nil {
+			if !errors.Is(err, pgx.ErrNoRows) {
+				log.Println("Failed to resolve legacy permalink:", err)
+			}
+			continue
+		}
+		ctx.Redirect(http.StatusMovedPermanently, postPermalink(row))
+		return true
+	}
+	return false
+}
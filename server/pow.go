@@ -0,0 +1,129 @@
+package server
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"blog.simoni.dev/pow"
+	"github.com/gin-gonic/gin"
+)
+
+// Forms a proof-of-work challenge can be required on.
+const (
+	powLogin      = "login"
+	powComment    = "comment"
+	powRegister   = "register"
+	powNewsletter = "newsletter"
+)
+
+const (
+	defaultPowForms         = powLogin + "," + powComment + "," + powRegister + "," + powNewsletter
+	defaultPowDifficulty    = 16
+	defaultPowMaxDifficulty = 24
+	// powThreshold is how many submissions of a form a minute are normal
+	// before the difficulty starts to rise.
+	powThreshold = 30
+	powTTL       = 10 * time.Minute
+)
+
+// powForms lists the forms that need a solved challenge, set with POW_FORMS
+// as a comma separated list. POW_FORMS=none turns the challenge off.
+func powForms() []string {
+	env, ok := os.LookupEnv("POW_FORMS")
+	if !ok {
+		env = defaultPowForms
+	}
+	var forms []string
+	for _, form := range strings.Split(env, ",") {
+		switch form = strings.TrimSpace(form); form {
+		case powLogin, powComment, powRegister, powNewsletter:
+			forms = append(forms, form)
+		case "", "none":
+		default:
+			log.Printf("Unknown proof-of-work form %q in POW_FORMS\n", form)
+		}
+	}
+	return forms
+}
+
+// withPowForms adds the forms that need a challenge to the context of a page
+// with one of them on it.
+func withPowForms(ct context.Context) context.Context {
+	return context.WithValue(ct, "powForms", powForms())
+}
+
+// powDifficulty is the number of leading zero bits asked for while a form
+// is quiet and the most it is raised to under attack, set with
+// POW_DIFFICULTY and POW_MAX_DIFFICULTY.
+func powDifficulty() (base, ceiling int) {
+	base, ceiling = defaultPowDifficulty, defaultPowMaxDifficulty
+	if n, err := strconv.Atoi(os.Getenv("POW_DIFFICULTY")); err == nil && n >= 0 && n <= 32 {
+		base = n
+	}
+	if n, err := strconv.Atoi(os.Getenv("POW_MAX_DIFFICULTY")); err == nil && n <= 32 {
+		ceiling = n
+	}
+	return base, max(base, ceiling)
+}
+
+// newPowGuards sets up a guard for every form that needs a challenge.
+func newPowGuards() map[string]*pow.Guard {
+	base, ceiling := powDifficulty()
+	guards := make(map[string]*pow.Guard)
+	for _, form := range powForms() {
+		guards[form] = &pow.Guard{
+			Purpose:   form,
+			Secret:    []byte(os.Getenv("JWT_SECRET")),
+			Base:      base,
+			Max:       ceiling,
+			Threshold: powThreshold,
+			TTL:       powTTL,
+		}
+	}
+	return guards
+}
+
+// HandlePowChallenge hands out a challenge for the form named in the path.
+func (r *Router) HandlePowChallenge(ctx *gin.Context) {
+	guard, ok := r.Pow[ctx.Param("form")]
+	if !ok {
+		r.HandleNotFound(ctx)
+		return
+	}
+
+	challenge, err := guard.Issue()
+	if err != nil {
+		log.Println("Proof-of-work challenge failed:", err)
+		ctx.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+	ctx.JSON(http.StatusOK, challenge)
+}
+
+// checkPow verifies the challenge posted with form, if it needs one. It
+// reports whether the request may go ahead; when it may not, message says
+// what to tell the visitor.
+func (r *Router) checkPow(ctx *gin.Context, form string) (ok bool, message string) {
+	guard, required := r.Pow[form]
+	if !required {
+		return true, ""
+	}
+
+	err := guard.Verify(ctx.PostForm("pow_challenge"), ctx.PostForm("pow_solution"))
+	switch err {
+	case nil:
+		return true, ""
+	case pow.ErrExpired:
+		return false, "That took a while. Please try again."
+	default:
+		log.Printf("Proof-of-work for %s failed: %v\n", form, err)
+		return false, "Your browser could not be verified. Please make sure JavaScript is enabled and try again."
+	}
+}
@@ -0,0 +1,65 @@
+package server
+
+import (
+	"context"
+	"log"
+	"time"
+
+	db "blog.simoni.dev/db/generated"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const publisherBatchSize = 20
+
+// RunPublisher publishes scheduled posts once their time has come, checking
+// every interval until ctx is cancelled.
+func RunPublisher(ctx context.Context, pool *pgxpool.Pool, interval time.Duration) {
+	queries := db.New(pool)
+	runEvery(ctx, interval, "Publisher", "published %d scheduled posts", func(ctx context.Context) (int, error) {
+		return publishDuePosts(ctx, pool, queries)
+	})
+}
+
+// publishDuePosts publishes one batch of due posts. Each post is published in
+// its own savepoint so a single bad row doesn't hold up the rest of the batch.
+// Posts keep the slug chosen in the editor, and are queued for the
+// newsletter when published for the first time.
+func publishDuePosts(ctx context.Context, pool *pgxpool.Pool, queries *db.Queries) (int, error) {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	due, err := queries.WithTx(tx).GetDueScheduledPosts(ctx, publisherBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	published := 0
+	for _, post := range due {
+		sp, err := tx.Begin(ctx)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := queries.WithTx(sp).PublishScheduledPost(ctx, post.ID); err != nil {
+			log.Printf("Publisher failed to publish post %d: %v\n", post.ID, err)
+			sp.Rollback(ctx)
+			continue
+		}
+		// A post that was published before has gone to subscribers already.
+		if !post.PublishedAt.Valid {
+			if err := queueNewsletter(ctx, queries.WithTx(sp), post.ID); err != nil {
+				log.Printf("Publisher failed to queue post %d for the newsletter: %v\n", post.ID, err)
+				sp.Rollback(ctx)
+				continue
+			}
+		}
+		if err := sp.Commit(ctx); err != nil {
+			return 0, err
+		}
+		published++
+	}
+
+	return published, tx.Commit(ctx)
+}
@@ -0,0 +1,139 @@
+package server
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	db "blog.simoni.dev/db/generated"
+	"blog.simoni.dev/permalink"
+	"blog.simoni.dev/templates/admin"
+	"blog.simoni.dev/templates/pages"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+var redirectStatusCodes = []int{
+	http.StatusMovedPermanently,
+	http.StatusFound,
+	http.StatusTemporaryRedirect,
+	http.StatusPermanentRedirect,
+}
+
+// HandleNoRoute serves unknown paths, following a redirect if an admin has
+// set one up for the path or if it is a permalink in an old pattern.
+// Permalinks of deleted posts are gone rather than not found.
+func (r *Router) HandleNoRoute(ctx *gin.Context) {
+	if r.serveRedirect(ctx) {
+		return
+	}
+	if r.serveLegacyPermalink(ctx) {
+		return
+	}
+	match, ok := permalink.Current().Match(ctx.Request.URL.EscapedPath())
+	if ok && r.isPermalinkGone(ctx.Request.Context(), match) {
+		r.HandleGone(ctx)
+		return
+	}
+	r.HandleNotFound(ctx)
+}
+
+func (r *Router) HandleGone(ctx *gin.Context) {
+	ctx.Status(http.StatusGone)
+	pages.GonePage().Render(createContext(ctx, "Gone"), ctx.Writer)
+}
+
+// serveRedirect redirects the request if its path is in the redirects table,
+// counting the hit. It reports whether a redirect was written.
+func (r *Router) serveRedirect(ctx *gin.Context) bool {
+	redirect, err := r.Queries.HitRedirect(ctx.Request.Context(), ctx.Request.URL.Path)
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			log.Println("Failed to look up redirect:", err)
+		}
+		return false
+	}
+	ctx.Redirect(int(redirect.StatusCode), redirect.TargetPath)
+	return true
+}
+
+func (r *Router) HandleAdminRedirects(ctx *gin.Context) {
+	rows, err := r.Queries.GetRedirects(ctx.Request.Context())
+	if err != nil {
+		log.Println("Redirects failed:", err)
+		ctx.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+	admin.RedirectsPage(mapRedirects(rows), redirectStatusCodes).Render(createContext(ctx, "Redirects"), ctx.Writer)
+}
+
+func (r *Router) HandleAdminCreateRedirect(ctx *gin.Context) {
+	source, ok := parseRedirectPath(ctx.PostForm("source"))
+	if !ok || source == "/" {
+		r.HandleError(ctx, "The source must be a path like /old-page", nil, nil)
+		return
+	}
+	target := strings.TrimSpace(ctx.PostForm("target"))
+	if !isRedirectTarget(target) {
+		r.HandleError(ctx, "The target must be a path or an http(s) URL", nil, nil)
+		return
+	}
+	if source == target {
+		r.HandleError(ctx, "A redirect can't point at itself", nil, nil)
+		return
+	}
+	status, err := strconv.Atoi(ctx.PostForm("status"))
+	if err != nil || !slices.Contains(redirectStatusCodes, status) {
+		r.HandleError(ctx, "Invalid status code", nil, err)
+		return
+	}
+
+	if _, err := r.Queries.CreateRedirect(ctx.Request.Context(), db.CreateRedirectParams{
+		SourcePath: source,
+		TargetPath: target,
+		StatusCode: int32(status),
+	}); err != nil {
+		r.HandleError(ctx, "Failed to create redirect. Is there one for that path already?", nil, err)
+		return
+	}
+
+	ctx.Redirect(http.StatusFound, adminRoute+"/redirects")
+}
+
+func (r *Router) HandleAdminDeleteRedirect(ctx *gin.Context) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		r.HandleError(ctx, "Invalid redirect ID", nil, err)
+		return
+	}
+	if err := r.Queries.DeleteRedirect(ctx.Request.Context(), id); err != nil {
+		r.HandleError(ctx, "Failed to delete redirect", nil, err)
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+}
+
+// parseRedirectPath reduces a source typed by an admin to the path requests
+// are matched on, dropping any scheme, host or query string.
+func parseRedirectPath(s string) (string, bool) {
+	u, err := url.Parse(strings.TrimSpace(s))
+	if err != nil || !strings.HasPrefix(u.Path, "/") {
+		return "", false
+	}
+	return u.Path, true
+}
+
+func isRedirectTarget(s string) bool {
+	if strings.HasPrefix(s, "/") && !strings.HasPrefix(s, "//") {
+		return true
+	}
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
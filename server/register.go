@@ -0,0 +1,375 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"log"
+	"net/http"
+	netmail "net/mail"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"blog.simoni.dev/auth"
+	db "blog.simoni.dev/db/generated"
+	"blog.simoni.dev/mail"
+	"blog.simoni.dev/models"
+	"blog.simoni.dev/templates/admin"
+	"blog.simoni.dev/templates/email"
+	"blog.simoni.dev/templates/pages"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// Registration modes, picked with REGISTRATION.
+const (
+	registrationOpen   = "open"
+	registrationInvite = "invite"
+	registrationClosed = "closed"
+)
+
+const (
+	defaultRegisterRateLimit = 10
+	minPasswordLength        = 8
+	invitePageSize           = 100
+)
+
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]{3,32}$`)
+
+// registrationMode is who may create an account, set with REGISTRATION:
+// anyone (open), only people with an invite (invite), or nobody (closed,
+// the default). Opening sign-ups has to be asked for.
+func registrationMode() string {
+	switch mode := os.Getenv("REGISTRATION"); mode {
+	case registrationOpen, registrationInvite:
+		return mode
+	}
+	return registrationClosed
+}
+
+// registerRateLimit is how many times an IP address may try to register, or
+// ask for another verification link, per hour. Set it with
+// REGISTER_RATE_LIMIT.
+func registerRateLimit() int {
+	if n, err := strconv.Atoi(os.Getenv("REGISTER_RATE_LIMIT")); err == nil && n > 0 {
+		return n
+	}
+	return defaultRegisterRateLimit
+}
+
+// registrationUnavailable explains why the visitor can't register, or
+// returns "" when they can.
+func (r *Router) registrationUnavailable(ctx context.Context, queries *db.Queries, invite string) (string, error) {
+	switch registrationMode() {
+	case registrationClosed:
+		return "Registration is closed.", nil
+	case registrationInvite:
+		if invite == "" {
+			return "Registration is by invite only.", nil
+		}
+		valid, err := queries.IsInviteValid(ctx, invite)
+		if err != nil {
+			return "", err
+		}
+		if !valid {
+			return "That invite has been used or doesn't exist.", nil
+		}
+	}
+	return "", nil
+}
+
+func (r *Router) HandleRegister(ctx *gin.Context) {
+	if _, authed := ctx.Get("authed"); authed {
+		ctx.Redirect(http.StatusFound, "/")
+		return
+	}
+
+	invite := ctx.Query("invite")
+	unavailable, err := r.registrationUnavailable(ctx.Request.Context(), r.Queries, invite)
+	if err != nil {
+		log.Println("Register failed to check the invite:", err)
+		ctx.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+	pages.RegisterPage(invite, unavailable, "").Render(withPowForms(createContext(ctx, "Register")), ctx.Writer)
+}
+
+func (r *Router) HandleRegisterRequest(ctx *gin.Context) {
+	username := strings.TrimSpace(ctx.PostForm("username"))
+	address := strings.TrimSpace(ctx.PostForm("email"))
+	password := ctx.PostForm("password")
+	invite := ctx.PostForm("invite")
+
+	fail := func(message string, err error) {
+		if err != nil {
+			log.Println("Register failed:", err)
+		}
+		r.HandleError(ctx, message, func(ctx *gin.Context) {
+			pages.RegisterPage(invite, "", message).Render(withPowForms(createContext(ctx, "Register")), ctx.Writer)
+		}, nil)
+	}
+
+	if !r.Register.Allow(ctx.ClientIP()) {
+		ctx.Header("Retry-After", strconv.Itoa(int(r.Register.RetryAfter(ctx.ClientIP()).Seconds())+1))
+		fail("Too many attempts. Try again later.", nil)
+		return
+	}
+	if ok, message := r.checkPow(ctx, powRegister); !ok {
+		fail(message, nil)
+		return
+	}
+
+	if !usernamePattern.MatchString(username) {
+		fail("Usernames are 3 to 32 letters, digits, dots, dashes or underscores.", nil)
+		return
+	}
+	parsed, err := netmail.ParseAddress(address)
+	if err != nil || parsed.Address != address {
+		fail("That doesn't look like an email address.", nil)
+		return
+	}
+	if len(password) < minPasswordLength {
+		fail("Password must be at least 8 characters.", nil)
+		return
+	}
+	// Hashing first keeps an address that already has an account from
+	// being answered noticeably faster.
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		fail("Failed to create your account.", err)
+		return
+	}
+
+	c := ctx.Request.Context()
+	tx, err := r.Pool.Begin(c)
+	if err != nil {
+		fail("Failed to create your account.", err)
+		return
+	}
+	defer tx.Rollback(c)
+	qtx := r.Queries.WithTx(tx)
+
+	var inviteRow db.Invite
+	switch registrationMode() {
+	case registrationClosed:
+		fail("Registration is closed.", nil)
+		return
+	case registrationInvite:
+		inviteRow, err = qtx.ClaimInvite(c, invite)
+		if errors.Is(err, pgx.ErrNoRows) {
+			fail("That invite has been used or doesn't exist.", nil)
+			return
+		}
+		if err != nil {
+			fail("Failed to create your account.", err)
+			return
+		}
+	}
+
+	if taken, err := qtx.IsUsernameTaken(c, username); err != nil || taken {
+		fail("That username is taken.", err)
+		return
+	}
+	// An address that already has an account gets the same answer as a
+	// new one, so the form can't be used to find out who has an account.
+	// Its owner is told by mail instead.
+	taken, err := qtx.IsEmailTaken(c, address)
+	if err != nil {
+		fail("Failed to create your account.", err)
+		return
+	}
+	if taken {
+		if err := sendAccountExistsEmail(c, qtx, address); err != nil {
+			fail("Failed to create your account.", err)
+			return
+		}
+		if err := tx.Commit(c); err != nil {
+			fail("Failed to create your account.", err)
+			return
+		}
+		renderRegistered(ctx, username, address)
+		return
+	}
+
+	row, err := qtx.CreateUser(c, db.CreateUserParams{Username: username, Email: &address, Password: hash})
+	if err != nil {
+		fail("Failed to create your account.", err)
+		return
+	}
+	if inviteRow.ID != 0 {
+		if err := qtx.UseInvite(c, db.UseInviteParams{ID: inviteRow.ID, UsedBy: &row.ID}); err != nil {
+			fail("Failed to create your account.", err)
+			return
+		}
+	}
+	user := mapUser(row)
+	if err := sendVerificationEmail(c, qtx, user); err != nil {
+		fail("Failed to create your account.", err)
+		return
+	}
+	if err := tx.Commit(c); err != nil {
+		fail("Failed to create your account.", err)
+		return
+	}
+
+	renderRegistered(ctx, user.Username, address)
+}
+
+// renderRegistered answers a sign-up. It doesn't sign the new user in, since
+// that would tell apart an address that already had an account.
+func renderRegistered(ctx *gin.Context, username string, address string) {
+	ctx.Status(http.StatusOK)
+	pages.MessagePage("Welcome, "+username, "We've sent a link to "+address+". Follow it to verify your address, and then log in.").
+		Render(createContext(ctx, "Welcome"), ctx.Writer)
+}
+
+// sendAccountExistsEmail tells the owner of address that someone tried to
+// register with it. Addresses of deleted accounts are still taken, but
+// there is no one to tell.
+func sendAccountExistsEmail(ctx context.Context, queries *db.Queries, address string) error {
+	row, err := queries.GetUserByEmail(ctx, address)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	user := mapUser(row)
+	loginLink := absoluteURL("/login")
+	resetLink := absoluteURL("/forgot-password")
+	html, err := mail.RenderHTML(ctx, email.AccountExistsEmail(user.Username, loginLink, resetLink))
+	if err != nil {
+		return err
+	}
+	return enqueueMail(ctx, queries, mail.Message{
+		To:      []string{user.Email},
+		Subject: "You already have an account",
+		Text:    "Hi " + user.Username + ",\n\nSomeone tried to make an account with this email address, but you already have one. If it was you, log in as " + user.Username + ":\n\n" + loginLink + "\n\nIf you forgot your password, you can reset it:\n\n" + resetLink + "\n\nIf it wasn't you, ignore this email. Nothing has changed.\n",
+		HTML:    html,
+	})
+}
+
+// sendVerificationEmail mails user a link to verify their address.
+func sendVerificationEmail(ctx context.Context, queries *db.Queries, user models.User) error {
+	token, err := auth.EmailVerificationToken(user.ID, user.Email)
+	if err != nil {
+		return err
+	}
+	link := absoluteURL("/verify-email?token=" + url.QueryEscape(token))
+	html, err := mail.RenderHTML(ctx, email.VerifyEmail(user.Username, link))
+	if err != nil {
+		return err
+	}
+	return enqueueMail(ctx, queries, mail.Message{
+		To:      []string{user.Email},
+		Subject: "Verify your email address",
+		Text:    "Hi " + user.Username + ",\n\nFollow this link to verify your email address:\n\n" + link + "\n\nThe link works for two days. If you didn't make an account, ignore this email.\n",
+		HTML:    html,
+	})
+}
+
+// HandleVerifyEmail verifies the address a link was sent to. Following the
+// link is proof enough, so unlike unsubscribing there's nothing to confirm.
+func (r *Router) HandleVerifyEmail(ctx *gin.Context) {
+	userId, address, err := auth.VerifyEmailVerificationToken(ctx.Query("token"))
+	if errors.Is(err, auth.ErrVerificationExpired) {
+		ctx.Status(http.StatusGone)
+		pages.MessagePage("Link expired", "That verification link has expired. You can get a new one from your notification settings.").
+			Render(createContext(ctx, "Verify email"), ctx.Writer)
+		return
+	}
+	if err != nil {
+		r.HandleNotFound(ctx)
+		return
+	}
+
+	if _, err := r.Queries.VerifyUserEmail(ctx.Request.Context(), db.VerifyUserEmailParams{ID: userId, Email: address}); err != nil {
+		r.HandleError(ctx, "Failed to verify your address", nil, err)
+		return
+	}
+	ctx.Status(http.StatusOK)
+	pages.MessagePage("Address verified", address+" is verified. Thanks!").Render(createContext(ctx, "Verify email"), ctx.Writer)
+}
+
+// HandleResendVerification mails another verification link to the signed in
+// user.
+func (r *Router) HandleResendVerification(ctx *gin.Context) {
+	user, ok := r.loadSignedInUser(ctx)
+	if !ok {
+		return
+	}
+	if user.Email == "" || user.EmailVerified() {
+		r.HandleError(ctx, "There's nothing to verify", nil, nil)
+		return
+	}
+	if !r.Register.Allow(ctx.ClientIP()) {
+		r.HandleError(ctx, "Too many attempts. Try again later.", nil, nil)
+		return
+	}
+	if err := sendVerificationEmail(ctx.Request.Context(), r.Queries, user); err != nil {
+		r.HandleError(ctx, "Failed to send the link", nil, err)
+		return
+	}
+	if r.HandleToast(ctx, "Verification link sent to "+user.Email) {
+		return
+	}
+	ctx.Redirect(http.StatusFound, notificationSettingsRoute)
+}
+
+// newInviteCode makes an unguessable invite code.
+func newInviteCode() string {
+	code := make([]byte, 16)
+	rand.Read(code)
+	return base64.RawURLEncoding.EncodeToString(code)
+}
+
+func inviteURL(code string) string {
+	return absoluteURL("/register?invite=" + url.QueryEscape(code))
+}
+
+func (r *Router) HandleAdminInvites(ctx *gin.Context) {
+	rows, err := r.Queries.GetInvites(ctx.Request.Context(), invitePageSize)
+	if err != nil {
+		log.Println("Invites failed:", err)
+		ctx.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+	admin.InvitesPage(mapInvites(rows), registrationMode(), inviteURL).Render(createContext(ctx, "Invites"), ctx.Writer)
+}
+
+func (r *Router) HandleAdminCreateInvite(ctx *gin.Context) {
+	claims := ctx.MustGet("authToken").(*auth.JwtPayload)
+	createdBy := int64(claims.UserId)
+	if _, err := r.Queries.CreateInvite(ctx.Request.Context(), db.CreateInviteParams{
+		Code:      newInviteCode(),
+		Note:      strings.TrimSpace(ctx.PostForm("note")),
+		CreatedBy: &createdBy,
+	}); err != nil {
+		r.HandleError(ctx, "Failed to create invite", nil, err)
+		return
+	}
+
+	ctx.Redirect(http.StatusFound, adminRoute+"/invites")
+}
+
+func (r *Router) HandleAdminDeleteInvite(ctx *gin.Context) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		r.HandleError(ctx, "Invalid invite ID", nil, err)
+		return
+	}
+	if err := r.Queries.DeleteInvite(ctx.Request.Context(), id); err != nil {
+		r.HandleError(ctx, "Failed to delete invite", nil, err)
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+}
@@ -0,0 +1,169 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"blog.simoni.dev/auth"
+	db "blog.simoni.dev/db/generated"
+	"blog.simoni.dev/diff"
+	"blog.simoni.dev/templates/admin"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// updatePostWithRevision updates a post and records its new title and content
+// as a revision. qtx should be bound to a transaction so the post and its
+// history can't disagree. No revision is written when neither changed.
+func updatePostWithRevision(ctx context.Context, qtx *db.Queries, previous db.BlogPost, params db.UpdatePostParams, author string) (db.BlogPost, error) {
+	updated, err := qtx.UpdatePost(ctx, params)
+	if err != nil {
+		return updated, err
+	}
+	if updated.Title == previous.Title && updated.Content == previous.Content {
+		return updated, nil
+	}
+
+	_, err = qtx.CreatePostRevision(ctx, db.CreatePostRevisionParams{
+		BlogPostID: updated.ID,
+		Author:     author,
+		Title:      updated.Title,
+		Content:    updated.Content,
+	})
+	return updated, err
+}
+
+func (r *Router) HandlePostRevisions(ctx *gin.Context) {
+	postId, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		r.HandleNotFound(ctx)
+		return
+	}
+
+	row, err := r.Queries.GetPostByID(ctx.Request.Context(), postId)
+	if err != nil {
+		log.Println("Revisions failed to get post:", err)
+		r.HandleNotFound(ctx)
+		return
+	}
+
+	revisions, err := r.Queries.GetPostRevisions(ctx.Request.Context(), postId)
+	if err != nil {
+		log.Println("Revisions failed to get revisions:", err)
+		ctx.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	post := mapPost(row, nil)
+	ctx.Status(http.StatusOK)
+	admin.RevisionsPage(post, mapRevisions(revisions)).Render(createContext(ctx, "Revisions of "+post.Title), ctx.Writer)
+}
+
+func (r *Router) HandlePostRevisionDiff(ctx *gin.Context) {
+	postId, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		r.HandleNotFound(ctx)
+		return
+	}
+	fromId, err := strconv.ParseInt(ctx.Query("from"), 10, 64)
+	if err != nil {
+		r.HandleError(ctx, "Invalid revision", nil, err)
+		return
+	}
+	toId, err := strconv.ParseInt(ctx.Query("to"), 10, 64)
+	if err != nil {
+		r.HandleError(ctx, "Invalid revision", nil, err)
+		return
+	}
+
+	row, err := r.Queries.GetPostByID(ctx.Request.Context(), postId)
+	if err != nil {
+		log.Println("Revision diff failed to get post:", err)
+		r.HandleNotFound(ctx)
+		return
+	}
+	from, err := r.Queries.GetPostRevisionByID(ctx.Request.Context(), db.GetPostRevisionByIDParams{ID: fromId, BlogPostID: postId})
+	if err != nil {
+		r.handleRevisionError(ctx, err)
+		return
+	}
+	to, err := r.Queries.GetPostRevisionByID(ctx.Request.Context(), db.GetPostRevisionByIDParams{ID: toId, BlogPostID: postId})
+	if err != nil {
+		r.handleRevisionError(ctx, err)
+		return
+	}
+
+	post := mapPost(row, nil)
+	lines := diff.Lines(from.Content, to.Content)
+	ctx.Status(http.StatusOK)
+	admin.RevisionDiffPage(post, mapRevision(from), mapRevision(to), lines).Render(createContext(ctx, "Changes to "+post.Title), ctx.Writer)
+}
+
+// HandlePostRevisionRestore copies an old revision back onto the post. The
+// restore is recorded as a new revision so no history is lost.
+func (r *Router) HandlePostRevisionRestore(ctx *gin.Context) {
+	postId, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		r.HandleError(ctx, "Invalid post ID", nil, err)
+		return
+	}
+	revisionId, err := strconv.ParseInt(ctx.Param("revisionId"), 10, 64)
+	if err != nil {
+		r.HandleError(ctx, "Invalid revision ID", nil, err)
+		return
+	}
+	author := ctx.MustGet("authToken").(*auth.JwtPayload).Username
+
+	tx, err := r.Pool.Begin(ctx.Request.Context())
+	if err != nil {
+		r.HandleError(ctx, "Failed to restore revision", nil, err)
+		return
+	}
+	defer tx.Rollback(ctx.Request.Context())
+	qtx := r.Queries.WithTx(tx)
+
+	row, err := qtx.GetPostByID(ctx.Request.Context(), postId)
+	if err != nil {
+		r.HandleError(ctx, "Failed to load post", nil, err)
+		return
+	}
+	revision, err := qtx.GetPostRevisionByID(ctx.Request.Context(), db.GetPostRevisionByIDParams{ID: revisionId, BlogPostID: postId})
+	if err != nil {
+		r.HandleError(ctx, "Failed to load revision", nil, err)
+		return
+	}
+
+	if _, err := updatePostWithRevision(ctx.Request.Context(), qtx, row, db.UpdatePostParams{
+		ID:          postId,
+		Title:       revision.Title,
+		Description: row.Description,
+		Content:     revision.Content,
+		Slug:        row.Slug,
+		Draft:       row.Draft,
+		PublishedAt: row.PublishedAt,
+		ScheduledAt: row.ScheduledAt,
+	}, author); err != nil {
+		r.HandleError(ctx, "Failed to restore revision", nil, err)
+		return
+	}
+
+	if err := tx.Commit(ctx.Request.Context()); err != nil {
+		r.HandleError(ctx, "Failed to restore revision", nil, err)
+		return
+	}
+
+	post := mapPost(row, nil)
+	ctx.Redirect(http.StatusFound, post.GetRevisionsLink(adminRoute))
+}
+
+func (r *Router) handleRevisionError(ctx *gin.Context, err error) {
+	if errors.Is(err, pgx.ErrNoRows) {
+		r.HandleNotFound(ctx)
+		return
+	}
+	log.Println("Failed to load revision:", err)
+	ctx.AbortWithStatus(http.StatusInternalServerError)
+}
@@ -3,7 +3,6 @@ package server
 import (
 	"context"
 	"errors"
-	"fmt"
 	"log"
 	"math/rand"
 	"net/http"
@@ -14,6 +13,11 @@ import (
 
 	"blog.simoni.dev/auth"
 	db "blog.simoni.dev/db/generated"
+	"blog.simoni.dev/models"
+	"blog.simoni.dev/permalink"
+	"blog.simoni.dev/pow"
+	"blog.simoni.dev/ratelimit"
+	"blog.simoni.dev/spam"
 	"blog.simoni.dev/templates/admin"
 	"blog.simoni.dev/templates/components"
 	"blog.simoni.dev/templates/pages"
@@ -27,11 +31,33 @@ import (
 type Router struct {
 	Queries *db.Queries
 	Pool    *pgxpool.Pool
+	Spam    spam.Filter
+	// Pow holds a proof-of-work guard for every form that needs one.
+	Pow map[string]*pow.Guard
+	// Register limits how often one IP address can register.
+	Register *ratelimit.Limiter
+	// PasswordReset limits how often reset links can be asked for, by IP
+	// address and by email address.
+	PasswordReset *ratelimit.Limiter
+	// TwoFactor limits how many codes can be tried for one account.
+	TwoFactor *ratelimit.Limiter
+	// Newsletter limits how often confirmation links can be asked for, by
+	// IP address and by email address.
+	Newsletter *ratelimit.Limiter
 }
 
 func NewRouter(pool *pgxpool.Pool) *Router {
 	queries := db.New(pool)
-	return &Router{Pool: pool, Queries: queries}
+	return &Router{
+		Pool:          pool,
+		Queries:       queries,
+		Spam:          newSpamFilter(queries),
+		Pow:           newPowGuards(),
+		Register:      ratelimit.New(registerRateLimit(), time.Hour),
+		PasswordReset: ratelimit.New(passwordResetRateLimit(), time.Hour),
+		TwoFactor:     ratelimit.New(twoFactorAttempts, twoFactorWindow),
+		Newsletter:    ratelimit.New(newsletterRateLimit(), time.Hour),
+	}
 }
 
 func (r *Router) HandlePasswordChange(ctx *gin.Context) {
@@ -72,14 +98,22 @@ func (r *Router) HandlePasswordChange(ctx *gin.Context) {
 		return
 	}
 
-	if err := r.Queries.UpdateUserPassword(ctx.Request.Context(), db.UpdateUserPasswordParams{
+	version, err := r.Queries.UpdateUserPassword(ctx.Request.Context(), db.UpdateUserPasswordParams{
 		ID:       int64(userId),
 		Password: hash,
-	}); err != nil {
+	})
+	if err != nil {
 		r.HandleError(ctx, "Failed to change password", nil, err)
 		return
 	}
 
+	// Every other session is signed out now, this one gets new tokens.
+	user.SessionVersion = version
+	if _, err := user.NewAuthTokens(ctx); err != nil {
+		r.HandleError(ctx, "Failed to generate tokens", nil, err)
+		return
+	}
+
 	// TODO change redirect location
 	ctx.Redirect(http.StatusFound, "/admin")
 }
@@ -126,21 +160,39 @@ func (r *Router) HandleUsernameChange(ctx *gin.Context) {
 }
 
 func (r *Router) HandleIndex(ctx *gin.Context) {
-	rows, err := r.Queries.GetPublishedPosts(ctx.Request.Context())
+	posts, pagination, err := r.loadPostPage(ctx, "/", publishedSortTime,
+		func(c context.Context, cursor postCursor, pageSize int32) ([]db.BlogPost, error) {
+			return r.Queries.GetPublishedPosts(c, db.GetPublishedPostsParams{
+				CursorTime: cursor.PgTime(),
+				CursorID:   cursor.ID,
+				PageSize:   pageSize,
+			})
+		},
+		func(c context.Context, cursor postCursor, pageSize int32) ([]db.BlogPost, error) {
+			return r.Queries.GetPublishedPostsBefore(c, db.GetPublishedPostsBeforeParams{
+				CursorTime: cursor.PgTime(),
+				CursorID:   cursor.ID,
+				PageSize:   pageSize,
+			})
+		})
 	if err != nil {
 		log.Println("Index failed to get posts:", err)
 		ctx.AbortWithStatus(http.StatusInternalServerError)
 		return
 	}
-	posts, err := r.loadPostsWithTags(ctx.Request.Context(), rows)
-	if err != nil {
-		log.Println("Index failed to load tags:", err)
-		ctx.AbortWithStatus(http.StatusInternalServerError)
-		return
-	}
 
+	r.renderIndex(ctx, posts, false, pagination, "mrchip53's blog")
+}
+
+// renderIndex renders a post listing, or only its posts when infinite scroll
+// asks for the next page.
+func (r *Router) renderIndex(ctx *gin.Context, posts []models.BlogPost, canDelete bool, pagination models.Pagination, title string) {
 	ctx.Status(http.StatusOK)
-	pages.IndexPage(posts, false).Render(createContext(ctx, "mrchip53's blog"), ctx.Writer)
+	if isPartialRequest(ctx) {
+		pages.IndexPosts(posts, canDelete, pagination).Render(createContext(ctx, title), ctx.Writer)
+		return
+	}
+	pages.IndexPage(posts, canDelete, pagination).Render(createContext(ctx, title), ctx.Writer)
 }
 
 func (r *Router) HandleSettings(ctx *gin.Context) {
@@ -155,91 +207,46 @@ func (r *Router) HandleSettings(ctx *gin.Context) {
 
 func (r *Router) HandleUser(ctx *gin.Context) {
 	username := ctx.Param("username")
-	rows, err := r.Queries.GetPostsByAuthor(ctx.Request.Context(), username)
+	posts, pagination, err := r.loadPostPage(ctx, "/user/"+url.PathEscape(username), publishedSortTime,
+		func(c context.Context, cursor postCursor, pageSize int32) ([]db.BlogPost, error) {
+			return r.Queries.GetPostsByAuthor(c, db.GetPostsByAuthorParams{
+				Author:     username,
+				CursorTime: cursor.PgTime(),
+				CursorID:   cursor.ID,
+				PageSize:   pageSize,
+			})
+		},
+		func(c context.Context, cursor postCursor, pageSize int32) ([]db.BlogPost, error) {
+			return r.Queries.GetPostsByAuthorBefore(c, db.GetPostsByAuthorBeforeParams{
+				Author:     username,
+				CursorTime: cursor.PgTime(),
+				CursorID:   cursor.ID,
+				PageSize:   pageSize,
+			})
+		})
 	if err != nil {
 		log.Println("User page failed to get posts:", err)
 		ctx.AbortWithStatus(http.StatusInternalServerError)
 		return
 	}
-	posts, err := r.loadPostsWithTags(ctx.Request.Context(), rows)
-	if err != nil {
-		log.Println("User page failed to load tags:", err)
-		ctx.AbortWithStatus(http.StatusInternalServerError)
-		return
-	}
 
-	ctx.Status(http.StatusOK)
-	pages.IndexPage(posts, false).Render(createContext(ctx, username+"'s Page"), ctx.Writer)
+	r.renderIndex(ctx, posts, false, pagination, username+"'s Page")
 }
 
-func (r *Router) HandleComment(ctx *gin.Context) {
-	if t, ok := ctx.Get("authToken"); !ok || t == nil {
-		r.HandleError(ctx, "You must be logged in to comment", nil, nil)
-		return
-	}
-
-	postIdStr := ctx.Param("postId")
-	author := ctx.PostForm("Username")
-	comment := ctx.PostForm("comment")
-
-	if len(comment) == 0 || len(author) == 0 {
-		r.HandleError(ctx, "Author and comment cannot be empty", nil, nil)
-		return
-	}
-
-	pid, err := strconv.ParseInt(postIdStr, 10, 64)
-	if err != nil {
-		r.HandleError(ctx, "Invalid post ID", nil, err)
-		return
-	}
-
-	if _, err := r.Queries.GetPostByID(ctx.Request.Context(), pid); err != nil {
-		r.HandleError(ctx, "Post not found", nil, err)
-		return
-	}
-
-	if _, err := r.Queries.CreateComment(ctx.Request.Context(), db.CreateCommentParams{
-		BlogPostID: pid,
-		Author:     author,
-		Comment:    comment,
-	}); err != nil {
-		r.HandleError(ctx, "Failed to create comment", nil, err)
-		return
-	}
-
-	dbComments, err := r.Queries.GetCommentsByPostID(ctx.Request.Context(), pid)
-	if err != nil {
-		r.HandleError(ctx, "Failed to load comments", nil, err)
+func (r *Router) HandlePost(ctx *gin.Context) {
+	path := ctx.Request.URL.EscapedPath()
+	match, ok := permalink.Current().Match(path)
+	if !ok {
+		// gin routes every path with the pattern's shape here, including
+		// redirects and legacy permalinks of the same shape.
+		r.HandleNoRoute(ctx)
 		return
 	}
 
-	ctx.Header("HX-Retarget", "#comments-"+postIdStr)
-	ctx.Header("HX-Reswap", "innerHTML")
-	ctx.Status(http.StatusOK)
-	components.CommentsComponent(mapComments(dbComments)).Render(context.TODO(), ctx.Writer)
-}
-
-func (r *Router) HandlePost(ctx *gin.Context) {
-	month := ctx.Param("month")
-	day := ctx.Param("day")
-	year := ctx.Param("year")
-	slug := ctx.Param("slug")
-
-	m, _ := strconv.Atoi(month)
-	d, _ := strconv.Atoi(day)
-	y, _ := strconv.Atoi(year)
-	// Use calendar-based next-day midnight so DST days (25h or 23h) are handled correctly.
-	startOfDay := time.Date(y, time.Month(m), d, 0, 0, 0, 0, time.Local)
-	endOfDay := time.Date(y, time.Month(m), d+1, 0, 0, 0, 0, time.Local)
-
-	row, err := r.Queries.GetPostBySlugAndDate(ctx.Request.Context(), db.GetPostBySlugAndDateParams{
-		Slug:       slug,
-		StartOfDay: pgtype.Timestamptz{Time: startOfDay, Valid: true},
-		EndOfDay:   pgtype.Timestamptz{Time: endOfDay, Valid: true},
-	})
+	row, err := r.resolvePermalink(ctx.Request.Context(), match)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			r.HandleNotFound(ctx)
+			r.HandleNoRoute(ctx)
 		} else {
 			log.Println("Post page failed:", err)
 			ctx.AbortWithStatus(http.StatusInternalServerError)
@@ -247,13 +254,20 @@ func (r *Router) HandlePost(ctx *gin.Context) {
 		return
 	}
 
+	// Old slugs and wrong dates lead to the post's one canonical address.
+	if canonical := postPermalink(row); canonical != path {
+		ctx.Redirect(http.StatusMovedPermanently, canonical)
+		return
+	}
+
 	dbTags, _ := r.Queries.GetTagsForPost(ctx.Request.Context(), row.ID)
 	post := mapPost(row, mapTags(dbTags))
 
 	dbComments, _ := r.Queries.GetCommentsByPostID(ctx.Request.Context(), row.ID)
+	comments := threadComments(mapComments(dbComments), nil)
 
 	ctx.Status(200)
-	pages.PostPage(post, string(parseMarkdown([]byte(post.Content))), mapComments(dbComments)).Render(createContext(ctx, post.Title), ctx.Writer)
+	pages.PostPage(post, string(parseMarkdown([]byte(post.Content))), comments).Render(withCommentForm(createContext(ctx, post.Title)), ctx.Writer)
 }
 
 func (r *Router) HandlePostEdit(ctx *gin.Context) {
@@ -284,49 +298,119 @@ func (r *Router) PostPostEdit(ctx *gin.Context) {
 		return
 	}
 
+	title := strings.TrimSpace(ctx.PostForm("title"))
+	description := strings.TrimSpace(ctx.PostForm("description"))
 	content := ctx.PostForm("content")
 	publish := ctx.PostForm("publish")
+	scheduled := ctx.PostForm("scheduledAt")
 
 	if len(content) == 0 {
 		r.HandleError(ctx, "Just delete the post instead.", nil, nil)
 		return
 	}
+	if title == "" {
+		r.HandleError(ctx, "A post needs a title.", nil, nil)
+		return
+	}
+	slug := normalizeSlug(ctx.PostForm("slug"))
+	if slug == "" {
+		slug = postSlug(title)
+	}
+	if len(slug) > maxSlugLength {
+		r.HandleError(ctx, "That slug is too long.", nil, nil)
+		return
+	}
 
-	row, err := r.Queries.GetPostByID(ctx.Request.Context(), postId)
+	author := ctx.MustGet("authToken").(*auth.JwtPayload).Username
+
+	tx, err := r.Pool.Begin(ctx.Request.Context())
+	if err != nil {
+		r.HandleError(ctx, "Failed to update post.", nil, err)
+		return
+	}
+	defer tx.Rollback(ctx.Request.Context())
+	qtx := r.Queries.WithTx(tx)
+
+	row, err := qtx.GetPostByID(ctx.Request.Context(), postId)
 	if err != nil {
 		r.HandleError(ctx, "Failed to load post.", nil, err)
 		return
 	}
 
+	taken, err := qtx.IsSlugTaken(ctx.Request.Context(), db.IsSlugTakenParams{Slug: slug, ID: postId})
+	if err != nil {
+		r.HandleError(ctx, "Failed to update post.", nil, err)
+		return
+	}
+	if taken {
+		r.HandleError(ctx, "That slug is already used by another post.", nil, nil)
+		return
+	}
+
 	draft := publish != "on"
 	publishedAt := row.PublishedAt
-	slug := row.Slug
 	if row.Draft && !draft {
 		// First time publishing
 		publishedAt = pgtype.Timestamptz{Time: time.Now().UTC(), Valid: true}
-		slug = url.QueryEscape(strings.ToLower(strings.ReplaceAll(row.Title, " ", "-")))
 	}
 
-	updated, err := r.Queries.UpdatePost(ctx.Request.Context(), db.UpdatePostParams{
+	// Keep old links to a published post working once its slug changes.
+	if !row.Draft && slug != row.Slug {
+		if err := qtx.CreatePostSlugHistory(ctx.Request.Context(), db.CreatePostSlugHistoryParams{
+			BlogPostID: row.ID,
+			Slug:       row.Slug,
+		}); err != nil {
+			r.HandleError(ctx, "Failed to update post.", nil, err)
+			return
+		}
+	}
+
+	// A draft with a publish time is left for the publisher to pick up.
+	var scheduledAt pgtype.Timestamptz
+	if draft && scheduled != "" {
+		t, err := time.ParseInLocation(models.ScheduleInputLayout, scheduled, time.Local)
+		if err != nil {
+			r.HandleError(ctx, "Invalid publish time.", nil, err)
+			return
+		}
+		if !t.After(time.Now()) {
+			r.HandleError(ctx, "The publish time must be in the future.", nil, nil)
+			return
+		}
+		scheduledAt = pgtype.Timestamptz{Time: t.UTC(), Valid: true}
+	}
+
+	updated, err := updatePostWithRevision(ctx.Request.Context(), qtx, row, db.UpdatePostParams{
 		ID:          postId,
-		Title:       row.Title,
+		Title:       title,
+		Description: description,
 		Content:     strings.TrimSpace(content),
 		Slug:        slug,
 		Draft:       draft,
 		PublishedAt: publishedAt,
-	})
+		ScheduledAt: scheduledAt,
+	}, author)
 	if err != nil {
 		r.HandleError(ctx, "Failed to update post.", nil, err)
 		return
 	}
 
-	location := adminRoute
-	if !updated.Draft {
-		if t := pgTimeToTime(updated.PublishedAt); !t.IsZero() {
-			tl := t.Local()
-			location = fmt.Sprintf("/post/%02d/%02d/%d/%s", tl.Month(), tl.Day(), tl.Year(), updated.Slug)
+	if !draft && !row.PublishedAt.Valid {
+		if err := queueNewsletter(ctx.Request.Context(), qtx, updated.ID); err != nil {
+			r.HandleError(ctx, "Failed to update post.", nil, err)
+			return
 		}
 	}
+
+	if err := tx.Commit(ctx.Request.Context()); err != nil {
+		r.HandleError(ctx, "Failed to update post.", nil, err)
+		return
+	}
+
+	location := adminRoute
+	if !updated.Draft && updated.PublishedAt.Valid {
+		location = postPermalink(updated)
+	}
 	ctx.Redirect(http.StatusFound, location)
 }
 
@@ -340,26 +424,36 @@ func (r *Router) HandleLogin(ctx *gin.Context) {
 	ctx.Status(http.StatusOK)
 
 	html := pages.LoginPage(redirect, "")
-	html.Render(createContext(ctx, "Login"), ctx.Writer)
+	html.Render(withPowForms(createContext(ctx, "Login")), ctx.Writer)
 }
 
 func (r *Router) HandleTag(ctx *gin.Context) {
 	tag := ctx.Param("tag")
 
-	rows, err := r.Queries.GetPublishedPostsByTag(ctx.Request.Context(), tag)
+	posts, pagination, err := r.loadPostPage(ctx, "/tag/"+url.PathEscape(tag), publishedSortTime,
+		func(c context.Context, cursor postCursor, pageSize int32) ([]db.BlogPost, error) {
+			return r.Queries.GetPublishedPostsByTag(c, db.GetPublishedPostsByTagParams{
+				Name:       tag,
+				CursorTime: cursor.PgTime(),
+				CursorID:   cursor.ID,
+				PageSize:   pageSize,
+			})
+		},
+		func(c context.Context, cursor postCursor, pageSize int32) ([]db.BlogPost, error) {
+			return r.Queries.GetPublishedPostsByTagBefore(c, db.GetPublishedPostsByTagBeforeParams{
+				Name:       tag,
+				CursorTime: cursor.PgTime(),
+				CursorID:   cursor.ID,
+				PageSize:   pageSize,
+			})
+		})
 	if err != nil {
 		log.Println("Tag failed to get posts:", err)
 		ctx.AbortWithStatus(http.StatusInternalServerError)
 		return
 	}
-	posts, err := r.loadPostsWithTags(ctx.Request.Context(), rows)
-	if err != nil {
-		log.Println("Tag failed to load tags:", err)
-		ctx.AbortWithStatus(http.StatusInternalServerError)
-		return
-	}
 
-	pages.IndexPage(posts, false).Render(createContext(ctx, "Posts tagged with "+tag), ctx.Writer)
+	r.renderIndex(ctx, posts, false, pagination, "Posts tagged with "+tag)
 }
 
 func (r *Router) HandleNotFound(ctx *gin.Context) {
@@ -392,12 +486,19 @@ func (r *Router) HandleLoginRequest(ctx *gin.Context) {
 
 	errString := "Invalid username or password"
 
+	if ok, message := r.checkPow(ctx, powLogin); !ok {
+		r.HandleError(ctx, message, func(ctx *gin.Context) {
+			pages.LoginPage(redirectPath, message).Render(withPowForms(createContext(ctx, "Login")), ctx.Writer)
+		}, nil)
+		return
+	}
+
 	row, err := r.Queries.GetUserByUsername(ctx.Request.Context(), username)
 	if err != nil {
 		time.Sleep(time.Duration(170+rand.Intn(35)) * time.Millisecond)
 		log.Println("Login failed to get user:", err)
 		r.HandleError(ctx, errString, func(ctx *gin.Context) {
-			pages.LoginPage(redirectPath, errString).Render(createContext(ctx, "Login"), ctx.Writer)
+			pages.LoginPage(redirectPath, errString).Render(withPowForms(createContext(ctx, "Login")), ctx.Writer)
 		}, err)
 		return
 	}
@@ -409,19 +510,38 @@ func (r *Router) HandleLoginRequest(ctx *gin.Context) {
 			log.Println("Login failed to verify password:", err)
 		}
 		r.HandleError(ctx, errString, func(ctx *gin.Context) {
-			pages.LoginPage(redirectPath, errString).Render(createContext(ctx, "Login"), ctx.Writer)
+			pages.LoginPage(redirectPath, errString).Render(withPowForms(createContext(ctx, "Login")), ctx.Writer)
 		}, err)
 		return
 	}
 
+	// Accounts with two-factor authentication get their tokens from the
+	// second step instead.
+	if user.TwoFactorEnabled() {
+		token, err := auth.TwoFactorLoginToken(user.ID, user.SessionVersion)
+		if err != nil {
+			log.Println("Login failed to start two-factor login:", err)
+			r.HandleError(ctx, errString, func(ctx *gin.Context) {
+				pages.LoginPage(redirectPath, errString).Render(withPowForms(createContext(ctx, "Login")), ctx.Writer)
+			}, err)
+			return
+		}
+		ctx.Status(http.StatusOK)
+		pages.TwoFactorLoginPage(token, redirectPath, "").Render(createContext(ctx, "Login"), ctx.Writer)
+		return
+	}
+
 	if _, err := user.NewAuthTokens(ctx); err != nil {
 		log.Println("Login failed to generate tokens:", err)
 		r.HandleError(ctx, errString, func(ctx *gin.Context) {
-			pages.LoginPage(redirectPath, errString).Render(createContext(ctx, "Login"), ctx.Writer)
+			pages.LoginPage(redirectPath, errString).Render(withPowForms(createContext(ctx, "Login")), ctx.Writer)
 		}, err)
 		return
 	}
 
+	if user.Admin && requireAdminTwoFactor() {
+		redirectPath = twoFactorRoute
+	}
 	ctx.Redirect(http.StatusFound, redirectPath)
 }
 
@@ -433,20 +553,44 @@ func (r *Router) HandleAdminDashboard(ctx *gin.Context) {
 		return
 	}
 
-	rows, err := r.Queries.GetDraftPosts(ctx.Request.Context())
+	posts, pagination, err := r.loadPostPage(ctx, adminRoute, adminSortTime,
+		func(c context.Context, cursor postCursor, pageSize int32) ([]db.BlogPost, error) {
+			return r.Queries.GetDraftPosts(c, db.GetDraftPostsParams{
+				CursorTime: cursor.PgTime(),
+				CursorID:   cursor.ID,
+				PageSize:   pageSize,
+			})
+		},
+		func(c context.Context, cursor postCursor, pageSize int32) ([]db.BlogPost, error) {
+			return r.Queries.GetDraftPostsBefore(c, db.GetDraftPostsBeforeParams{
+				CursorTime: cursor.PgTime(),
+				CursorID:   cursor.ID,
+				PageSize:   pageSize,
+			})
+		})
 	if err != nil {
 		log.Println("Dashboard failed to get posts:", err)
 		ctx.AbortWithStatus(http.StatusInternalServerError)
 		return
 	}
-	posts, err := r.loadPostsWithTags(ctx.Request.Context(), rows)
+	numDrafts, err := r.Queries.CountDraftPosts(ctx.Request.Context())
+	if err != nil {
+		log.Println("Dashboard failed to count drafts:", err)
+		ctx.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+	scheduledRows, err := r.Queries.GetScheduledPosts(ctx.Request.Context())
 	if err != nil {
-		log.Println("Dashboard failed to load tags:", err)
+		log.Println("Dashboard failed to get scheduled posts:", err)
 		ctx.AbortWithStatus(http.StatusInternalServerError)
 		return
 	}
+	scheduled := make([]models.BlogPost, len(scheduledRows))
+	for i, row := range scheduledRows {
+		scheduled[i] = mapPost(row, nil)
+	}
 
-	admin.DashboardPage(posts, strconv.Itoa(len(posts)), "0").Render(createContext(ctx, "Admin Dashboard"), ctx.Writer)
+	admin.DashboardPage(posts, scheduled, strconv.FormatInt(numDrafts, 10), strconv.Itoa(pagination.Page), pagination).Render(createContext(ctx, "Admin Dashboard"), ctx.Writer)
 }
 
 func (r *Router) HandleAdminAddTagToPost(ctx *gin.Context) {
@@ -536,7 +680,7 @@ func (r *Router) HandleAdminLogin(ctx *gin.Context) {
 	}
 
 	html := pages.LoginPage(redirect, "")
-	html.Render(createContext(ctx, "Login"), ctx.Writer)
+	html.Render(withPowForms(createContext(ctx, "Login")), ctx.Writer)
 }
 
 func (r *Router) HandleAdminNewBlogPost(ctx *gin.Context) {
@@ -551,7 +695,7 @@ func (r *Router) HandleAdminNewBlogPostRequest(ctx *gin.Context) {
 	content := ctx.PostForm("content")
 	description := ctx.PostForm("description")
 	draft := ctx.PostForm("publish") != "true"
-	slug := url.QueryEscape(strings.ToLower(strings.ReplaceAll(title, " ", "-")))
+	slug := postSlug(title)
 
 	jwt, exists := ctx.Get("authToken")
 	if !exists {
@@ -587,6 +731,16 @@ func (r *Router) HandleAdminNewBlogPostRequest(ctx *gin.Context) {
 		return
 	}
 
+	if _, err := qtx.CreatePostRevision(ctx.Request.Context(), db.CreatePostRevisionParams{
+		BlogPostID: post.ID,
+		Author:     author,
+		Title:      post.Title,
+		Content:    post.Content,
+	}); err != nil {
+		r.HandleError(ctx, "Failed to create blog post", nil, err)
+		return
+	}
+
 	for _, tagName := range tags {
 		tagName = strings.TrimSpace(tagName)
 		if tagName == "" {
@@ -613,6 +767,13 @@ func (r *Router) HandleAdminNewBlogPostRequest(ctx *gin.Context) {
 		}
 	}
 
+	if !draft {
+		if err := queueNewsletter(ctx.Request.Context(), qtx, post.ID); err != nil {
+			r.HandleError(ctx, "Failed to create blog post", nil, err)
+			return
+		}
+	}
+
 	if err := tx.Commit(ctx.Request.Context()); err != nil {
 		r.HandleError(ctx, "Failed to create blog post", nil, err)
 		return
@@ -635,33 +796,32 @@ func (r *Router) HandleAdminPostsDelete(ctx *gin.Context) {
 }
 
 func (r *Router) HandleAdminPosts(ctx *gin.Context) {
-	rows, err := r.Queries.GetAllPostsAdmin(ctx.Request.Context())
+	posts, pagination, err := r.loadPostPage(ctx, adminRoute+"/posts", adminSortTime,
+		func(c context.Context, cursor postCursor, pageSize int32) ([]db.BlogPost, error) {
+			return r.Queries.GetAllPostsAdmin(c, db.GetAllPostsAdminParams{
+				CursorTime: cursor.PgTime(),
+				CursorID:   cursor.ID,
+				PageSize:   pageSize,
+			})
+		},
+		func(c context.Context, cursor postCursor, pageSize int32) ([]db.BlogPost, error) {
+			return r.Queries.GetAllPostsAdminBefore(c, db.GetAllPostsAdminBeforeParams{
+				CursorTime: cursor.PgTime(),
+				CursorID:   cursor.ID,
+				PageSize:   pageSize,
+			})
+		})
 	if err != nil {
 		log.Println("Admin posts failed:", err)
 		ctx.AbortWithStatus(http.StatusInternalServerError)
 		return
 	}
-	posts, err := r.loadPostsWithTags(ctx.Request.Context(), rows)
-	if err != nil {
-		log.Println("Admin posts failed to load tags:", err)
-		ctx.AbortWithStatus(http.StatusInternalServerError)
-		return
-	}
 
-	ctx.Status(200)
-	pages.IndexPage(posts, true).Render(createContext(ctx, "Manage Posts"), ctx.Writer)
+	r.renderIndex(ctx, posts, true, pagination, "Manage Posts")
 }
 
 func (r *Router) HandleError(ctx *gin.Context, message string, fn func(ctx *gin.Context), err error) {
-	toastId := uuid.New().String()
-
-	hxRequest, exists := ctx.Get("isHXRequest")
-	if exists && hxRequest.(bool) {
-		ctx.Header("HX-Retarget", "#toastContainer")
-		ctx.Header("HX-Reswap", "beforeend")
-		ctx.Status(http.StatusOK)
-		toast := components.ToastComponent("toast-"+toastId, message)
-		toast.Render(context.TODO(), ctx.Writer)
+	if r.HandleToast(ctx, message) {
 		return
 	}
 
@@ -672,6 +832,23 @@ func (r *Router) HandleError(ctx *gin.Context, message string, fn func(ctx *gin.
 	}
 }
 
+// HandleToast shows message in a toast when the request came from htmx. It
+// reports whether it wrote a response.
+func (r *Router) HandleToast(ctx *gin.Context, message string) bool {
+	hxRequest, exists := ctx.Get("isHXRequest")
+	if !exists || !hxRequest.(bool) {
+		return false
+	}
+
+	toastId := uuid.New().String()
+	ctx.Header("HX-Retarget", "#toastContainer")
+	ctx.Header("HX-Reswap", "beforeend")
+	ctx.Status(http.StatusOK)
+	toast := components.ToastComponent("toast-"+toastId, message)
+	toast.Render(context.TODO(), ctx.Writer)
+	return true
+}
+
 func (r *Router) HandleWasmLoader(ctx *gin.Context) {
 	wasmType := ctx.Param("type")
 	url := ctx.Query("url")
@@ -709,6 +886,8 @@ func createContext(ctx *gin.Context, pageTitle string) context.Context {
 		ct = context.WithValue(ct, "theme", theme.(string))
 	}
 	ct = context.WithValue(ct, "pageTitle", pageTitle)
+	ct = context.WithValue(ct, "guestComments", guestCommentsAllowed())
+	ct = context.WithValue(ct, "registration", registrationMode())
 
 	return ct
 }
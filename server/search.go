@@ -0,0 +1,98 @@
+package server
+
+import (
+	"html"
+	"log"
+	"net/http"
+	"strings"
+
+	db "blog.simoni.dev/db/generated"
+	"blog.simoni.dev/models"
+	"blog.simoni.dev/templates/components"
+	"blog.simoni.dev/templates/pages"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	searchPageSize = 20
+	liveSearchSize = 5
+)
+
+func (r *Router) HandleSearch(ctx *gin.Context) {
+	query := strings.TrimSpace(ctx.Query("q"))
+	isAdmin, _ := ctx.Get("isAdmin")
+	admin, _ := isAdmin.(bool)
+	includeDrafts := admin && ctx.Query("drafts") == "true"
+
+	var results []models.SearchResult
+	if query != "" {
+		var err error
+		results, err = r.searchPosts(ctx, query, includeDrafts, searchPageSize)
+		if err != nil {
+			log.Println("Search failed:", err)
+			ctx.AbortWithStatus(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	ctx.Status(http.StatusOK)
+	pages.SearchPage(query, includeDrafts, results).Render(createContext(ctx, "Search"), ctx.Writer)
+}
+
+// HandleLiveSearch renders the navbar search dropdown.
+func (r *Router) HandleLiveSearch(ctx *gin.Context) {
+	query := strings.TrimSpace(ctx.Query("q"))
+	if query == "" {
+		ctx.Status(http.StatusOK)
+		return
+	}
+
+	results, err := r.searchPosts(ctx, query, false, liveSearchSize)
+	if err != nil {
+		log.Println("Live search failed:", err)
+		ctx.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+	components.SearchDropdown(query, results).Render(createContext(ctx, "Search"), ctx.Writer)
+}
+
+func (r *Router) searchPosts(ctx *gin.Context, query string, includeDrafts bool, limit int32) ([]models.SearchResult, error) {
+	rows, err := r.Queries.SearchPosts(ctx.Request.Context(), db.SearchPostsParams{
+		Query:         query,
+		IncludeDrafts: includeDrafts,
+		PageSize:      limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]models.SearchResult, len(rows))
+	for i, row := range rows {
+		results[i] = models.SearchResult{
+			Post: models.BlogPost{
+				ID:          row.ID,
+				CreatedAt:   pgTimeToTime(row.CreatedAt),
+				UpdatedAt:   pgTimeToTime(row.UpdatedAt),
+				Title:       row.Title,
+				Author:      row.Author,
+				Slug:        row.Slug,
+				Description: row.Description,
+				Draft:       row.Draft,
+				PublishedAt: pgTimeToTimePtr(row.PublishedAt),
+			},
+			Rank:        row.Rank,
+			SnippetHtml: highlightSnippet(row.Snippet),
+		}
+	}
+	return results, nil
+}
+
+// highlightSnippet escapes a ts_headline snippet, keeping only the <mark>
+// tags Postgres wrapped around matching words.
+func highlightSnippet(snippet string) string {
+	escaped := html.EscapeString(snippet)
+	escaped = strings.ReplaceAll(escaped, "&lt;mark&gt;", "<mark>")
+	return strings.ReplaceAll(escaped, "&lt;/mark&gt;", "</mark>")
+}
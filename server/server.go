@@ -2,13 +2,31 @@ package server
 
 import (
 	"log"
+	"os"
+	"strings"
 
+	"blog.simoni.dev/permalink"
 	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 var adminRoute = "/admin"
 
+// trustedProxies lists the addresses or CIDR ranges of reverse proxies whose
+// X-Forwarded-For header is believed, set with TRUSTED_PROXIES as a comma
+// separated list. Without it the client IP is the address the connection
+// came from, which behind a proxy is the proxy for everyone, so rate limits
+// by IP would be shared by all visitors.
+func trustedProxies() []string {
+	var proxies []string
+	for _, proxy := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	return proxies
+}
+
 func NewServer(pool *pgxpool.Pool) (*gin.Engine, error) {
 	router := NewRouter(pool)
 
@@ -17,32 +35,80 @@ func NewServer(pool *pgxpool.Pool) (*gin.Engine, error) {
 
 	engine := gin.Default()
 
-	if err := engine.SetTrustedProxies(nil); err != nil {
+	if err := engine.SetTrustedProxies(trustedProxies()); err != nil {
 		return nil, err
 	}
 
-	engine.Use(IsHXRequest())
-	engine.Use(ExtractAuth())
-
+	// Static files are registered before the middleware so they skip it:
+	// they don't depend on who is signed in, and checking the session would
+	// cost a query for every stylesheet and script.
 	engine.Static("/css", "css")
 	engine.Static("/js", "js")
 
-	engine.NoRoute(router.HandleNotFound)
+	engine.Use(IsHXRequest())
+	engine.Use(ExtractAuth(router.Queries))
+
+	engine.NoRoute(router.HandleNoRoute)
 
 	// Regular pages
 	engine.GET("/", router.HandleIndex)
-	engine.GET("/post/:month/:day/:year/:slug", router.HandlePost)
+	engine.GET(permalink.Current().String(), router.HandlePost)
 	engine.GET("/tag/:tag", router.HandleTag)
 	engine.GET("/user/:username", router.HandleUser)
 	engine.GET("/settings", router.HandleSettings)
+	engine.GET("/settings/notifications", router.HandleNotificationSettings)
+	engine.GET("/settings/two-factor", router.HandleTwoFactorSettings)
+	engine.GET("/unsubscribe", router.HandleUnsubscribe)
+	engine.GET("/newsletter", router.HandleNewsletter)
+	engine.GET("/newsletter/confirm", router.HandleNewsletterConfirm)
+	engine.GET("/newsletter/unsubscribe", router.HandleNewsletterUnsubscribe)
 	engine.GET("/login", router.HandleLogin)
-
+	engine.GET("/register", router.HandleRegister)
+	engine.GET("/verify-email", router.HandleVerifyEmail)
+	engine.GET("/forgot-password", router.HandleForgotPassword)
+	engine.GET("/reset-password", router.HandleResetPassword)
+	engine.GET("/search", router.HandleSearch)
+	engine.GET("/search/live", router.HandleLiveSearch)
+
+	// Feeds
+	engine.GET("/feed.xml", router.HandleFeed)
+	engine.GET("/atom.xml", router.HandleAtomFeed)
+	engine.GET("/feed.json", router.HandleJsonFeed)
+	engine.GET("/tag/:tag/feed.xml", router.HandleTagFeed)
+	engine.GET("/tag/:tag/feed.json", router.HandleTagJsonFeed)
+	engine.GET("/user/:username/feed.xml", router.HandleUserFeed)
+	engine.GET("/user/:username/feed.json", router.HandleUserJsonFeed)
+
+	engine.GET("/sitemap.xml", router.HandleSitemap)
+	engine.GET("/sitemaps/:page", router.HandleSitemapPage)
+	engine.GET("/robots.txt", router.HandleRobots)
+
+	engine.GET("/pow/challenge/:form", router.HandlePowChallenge)
+
+	engine.GET("/comment/:postId/thread/:commentId", router.HandleCommentThread)
+	engine.POST("/comment/preview", router.HandleCommentPreview)
 	engine.POST("/comment/:postId", router.HandleComment)
+	engine.POST("/comment/:postId/:commentId/edit", router.HandleCommentEdit)
+	engine.DELETE("/comment/:postId/:commentId", router.HandleCommentDelete)
 
 	engine.POST("/user/username", router.HandleUsernameChange)
 	engine.POST("/user/password", router.HandlePasswordChange)
+	engine.POST("/settings/notifications", router.HandleNotificationSettingsUpdate)
+	engine.POST("/settings/verify-email", router.HandleResendVerification)
+	engine.POST("/settings/two-factor", router.HandleTwoFactorEnable)
+	engine.POST("/settings/two-factor/setup", router.HandleTwoFactorSetup)
+	engine.POST("/settings/two-factor/recovery-codes", router.HandleTwoFactorRecoveryCodes)
+	engine.POST("/settings/two-factor/disable", router.HandleTwoFactorDisable)
+	engine.POST("/unsubscribe", router.HandleUnsubscribeRequest)
+	engine.POST("/newsletter", router.HandleNewsletterSubscribe)
+	engine.POST("/newsletter/confirm", router.HandleNewsletterConfirmRequest)
+	engine.POST("/newsletter/unsubscribe", router.HandleNewsletterUnsubscribeRequest)
 
 	engine.POST("/login", router.HandleLoginRequest)
+	engine.POST("/login/two-factor", router.HandleTwoFactorLoginRequest)
+	engine.POST("/register", router.HandleRegisterRequest)
+	engine.POST("/forgot-password", router.HandleForgotPasswordRequest)
+	engine.POST("/reset-password", router.HandleResetPasswordRequest)
 	engine.GET("/logout", router.HandleLogoutRequest)
 
 	engine.GET("/wasm/:type", router.HandleWasmLoader)
@@ -52,16 +118,36 @@ func NewServer(pool *pgxpool.Pool) (*gin.Engine, error) {
 	engine.GET(adminRoute+"/new-post", router.HandleAdminNewBlogPost)
 	engine.GET(adminRoute+"/posts", router.HandleAdminPosts)
 	engine.GET(adminRoute+"/edit/:postId", router.HandlePostEdit)
+	engine.GET(adminRoute+"/post/:id/revisions", router.HandlePostRevisions)
+	engine.GET(adminRoute+"/post/:id/revisions/diff", router.HandlePostRevisionDiff)
+	engine.GET(adminRoute+"/slug-preview", router.HandleSlugPreview)
+	engine.GET(adminRoute+"/redirects", router.HandleAdminRedirects)
+	engine.GET(adminRoute+"/comments", router.HandleAdminComments)
+	engine.GET(adminRoute+"/spam", router.HandleAdminSpam)
+	engine.GET(adminRoute+"/outbox", router.HandleAdminOutbox)
+	engine.GET(adminRoute+"/outbox/:id", router.HandleAdminOutboxMail)
+	engine.GET(adminRoute+"/newsletter", router.HandleAdminNewsletter)
+	engine.GET(adminRoute+"/invites", router.HandleAdminInvites)
 
 	engine.POST(adminRoute+"/edit/:postId", router.PostPostEdit)
 	engine.POST(adminRoute+"/new-post", router.HandleAdminNewBlogPostRequest)
+	engine.POST(adminRoute+"/redirects", router.HandleAdminCreateRedirect)
+	engine.POST(adminRoute+"/comments", router.HandleAdminCommentsBulk)
+	engine.POST(adminRoute+"/spam/blocklist", router.HandleAdminCreateBlocklistEntry)
+	engine.POST(adminRoute+"/outbox/:id/resend", router.HandleAdminOutboxResend)
+	engine.POST(adminRoute+"/newsletter/subscribers/:id/reset", router.HandleAdminResetSubscriberBounces)
+	engine.POST(adminRoute+"/invites", router.HandleAdminCreateInvite)
 
 	// Authed utility endpoints
 	engine.POST(adminRoute+"/generate-markdown", router.HandleAdminGenerateMarkdown)
 	engine.POST(adminRoute+"/post/:id/tag", router.HandleAdminAddTagToPost)
+	engine.POST(adminRoute+"/post/:id/revisions/:revisionId/restore", router.HandlePostRevisionRestore)
 
 	engine.DELETE(adminRoute+"/post/:id", router.HandleAdminPostsDelete)
 	engine.DELETE(adminRoute+"/post/:id/tag/:tagId", router.HandleAdminDeleteTagFromPost)
+	engine.DELETE(adminRoute+"/redirects/:id", router.HandleAdminDeleteRedirect)
+	engine.DELETE(adminRoute+"/spam/blocklist/:id", router.HandleAdminDeleteBlocklistEntry)
+	engine.DELETE(adminRoute+"/invites/:id", router.HandleAdminDeleteInvite)
 
 	engine.GET("/hp", router.HandleHealth)
 
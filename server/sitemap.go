@@ -0,0 +1,138 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"blog.simoni.dev/models"
+	"blog.simoni.dev/sitemap"
+	"github.com/gin-gonic/gin"
+)
+
+var robotsDisallow = []string{adminRoute, "/login", "/wasm/", "/settings"}
+
+func (r *Router) HandleRobots(ctx *gin.Context) {
+	var sb strings.Builder
+	sb.WriteString("User-agent: *\n")
+	for _, path := range robotsDisallow {
+		sb.WriteString("Disallow: " + path + "\n")
+	}
+	sb.WriteString("\nSitemap: " + absoluteURL("/sitemap.xml") + "\n")
+
+	ctx.String(http.StatusOK, sb.String())
+}
+
+// HandleSitemap serves the full sitemap, or a sitemap index pointing at
+// /sitemaps/:page once there are too many URLs for a single file.
+func (r *Router) HandleSitemap(ctx *gin.Context) {
+	urls, err := r.sitemapUrls(ctx.Request.Context())
+	if err != nil {
+		log.Println("Sitemap failed to load urls:", err)
+		ctx.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	chunks := sitemap.Split(urls)
+
+	var body []byte
+	if len(chunks) == 1 {
+		body, err = sitemap.UrlSet(urls)
+	} else {
+		locs := make([]string, len(chunks))
+		for i := range chunks {
+			locs[i] = absoluteURL(fmt.Sprintf("/sitemaps/%d.xml", i+1))
+		}
+		body, err = sitemap.Index(locs, chunks)
+	}
+	if err != nil {
+		log.Println("Sitemap failed to render:", err)
+		ctx.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	ctx.Data(http.StatusOK, "application/xml; charset=utf-8", body)
+}
+
+func (r *Router) HandleSitemapPage(ctx *gin.Context) {
+	page, err := strconv.Atoi(strings.TrimSuffix(ctx.Param("page"), ".xml"))
+	if err != nil || page < 1 {
+		r.HandleNotFound(ctx)
+		return
+	}
+
+	urls, err := r.sitemapUrls(ctx.Request.Context())
+	if err != nil {
+		log.Println("Sitemap page failed to load urls:", err)
+		ctx.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	chunks := sitemap.Split(urls)
+	if page > len(chunks) {
+		r.HandleNotFound(ctx)
+		return
+	}
+
+	body, err := sitemap.UrlSet(chunks[page-1])
+	if err != nil {
+		log.Println("Sitemap page failed to render:", err)
+		ctx.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	ctx.Data(http.StatusOK, "application/xml; charset=utf-8", body)
+}
+
+func (r *Router) sitemapUrls(ctx context.Context) ([]sitemap.Url, error) {
+	postRows, err := r.Queries.GetSitemapPosts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tagRows, err := r.Queries.GetSitemapTags(ctx)
+	if err != nil {
+		return nil, err
+	}
+	authorRows, err := r.Queries.GetSitemapAuthors(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var lastPost time.Time
+	urls := make([]sitemap.Url, 0, 1+len(postRows)+len(tagRows)+len(authorRows))
+	urls = append(urls, sitemap.Url{Loc: absoluteURL("/")})
+	for _, row := range postRows {
+		post := models.BlogPost{
+			ID:          row.ID,
+			CreatedAt:   pgTimeToTime(row.CreatedAt),
+			UpdatedAt:   pgTimeToTime(row.UpdatedAt),
+			Slug:        row.Slug,
+			PublishedAt: pgTimeToTimePtr(row.PublishedAt),
+		}
+		if post.UpdatedAt.After(lastPost) {
+			lastPost = post.UpdatedAt
+		}
+		urls = append(urls, sitemap.Url{Loc: absoluteURL(post.Permalink()), LastMod: post.UpdatedAt})
+	}
+	urls[0].LastMod = lastPost
+
+	for _, row := range tagRows {
+		urls = append(urls, sitemap.Url{
+			Loc:     absoluteURL("/tag/" + url.PathEscape(row.Name)),
+			LastMod: pgTimeToTime(row.LastModified),
+		})
+	}
+	for _, row := range authorRows {
+		urls = append(urls, sitemap.Url{
+			Loc:     absoluteURL("/user/" + url.PathEscape(row.Author)),
+			LastMod: pgTimeToTime(row.LastModified),
+		})
+	}
+
+	return urls, nil
+}
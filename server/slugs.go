@@ -0,0 +1,45 @@
+package server
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	db "blog.simoni.dev/db/generated"
+	"blog.simoni.dev/templates/admin"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// HandleSlugPreview renders the permalink a post would get with the slug
+// being typed into the editor, and whether another post already uses it.
+func (r *Router) HandleSlugPreview(ctx *gin.Context) {
+	postId, _ := strconv.ParseInt(ctx.Query("id"), 10, 64)
+
+	slug := normalizeSlug(ctx.Query("slug"))
+	if slug == "" {
+		slug = postSlug(ctx.Query("title"))
+	}
+
+	preview := db.BlogPost{ID: postId, Slug: slug, PublishedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true}}
+	if postId != 0 {
+		if row, err := r.Queries.GetPostByID(ctx.Request.Context(), postId); err == nil && row.PublishedAt.Valid {
+			preview.PublishedAt = row.PublishedAt
+		}
+	}
+
+	taken := false
+	if slug != "" {
+		var err error
+		taken, err = r.Queries.IsSlugTaken(ctx.Request.Context(), db.IsSlugTakenParams{Slug: slug, ID: postId})
+		if err != nil {
+			log.Println("Slug preview failed:", err)
+			ctx.AbortWithStatus(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	ctx.Status(http.StatusOK)
+	admin.SlugPreview(postPermalink(preview), taken, len(slug) > maxSlugLength).Render(ctx.Request.Context(), ctx.Writer)
+}
@@ -0,0 +1,259 @@
+package server
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	db "blog.simoni.dev/db/generated"
+	"blog.simoni.dev/models"
+	"blog.simoni.dev/spam"
+	"blog.simoni.dev/templates/admin"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultSpamMaxLinks    = 2
+	defaultSpamMinFormTime = 3 * time.Second
+	// Forms older than this are held rather than trusted.
+	spamMaxFormAge = 24 * time.Hour
+	// The classifier holds comments at least this likely to be spam once it
+	// has seen spamMinTrained comments of each kind.
+	spamThreshold  = 0.9
+	spamMinTrained = 10
+	maxBlockedTerm = 100
+)
+
+// Names of the extra comment form fields the spam filter reads.
+const (
+	// honeypotField is hidden from people, so anything in it came from a bot.
+	honeypotField  = "website"
+	formTokenField = "form_token"
+)
+
+// Classifier training labels, stored in comments.trained_as.
+const (
+	trainedHam  = "ham"
+	trainedSpam = "spam"
+)
+
+// spamMaxLinks is how many links a comment may have before it is held, set
+// with SPAM_MAX_LINKS.
+func spamMaxLinks() int {
+	if n, err := strconv.Atoi(os.Getenv("SPAM_MAX_LINKS")); err == nil && n >= 0 {
+		return n
+	}
+	return defaultSpamMaxLinks
+}
+
+// spamMinFormTime is how long a comment form has to be open before it can be
+// posted, set with SPAM_MIN_FORM_TIME as a duration such as 5s.
+func spamMinFormTime() time.Duration {
+	if d, err := time.ParseDuration(os.Getenv("SPAM_MIN_FORM_TIME")); err == nil && d >= 0 {
+		return d
+	}
+	return defaultSpamMinFormTime
+}
+
+func formTokenSecret() []byte {
+	return []byte(os.Getenv("JWT_SECRET"))
+}
+
+// newCommentFormToken is rendered into every comment form so the time spent
+// on the page can be checked when the comment comes in.
+func newCommentFormToken() string {
+	return spam.NewFormToken(formTokenSecret(), time.Now())
+}
+
+// withCommentForm adds what comment forms are rendered with to the context
+// of a page that has them.
+func withCommentForm(ct context.Context) context.Context {
+	ct = context.WithValue(ct, "commentFormToken", newCommentFormToken())
+	return withPowForms(ct)
+}
+
+// newSpamFilter builds the checks every comment not written by an admin goes
+// through, cheapest first.
+func newSpamFilter(queries *db.Queries) spam.Filter {
+	return spam.Filter{
+		spam.Honeypot{},
+		spam.TimeOnPage{Secret: formTokenSecret(), Min: spamMinFormTime(), Max: spamMaxFormAge},
+		spam.LinkCount{Max: spamMaxLinks()},
+		spam.Blocklist{Load: func(ctx context.Context) ([]spam.BlockedTerm, error) {
+			rows, err := queries.GetBlocklist(ctx)
+			if err != nil {
+				return nil, err
+			}
+			terms := make([]spam.BlockedTerm, len(rows))
+			for i, row := range rows {
+				terms[i] = spam.BlockedTerm{Kind: row.Kind, Value: row.Value}
+			}
+			return terms, nil
+		}},
+		spam.Bayes{Store: bayesStore{queries}, Threshold: spamThreshold, MinTrained: spamMinTrained},
+	}
+}
+
+// checkSpam runs the spam filter over a new comment. Admins skip it.
+func (r *Router) checkSpam(ctx *gin.Context, author, comment string) spam.Result {
+	isAdmin, _ := ctx.Get("isAdmin")
+	if admin, _ := isAdmin.(bool); admin {
+		return spam.Result{Verdict: spam.Pass}
+	}
+
+	result, errs := r.Spam.Check(ctx.Request.Context(), spam.Submission{
+		Author:    author,
+		Body:      comment,
+		Honeypot:  ctx.PostForm(honeypotField),
+		FormToken: ctx.PostForm(formTokenField),
+	})
+	for _, err := range errs {
+		log.Println("Spam check failed:", err)
+	}
+	if result.Verdict != spam.Pass {
+		log.Printf("Spam filter %v comment by %q: %s\n", result.Verdict, author, result.Reason)
+	}
+	return result
+}
+
+// bayesStore keeps the classifier's counts in Postgres.
+type bayesStore struct {
+	queries *db.Queries
+}
+
+func (s bayesStore) TokenCounts(ctx context.Context, tokens []string) (map[string]spam.TokenCount, error) {
+	rows, err := s.queries.GetSpamTokens(ctx, tokens)
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]spam.TokenCount, len(rows))
+	for _, row := range rows {
+		counts[row.Token] = spam.TokenCount{Ham: row.HamCount, Spam: row.SpamCount}
+	}
+	return counts, nil
+}
+
+func (s bayesStore) Totals(ctx context.Context) (int64, int64, error) {
+	row, err := s.queries.CountTrainedComments(ctx)
+	return row.Ham, row.Spam, err
+}
+
+// trainSpamFilter teaches the classifier the moderators' decision on the
+// given comments. Approved comments are learnt as ham and spam as spam; a
+// comment learnt the other way before is unlearnt first. Other statuses leave
+// the classifier alone.
+func trainSpamFilter(ctx context.Context, queries *db.Queries, ids []int64, status string) error {
+	var label string
+	switch status {
+	case models.CommentApproved:
+		label = trainedHam
+	case models.CommentSpam:
+		label = trainedSpam
+	default:
+		return nil
+	}
+
+	comments, err := queries.GetCommentsToTrain(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for _, c := range comments {
+		if c.TrainedAs != nil && *c.TrainedAs == label {
+			continue
+		}
+		tokens := spam.Tokens(c.Author + " " + c.Comment)
+		if c.TrainedAs != nil {
+			if err := queries.TrainSpamTokens(ctx, trainingCounts(tokens, *c.TrainedAs, -1)); err != nil {
+				return err
+			}
+		}
+		if err := queries.TrainSpamTokens(ctx, trainingCounts(tokens, label, 1)); err != nil {
+			return err
+		}
+		if err := queries.SetCommentTrainedAs(ctx, db.SetCommentTrainedAsParams{ID: c.ID, TrainedAs: &label}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func trainingCounts(tokens []string, label string, n int64) db.TrainSpamTokensParams {
+	params := db.TrainSpamTokensParams{Tokens: tokens}
+	if label == trainedSpam {
+		params.Spam = n
+	} else {
+		params.Ham = n
+	}
+	return params
+}
+
+func (r *Router) HandleAdminSpam(ctx *gin.Context) {
+	rows, err := r.Queries.GetBlocklist(ctx.Request.Context())
+	if err != nil {
+		log.Println("Blocklist failed:", err)
+		ctx.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+	trained, err := r.Queries.CountTrainedComments(ctx.Request.Context())
+	if err != nil {
+		log.Println("Spam stats failed:", err)
+		ctx.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+	tokens, err := r.Queries.CountSpamTokens(ctx.Request.Context())
+	if err != nil {
+		log.Println("Spam stats failed:", err)
+		ctx.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	stats := models.SpamStats{TrainedHam: trained.Ham, TrainedSpam: trained.Spam, Tokens: tokens}
+	ctx.Status(http.StatusOK)
+	admin.SpamPage(mapBlocklist(rows), spam.BlockKinds, stats).Render(createContext(ctx, "Spam filter"), ctx.Writer)
+}
+
+func (r *Router) HandleAdminCreateBlocklistEntry(ctx *gin.Context) {
+	kind := ctx.PostForm("kind")
+	if !slices.Contains(spam.BlockKinds, kind) {
+		r.HandleError(ctx, "Unknown blocklist kind", nil, nil)
+		return
+	}
+	value := strings.ToLower(strings.TrimSpace(ctx.PostForm("value")))
+	if kind == spam.BlockDomain {
+		value = strings.TrimPrefix(strings.TrimPrefix(value, "https://"), "http://")
+		value = strings.TrimPrefix(strings.TrimSuffix(value, "/"), "www.")
+	}
+	if value == "" || len(value) > maxBlockedTerm {
+		r.HandleError(ctx, "Enter a word or domain of up to 100 characters", nil, nil)
+		return
+	}
+
+	if _, err := r.Queries.CreateBlocklistEntry(ctx.Request.Context(), db.CreateBlocklistEntryParams{
+		Kind:  kind,
+		Value: value,
+	}); err != nil {
+		r.HandleError(ctx, "Failed to add to the blocklist. Is it on there already?", nil, err)
+		return
+	}
+
+	ctx.Redirect(http.StatusFound, adminRoute+"/spam")
+}
+
+func (r *Router) HandleAdminDeleteBlocklistEntry(ctx *gin.Context) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		r.HandleError(ctx, "Invalid blocklist entry", nil, err)
+		return
+	}
+	if err := r.Queries.DeleteBlocklistEntry(ctx.Request.Context(), id); err != nil {
+		r.HandleError(ctx, "Failed to remove from the blocklist", nil, err)
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+}
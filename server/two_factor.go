@@ -0,0 +1,370 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"blog.simoni.dev/auth"
+	db "blog.simoni.dev/db/generated"
+	"blog.simoni.dev/models"
+	"blog.simoni.dev/qr"
+	"blog.simoni.dev/templates/pages"
+	"blog.simoni.dev/totp"
+	"github.com/gin-gonic/gin"
+)
+
+const twoFactorRoute = "/settings/two-factor"
+
+// twoFactorAttempts is how many codes may be tried per account in each
+// twoFactorWindow, which keeps six digit codes from being guessed.
+const (
+	twoFactorAttempts = 5
+	twoFactorWindow   = 5 * time.Minute
+)
+
+// requireAdminTwoFactor reports whether admins have to use two-factor
+// authentication, set with REQUIRE_ADMIN_2FA. Admins without it are sent
+// to enroll before they can use the admin pages.
+func requireAdminTwoFactor() bool {
+	required, _ := strconv.ParseBool(os.Getenv("REQUIRE_ADMIN_2FA"))
+	return required
+}
+
+// totpIssuer is the name authenticator apps show the account under.
+func totpIssuer() string {
+	if u, err := url.Parse(siteURL()); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return siteURL()
+}
+
+// checkSecondFactor reports whether code is a current authenticator code,
+// or one of the user's unused recovery codes. Either is used up by
+// checking it.
+func checkSecondFactor(ctx context.Context, queries *db.Queries, user models.User, code string) (bool, error) {
+	code = strings.TrimSpace(code)
+	if step, ok := totp.Validate(user.TOTPSecret, code, time.Now(), user.TOTPLastStep); ok {
+		// Recording the step only succeeds once, even if the same code
+		// comes in twice at the same time.
+		rows, err := queries.UseTOTPStep(ctx, db.UseTOTPStepParams{ID: user.ID, Step: step})
+		return rows == 1, err
+	}
+	if len(code) <= totp.Digits {
+		return false, nil
+	}
+	rows, err := queries.UseRecoveryCode(ctx, db.UseRecoveryCodeParams{UserID: user.ID, CodeHash: auth.HashRecoveryCode(code)})
+	return rows == 1, err
+}
+
+// allowTwoFactorAttempt rate limits code attempts for user.
+func (r *Router) allowTwoFactorAttempt(userId int64) bool {
+	return r.TwoFactor.Allow(strconv.FormatInt(userId, 10))
+}
+
+// HandleTwoFactorLoginRequest is the second login step, for accounts with
+// two-factor authentication. It takes the token from the first step and a
+// code, and only then signs the user in.
+func (r *Router) HandleTwoFactorLoginRequest(ctx *gin.Context) {
+	token := ctx.PostForm("token")
+	redirectPath := ctx.PostForm("redirect")
+	code := ctx.PostForm("code")
+
+	fail := func(message string, err error) {
+		if err != nil {
+			log.Println("Two-factor login failed:", err)
+		}
+		r.HandleError(ctx, message, func(ctx *gin.Context) {
+			pages.TwoFactorLoginPage(token, redirectPath, message).Render(createContext(ctx, "Login"), ctx.Writer)
+		}, nil)
+	}
+	restart := func(message string) {
+		r.HandleError(ctx, message, func(ctx *gin.Context) {
+			pages.LoginPage(redirectPath, message).Render(withPowForms(createContext(ctx, "Login")), ctx.Writer)
+		}, nil)
+	}
+
+	userId, sessionVersion, err := auth.VerifyTwoFactorLoginToken(token)
+	if errors.Is(err, auth.ErrTwoFactorLoginExpired) {
+		restart("That took too long. Please log in again.")
+		return
+	}
+	if err != nil {
+		restart("Please log in again.")
+		return
+	}
+
+	if !r.allowTwoFactorAttempt(userId) {
+		fail("Too many attempts. Try again in a few minutes.", nil)
+		return
+	}
+
+	row, err := r.Queries.GetUserByID(ctx.Request.Context(), userId)
+	if err != nil {
+		restart("Please log in again.")
+		return
+	}
+	user := mapUser(row)
+	if user.SessionVersion != sessionVersion || !user.TwoFactorEnabled() {
+		restart("Please log in again.")
+		return
+	}
+
+	ok, err := checkSecondFactor(ctx.Request.Context(), r.Queries, user, code)
+	if err != nil || !ok {
+		fail("That code didn't work.", err)
+		return
+	}
+
+	if _, err := user.NewAuthTokens(ctx); err != nil {
+		fail("Failed to log in.", err)
+		return
+	}
+	if redirectPath == "" {
+		redirectPath = "/"
+	}
+	ctx.Redirect(http.StatusFound, redirectPath)
+}
+
+// HandleTwoFactorSettings shows whether two-factor authentication is on.
+// When it isn't, it starts enrolling with a new secret and its QR code.
+func (r *Router) HandleTwoFactorSettings(ctx *gin.Context) {
+	user, ok := r.loadSignedInUser(ctx)
+	if !ok {
+		return
+	}
+
+	c := ctx.Request.Context()
+	if user.TwoFactorEnabled() {
+		remaining, err := r.Queries.CountRecoveryCodes(c, user.ID)
+		if err != nil {
+			log.Println("Two-factor settings failed to count recovery codes:", err)
+			ctx.AbortWithStatus(http.StatusInternalServerError)
+			return
+		}
+		canDisable := !(user.Admin && requireAdminTwoFactor())
+		ctx.Status(http.StatusOK)
+		pages.TwoFactorStatusPage(remaining, canDisable).Render(createContext(ctx, "Two-factor authentication"), ctx.Writer)
+		return
+	}
+
+	// The pending secret is only shown here, never replaced, so reloading
+	// the page or opening it twice keeps the code that was scanned working.
+	var qrSVG string
+	if user.TOTPSecret != "" {
+		code, err := qr.Encode(totp.URI(totpIssuer(), user.Username, user.TOTPSecret))
+		if err != nil {
+			log.Println("Two-factor settings failed to draw the QR code:", err)
+			ctx.AbortWithStatus(http.StatusInternalServerError)
+			return
+		}
+		qrSVG = code.SVG()
+	}
+
+	required := user.Admin && requireAdminTwoFactor()
+	ctx.Status(http.StatusOK)
+	pages.TwoFactorSetupPage(qrSVG, user.TOTPSecret, required).Render(createContext(ctx, "Two-factor authentication"), ctx.Writer)
+}
+
+// HandleTwoFactorSetup starts enrolling with a new secret, replacing any
+// pending one from an earlier attempt.
+func (r *Router) HandleTwoFactorSetup(ctx *gin.Context) {
+	user, ok := r.loadSignedInUser(ctx)
+	if !ok {
+		return
+	}
+	if user.TwoFactorEnabled() {
+		ctx.Redirect(http.StatusFound, twoFactorRoute)
+		return
+	}
+
+	secret, err := totp.NewSecret()
+	if err != nil {
+		r.HandleError(ctx, "Failed to set up two-factor authentication", nil, err)
+		return
+	}
+	if err := r.Queries.SetPendingTOTPSecret(ctx.Request.Context(), db.SetPendingTOTPSecretParams{ID: user.ID, TotpSecret: secret}); err != nil {
+		r.HandleError(ctx, "Failed to set up two-factor authentication", nil, err)
+		return
+	}
+	ctx.Redirect(http.StatusFound, twoFactorRoute)
+}
+
+// HandleTwoFactorEnable finishes enrolling once the user has entered a code
+// from their app, and shows their recovery codes.
+func (r *Router) HandleTwoFactorEnable(ctx *gin.Context) {
+	user, ok := r.loadSignedInUser(ctx)
+	if !ok {
+		return
+	}
+	if user.TwoFactorEnabled() || user.TOTPSecret == "" {
+		ctx.Redirect(http.StatusFound, twoFactorRoute)
+		return
+	}
+	if !r.allowTwoFactorAttempt(user.ID) {
+		r.HandleError(ctx, "Too many attempts. Try again in a few minutes.", nil, nil)
+		return
+	}
+
+	step, ok := totp.Validate(user.TOTPSecret, strings.TrimSpace(ctx.PostForm("code")), time.Now(), 0)
+	if !ok {
+		// The secret is still pending, so keep the QR code that was
+		// scanned rather than making a new one.
+		r.HandleError(ctx, "That code didn't work. Check your device's clock and try again.", nil, nil)
+		return
+	}
+
+	c := ctx.Request.Context()
+	tx, err := r.Pool.Begin(c)
+	if err != nil {
+		r.HandleError(ctx, "Failed to turn on two-factor authentication", nil, err)
+		return
+	}
+	defer tx.Rollback(c)
+	qtx := r.Queries.WithTx(tx)
+
+	version, err := qtx.EnableTOTP(c, db.EnableTOTPParams{ID: user.ID, TotpLastStep: step})
+	if err != nil {
+		r.HandleError(ctx, "Failed to turn on two-factor authentication", nil, err)
+		return
+	}
+	codes, err := replaceRecoveryCodes(c, qtx, user.ID)
+	if err != nil {
+		r.HandleError(ctx, "Failed to turn on two-factor authentication", nil, err)
+		return
+	}
+	if err := tx.Commit(c); err != nil {
+		r.HandleError(ctx, "Failed to turn on two-factor authentication", nil, err)
+		return
+	}
+
+	now := time.Now()
+	user.SessionVersion = version
+	user.TOTPEnabledAt = &now
+	if _, err := user.NewAuthTokens(ctx); err != nil {
+		log.Println("Two-factor enable failed to generate tokens:", err)
+	}
+	ctx.Status(http.StatusOK)
+	pages.RecoveryCodesPage(codes).Render(createContext(ctx, "Recovery codes"), ctx.Writer)
+}
+
+// HandleTwoFactorRecoveryCodes replaces the user's recovery codes with a
+// new set, which takes a current code.
+func (r *Router) HandleTwoFactorRecoveryCodes(ctx *gin.Context) {
+	user, ok := r.loadSignedInUser(ctx)
+	if !ok {
+		return
+	}
+	if !user.TwoFactorEnabled() {
+		ctx.Redirect(http.StatusFound, twoFactorRoute)
+		return
+	}
+	if !r.allowTwoFactorAttempt(user.ID) {
+		r.HandleError(ctx, "Too many attempts. Try again in a few minutes.", nil, nil)
+		return
+	}
+
+	c := ctx.Request.Context()
+	tx, err := r.Pool.Begin(c)
+	if err != nil {
+		r.HandleError(ctx, "Failed to make new recovery codes", nil, err)
+		return
+	}
+	defer tx.Rollback(c)
+	qtx := r.Queries.WithTx(tx)
+
+	if ok, err := checkSecondFactor(c, qtx, user, ctx.PostForm("code")); err != nil || !ok {
+		r.HandleError(ctx, "That code didn't work", nil, err)
+		return
+	}
+	codes, err := replaceRecoveryCodes(c, qtx, user.ID)
+	if err != nil {
+		r.HandleError(ctx, "Failed to make new recovery codes", nil, err)
+		return
+	}
+	if err := tx.Commit(c); err != nil {
+		r.HandleError(ctx, "Failed to make new recovery codes", nil, err)
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+	pages.RecoveryCodesPage(codes).Render(createContext(ctx, "Recovery codes"), ctx.Writer)
+}
+
+// HandleTwoFactorDisable turns two-factor authentication off, which takes a
+// current code. Admins can't while it is required.
+func (r *Router) HandleTwoFactorDisable(ctx *gin.Context) {
+	user, ok := r.loadSignedInUser(ctx)
+	if !ok {
+		return
+	}
+	if !user.TwoFactorEnabled() {
+		ctx.Redirect(http.StatusFound, twoFactorRoute)
+		return
+	}
+	if user.Admin && requireAdminTwoFactor() {
+		r.HandleError(ctx, "Admins have to use two-factor authentication", nil, nil)
+		return
+	}
+	if !r.allowTwoFactorAttempt(user.ID) {
+		r.HandleError(ctx, "Too many attempts. Try again in a few minutes.", nil, nil)
+		return
+	}
+
+	c := ctx.Request.Context()
+	tx, err := r.Pool.Begin(c)
+	if err != nil {
+		r.HandleError(ctx, "Failed to turn off two-factor authentication", nil, err)
+		return
+	}
+	defer tx.Rollback(c)
+	qtx := r.Queries.WithTx(tx)
+
+	if ok, err := checkSecondFactor(c, qtx, user, ctx.PostForm("code")); err != nil || !ok {
+		r.HandleError(ctx, "That code didn't work", nil, err)
+		return
+	}
+	if err := qtx.DisableTOTP(c, user.ID); err != nil {
+		r.HandleError(ctx, "Failed to turn off two-factor authentication", nil, err)
+		return
+	}
+	if err := qtx.DeleteRecoveryCodes(c, user.ID); err != nil {
+		r.HandleError(ctx, "Failed to turn off two-factor authentication", nil, err)
+		return
+	}
+	if err := tx.Commit(c); err != nil {
+		r.HandleError(ctx, "Failed to turn off two-factor authentication", nil, err)
+		return
+	}
+
+	user.TOTPEnabledAt = nil
+	if _, err := user.NewAuthTokens(ctx); err != nil {
+		log.Println("Two-factor disable failed to generate tokens:", err)
+	}
+	if r.HandleToast(ctx, "Two-factor authentication is off") {
+		return
+	}
+	ctx.Redirect(http.StatusFound, twoFactorRoute)
+}
+
+// replaceRecoveryCodes throws away the user's recovery codes and stores a
+// new set, returning the codes to show them.
+func replaceRecoveryCodes(ctx context.Context, queries *db.Queries, userId int64) ([]string, error) {
+	codes, hashes, err := auth.NewRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := queries.DeleteRecoveryCodes(ctx, userId); err != nil {
+		return nil, err
+	}
+	if err := queries.AddRecoveryCodes(ctx, db.AddRecoveryCodesParams{UserID: userId, CodeHashes: hashes}); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
@@ -0,0 +1,87 @@
+package sitemap
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+// MaxUrls is the most URLs a single sitemap file may hold according to the
+// sitemaps.org protocol.
+const MaxUrls = 50000
+
+type Url struct {
+	Loc     string
+	LastMod time.Time
+}
+
+type urlSet struct {
+	XMLName xml.Name   `xml:"http://www.sitemaps.org/schemas/sitemap/0.9 urlset"`
+	Urls    []urlEntry `xml:"url"`
+}
+
+type urlEntry struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+type sitemapIndex struct {
+	XMLName  xml.Name       `xml:"http://www.sitemaps.org/schemas/sitemap/0.9 sitemapindex"`
+	Sitemaps []sitemapEntry `xml:"sitemap"`
+}
+
+type sitemapEntry struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+// Split breaks urls into chunks that each fit into a single sitemap file.
+func Split(urls []Url) [][]Url {
+	var chunks [][]Url
+	for len(urls) > MaxUrls {
+		chunks = append(chunks, urls[:MaxUrls])
+		urls = urls[MaxUrls:]
+	}
+	return append(chunks, urls)
+}
+
+// UrlSet renders urls as a sitemap urlset document.
+func UrlSet(urls []Url) ([]byte, error) {
+	set := urlSet{Urls: make([]urlEntry, len(urls))}
+	for i, u := range urls {
+		set.Urls[i] = urlEntry{Loc: u.Loc, LastMod: formatLastMod(u.LastMod)}
+	}
+	return marshalXml(set)
+}
+
+// Index renders a sitemap index pointing at the sitemap files in locs. Each
+// entry's lastmod is the most recent lastmod of the matching chunk.
+func Index(locs []string, chunks [][]Url) ([]byte, error) {
+	index := sitemapIndex{Sitemaps: make([]sitemapEntry, len(locs))}
+	for i, loc := range locs {
+		var lastMod time.Time
+		if i < len(chunks) {
+			for _, u := range chunks[i] {
+				if u.LastMod.After(lastMod) {
+					lastMod = u.LastMod
+				}
+			}
+		}
+		index.Sitemaps[i] = sitemapEntry{Loc: loc, LastMod: formatLastMod(lastMod)}
+	}
+	return marshalXml(index)
+}
+
+func formatLastMod(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func marshalXml(v any) ([]byte, error) {
+	out, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), out...), nil
+}
@@ -0,0 +1,60 @@
+package sitemap
+
+import (
+	"encoding/xml"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestSplit(t *testing.T) {
+	urls := make([]Url, MaxUrls*2+1)
+	for i := range urls {
+		urls[i] = Url{Loc: fmt.Sprintf("https://example.com/%d", i)}
+	}
+
+	chunks := Split(urls)
+	if len(chunks) != 3 {
+		t.Fatalf("got %d chunks, want 3", len(chunks))
+	}
+	if len(chunks[0]) != MaxUrls || len(chunks[1]) != MaxUrls || len(chunks[2]) != 1 {
+		t.Errorf("chunk sizes = %d, %d, %d", len(chunks[0]), len(chunks[1]), len(chunks[2]))
+	}
+
+	if chunks := Split(urls[:10]); len(chunks) != 1 || len(chunks[0]) != 10 {
+		t.Errorf("small sitemap was split into %d chunks", len(chunks))
+	}
+}
+
+func TestIndex(t *testing.T) {
+	older := time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC)
+	newer := older.Add(48 * time.Hour)
+	chunks := [][]Url{
+		{{Loc: "https://example.com/a", LastMod: older}, {Loc: "https://example.com/b", LastMod: newer}},
+		{{Loc: "https://example.com/c"}},
+	}
+
+	out, err := Index([]string{"https://example.com/sitemaps/1.xml", "https://example.com/sitemaps/2.xml"}, chunks)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var doc struct {
+		Sitemaps []struct {
+			Loc     string `xml:"loc"`
+			LastMod string `xml:"lastmod"`
+		} `xml:"sitemap"`
+	}
+	if err := xml.Unmarshal(out, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Sitemaps) != 2 {
+		t.Fatalf("got %d sitemaps, want 2", len(doc.Sitemaps))
+	}
+	if doc.Sitemaps[0].LastMod != "2026-01-03T00:00:00Z" {
+		t.Errorf("lastmod = %q", doc.Sitemaps[0].LastMod)
+	}
+	if doc.Sitemaps[1].LastMod != "" {
+		t.Errorf("empty chunk lastmod = %q", doc.Sitemaps[1].LastMod)
+	}
+}
@@ -0,0 +1,109 @@
+package spam
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	minTokenLength = 3
+	maxTokenLength = 30
+	// maxTokens caps how many distinct tokens of a comment are looked at.
+	maxTokens = 200
+)
+
+// Tokens splits text into the distinct tokens the classifier learns from:
+// lowercased words, and the host of every link prefixed with "host:".
+func Tokens(text string) []string {
+	seen := make(map[string]bool)
+	var tokens []string
+	add := func(t string) {
+		if !seen[t] && len(tokens) < maxTokens {
+			seen[t] = true
+			tokens = append(tokens, t)
+		}
+	}
+
+	for _, host := range LinkHosts(text) {
+		add("host:" + host)
+	}
+	for _, w := range wordPattern.FindAllString(strings.ToLower(linkPattern.ReplaceAllString(text, " ")), -1) {
+		if n := utf8.RuneCountInString(w); n >= minTokenLength && n <= maxTokenLength {
+			add(w)
+		}
+	}
+	slices.Sort(tokens)
+	return tokens
+}
+
+// TokenCount is how many ham and spam comments a token has been seen in.
+type TokenCount struct {
+	Ham  int64
+	Spam int64
+}
+
+// BayesStore holds what the classifier has learnt from moderators.
+type BayesStore interface {
+	// TokenCounts returns the counts of the given tokens. Tokens never seen
+	// may be left out.
+	TokenCounts(ctx context.Context, tokens []string) (map[string]TokenCount, error)
+	// Totals returns how many ham and spam comments have been learnt from.
+	Totals(ctx context.Context) (ham, spam int64, err error)
+}
+
+// Bayes is a naive Bayes classifier over comment tokens. It holds comments
+// whose spam probability reaches Threshold, and stays quiet until it has
+// learnt from at least MinTrained comments of each kind.
+type Bayes struct {
+	Store      BayesStore
+	Threshold  float64
+	MinTrained int64
+}
+
+func (b Bayes) Check(ctx context.Context, s Submission) (Result, error) {
+	ham, spam, err := b.Store.Totals(ctx)
+	if err != nil {
+		return Result{}, err
+	}
+	if ham < b.MinTrained || spam < b.MinTrained {
+		return Result{}, nil
+	}
+
+	tokens := Tokens(s.Author + " " + s.Body)
+	counts, err := b.Store.TokenCounts(ctx, tokens)
+	if err != nil {
+		return Result{}, err
+	}
+
+	p := SpamProbability(tokens, counts, ham, spam)
+	if p >= b.Threshold {
+		return Result{Verdict: Hold, Reason: fmt.Sprintf("%.0f%% likely spam", p*100)}, nil
+	}
+	return Result{}, nil
+}
+
+// SpamProbability combines the per-token probabilities of tokens into the
+// probability that the comment is spam, starting from the share of spam
+// among the learnt comments. Counts are smoothed so one sighting of a token
+// doesn't decide a comment.
+func SpamProbability(tokens []string, counts map[string]TokenCount, ham, spam int64) float64 {
+	if ham == 0 || spam == 0 {
+		return 0
+	}
+	// Work in log odds to stay clear of underflow on long comments.
+	logOdds := math.Log(float64(spam)) - math.Log(float64(ham))
+	for _, t := range tokens {
+		c, ok := counts[t]
+		if !ok {
+			continue
+		}
+		pSpam := (float64(c.Spam) + 1) / (float64(spam) + 2)
+		pHam := (float64(c.Ham) + 1) / (float64(ham) + 2)
+		logOdds += math.Log(pSpam) - math.Log(pHam)
+	}
+	return 1 / (1 + math.Exp(-logOdds))
+}
@@ -0,0 +1,115 @@
+package spam
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var (
+	linkPattern = regexp.MustCompile(`(?i)\bhttps?://[^\s<>()\[\]"']+|\bwww\.[^\s<>()\[\]"']+`)
+	wordPattern = regexp.MustCompile(`[\p{L}\p{N}']+`)
+)
+
+// Links returns every link written out in text, including bare www. ones.
+func Links(text string) []string {
+	return linkPattern.FindAllString(text, -1)
+}
+
+// LinkHosts returns the lowercased host of every link in text.
+func LinkHosts(text string) []string {
+	var hosts []string
+	for _, link := range Links(text) {
+		if !strings.Contains(link, "://") {
+			link = "http://" + link
+		}
+		u, err := url.Parse(link)
+		if err != nil || u.Hostname() == "" {
+			continue
+		}
+		hosts = append(hosts, strings.ToLower(u.Hostname()))
+	}
+	return hosts
+}
+
+// Honeypot rejects submissions that filled in the hidden honeypot field.
+type Honeypot struct{}
+
+func (Honeypot) Check(_ context.Context, s Submission) (Result, error) {
+	if strings.TrimSpace(s.Honeypot) != "" {
+		return Result{Verdict: Reject, Reason: "honeypot field filled in"}, nil
+	}
+	return Result{}, nil
+}
+
+// LinkCount holds comments with more than Max links, and any comment whose
+// author name is a link.
+type LinkCount struct {
+	Max int
+}
+
+func (c LinkCount) Check(_ context.Context, s Submission) (Result, error) {
+	if len(Links(s.Author)) > 0 {
+		return Result{Verdict: Hold, Reason: "link in the author name"}, nil
+	}
+	if n := len(Links(s.Body)); n > c.Max {
+		return Result{Verdict: Hold, Reason: fmt.Sprintf("%d links", n)}, nil
+	}
+	return Result{}, nil
+}
+
+// Blocked terms are either words, matched case-insensitively against whole
+// words of the comment and author name, or domains, matched against the
+// hosts of links including their subdomains.
+const (
+	BlockWord   = "word"
+	BlockDomain = "domain"
+)
+
+var BlockKinds = []string{BlockWord, BlockDomain}
+
+type BlockedTerm struct {
+	Kind  string
+	Value string
+}
+
+// Blocklist holds comments containing a blocked word or linking to a blocked
+// domain. The list is loaded on every check so edits apply straight away.
+type Blocklist struct {
+	Load func(ctx context.Context) ([]BlockedTerm, error)
+}
+
+func (b Blocklist) Check(ctx context.Context, s Submission) (Result, error) {
+	terms, err := b.Load(ctx)
+	if err != nil {
+		return Result{}, err
+	}
+	if len(terms) == 0 {
+		return Result{}, nil
+	}
+
+	words := make(map[string]bool)
+	for _, w := range wordPattern.FindAllString(strings.ToLower(s.Author+" "+s.Body), -1) {
+		words[w] = true
+	}
+	hosts := LinkHosts(s.Author + " " + s.Body)
+
+	for _, term := range terms {
+		value := strings.ToLower(term.Value)
+		switch term.Kind {
+		case BlockWord:
+			if words[value] || strings.ContainsRune(value, ' ') && strings.Contains(strings.ToLower(s.Body), value) {
+				return Result{Verdict: Hold, Reason: "blocked word " + term.Value}, nil
+			}
+		case BlockDomain:
+			for _, host := range hosts {
+				if host == value || strings.HasSuffix(host, "."+value) {
+					return Result{Verdict: Hold, Reason: "blocked domain " + term.Value}, nil
+				}
+			}
+		}
+	}
+	return Result{}, nil
+}
@@ -0,0 +1,82 @@
+// Package spam decides whether a new comment looks like spam. A Filter runs a
+// list of checks in order and stops at the first one that objects.
+package spam
+
+import (
+	"context"
+)
+
+// Submission is a comment as it was posted, along with the form fields the
+// checks look at.
+type Submission struct {
+	Author string
+	Body   string
+	// Honeypot is the value of the hidden field only bots fill in.
+	Honeypot string
+	// FormToken is the token the comment form was rendered with.
+	FormToken string
+}
+
+type Verdict int
+
+const (
+	// Pass lets the comment through to moderation as usual.
+	Pass Verdict = iota
+	// Hold puts the comment in the spam queue for a moderator to look at.
+	Hold
+	// Reject drops the comment. It is used when a bot is all but certain.
+	Reject
+)
+
+func (v Verdict) String() string {
+	switch v {
+	case Pass:
+		return "pass"
+	case Hold:
+		return "hold"
+	case Reject:
+		return "reject"
+	}
+	return "unknown"
+}
+
+type Result struct {
+	Verdict Verdict
+	// Reason is shown to moderators next to held comments.
+	Reason string
+}
+
+// Checker is a single spam check. Checks that need outside state, like the
+// blocklist, may fail; the filter then moves on to the next check.
+type Checker interface {
+	Check(ctx context.Context, s Submission) (Result, error)
+}
+
+// CheckerFunc lets a plain function be used as a Checker.
+type CheckerFunc func(ctx context.Context, s Submission) (Result, error)
+
+func (f CheckerFunc) Check(ctx context.Context, s Submission) (Result, error) {
+	return f(ctx, s)
+}
+
+// Filter runs its checks in order. Cheap checks that reject should come
+// first.
+type Filter []Checker
+
+// Check returns the first result that is not Pass. Errors from individual
+// checks are returned alongside the result so they can be logged; a broken
+// check never blocks a comment on its own.
+func (f Filter) Check(ctx context.Context, s Submission) (Result, []error) {
+	var errs []error
+	for _, c := range f {
+		result, err := c.Check(ctx, s)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if result.Verdict != Pass {
+			return result, errs
+		}
+	}
+	return Result{Verdict: Pass}, errs
+}
@@ -0,0 +1,173 @@
+package spam
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestFilterStopsAtFirstObjection(t *testing.T) {
+	var calls []string
+	check := func(name string, v Verdict, err error) Checker {
+		return CheckerFunc(func(context.Context, Submission) (Result, error) {
+			calls = append(calls, name)
+			return Result{Verdict: v, Reason: name}, err
+		})
+	}
+
+	f := Filter{check("broken", Reject, errors.New("down")), check("pass", Pass, nil), check("hold", Hold, nil), check("never", Reject, nil)}
+	result, errs := f.Check(context.Background(), Submission{})
+	if result.Verdict != Hold || result.Reason != "hold" {
+		t.Errorf("result = %+v", result)
+	}
+	if len(errs) != 1 {
+		t.Errorf("errs = %v", errs)
+	}
+	if !slices.Equal(calls, []string{"broken", "pass", "hold"}) {
+		t.Errorf("calls = %v", calls)
+	}
+}
+
+func TestLinkCount(t *testing.T) {
+	c := LinkCount{Max: 2}
+	tests := []struct {
+		s    Submission
+		want Verdict
+	}{
+		{Submission{Author: "ann", Body: "see https://a.example and www.b.example"}, Pass},
+		{Submission{Author: "ann", Body: "https://a.example https://b.example [c](http://c.example)"}, Hold},
+		{Submission{Author: "https://cheap.example", Body: "nice post"}, Hold},
+	}
+	for _, tt := range tests {
+		if got, _ := c.Check(context.Background(), tt.s); got.Verdict != tt.want {
+			t.Errorf("%+v: got %v, want %v", tt.s, got.Verdict, tt.want)
+		}
+	}
+}
+
+func TestHoneypot(t *testing.T) {
+	if got, _ := (Honeypot{}).Check(context.Background(), Submission{Honeypot: "http://spam.example"}); got.Verdict != Reject {
+		t.Errorf("filled honeypot got %v", got.Verdict)
+	}
+	if got, _ := (Honeypot{}).Check(context.Background(), Submission{}); got.Verdict != Pass {
+		t.Errorf("empty honeypot got %v", got.Verdict)
+	}
+}
+
+func TestTimeOnPage(t *testing.T) {
+	secret := []byte("secret")
+	rendered := time.Date(2026, time.October, 18, 12, 0, 0, 0, time.UTC)
+	token := NewFormToken(secret, rendered)
+
+	tests := []struct {
+		name  string
+		token string
+		after time.Duration
+		want  Verdict
+	}{
+		{"human", token, 30 * time.Second, Pass},
+		{"too fast", token, time.Second, Reject},
+		{"stale", token, 48 * time.Hour, Hold},
+		{"missing", "", time.Minute, Reject},
+		{"forged", NewFormToken([]byte("other"), rendered), time.Minute, Reject},
+	}
+	for _, tt := range tests {
+		c := TimeOnPage{Secret: secret, Min: 3 * time.Second, Max: 24 * time.Hour, Now: func() time.Time { return rendered.Add(tt.after) }}
+		if got, _ := c.Check(context.Background(), Submission{FormToken: tt.token}); got.Verdict != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got.Verdict, tt.want)
+		}
+	}
+}
+
+func TestBlocklist(t *testing.T) {
+	b := Blocklist{Load: func(context.Context) ([]BlockedTerm, error) {
+		return []BlockedTerm{{BlockWord, "Casino"}, {BlockWord, "cheap pills"}, {BlockDomain, "spam.example"}}, nil
+	}}
+	tests := []struct {
+		body string
+		want Verdict
+	}{
+		{"best casino in town", Hold},
+		{"get CHEAP PILLS now", Hold},
+		{"occasional reader here", Pass},
+		{"see https://shop.spam.example/buy", Hold},
+		{"see https://notspam.example", Pass},
+	}
+	for _, tt := range tests {
+		if got, _ := b.Check(context.Background(), Submission{Body: tt.body}); got.Verdict != tt.want {
+			t.Errorf("%q: got %v, want %v", tt.body, got.Verdict, tt.want)
+		}
+	}
+}
+
+func TestTokens(t *testing.T) {
+	got := Tokens("Buy cheap WATCHES at https://Shop.example/x, buy now!")
+	want := []string{"buy", "cheap", "host:shop.example", "now", "watches"}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+type memoryStore struct {
+	counts    map[string]TokenCount
+	ham, spam int64
+}
+
+func (m *memoryStore) train(text string, spam bool) {
+	for _, t := range Tokens(text) {
+		c := m.counts[t]
+		if spam {
+			c.Spam++
+		} else {
+			c.Ham++
+		}
+		m.counts[t] = c
+	}
+	if spam {
+		m.spam++
+	} else {
+		m.ham++
+	}
+}
+
+func (m *memoryStore) TokenCounts(_ context.Context, tokens []string) (map[string]TokenCount, error) {
+	return m.counts, nil
+}
+
+func (m *memoryStore) Totals(context.Context) (int64, int64, error) {
+	return m.ham, m.spam, nil
+}
+
+func TestBayes(t *testing.T) {
+	store := &memoryStore{counts: map[string]TokenCount{}}
+	b := Bayes{Store: store, Threshold: 0.9, MinTrained: 3}
+
+	spammy := Submission{Body: "cheap replica watches, visit https://watches.example"}
+	if got, _ := b.Check(context.Background(), spammy); got.Verdict != Pass {
+		t.Errorf("untrained classifier got %v", got.Verdict)
+	}
+
+	for _, s := range []string{
+		"cheap replica watches at https://watches.example",
+		"buy cheap watches online https://watches.example",
+		"replica bags and watches, cheap prices",
+	} {
+		store.train(s, true)
+	}
+	for _, s := range []string{
+		"thanks for the write up on goroutines",
+		"the benchmark in the second section surprised me",
+		"have you tried this with generics? thanks",
+	} {
+		store.train(s, false)
+	}
+
+	if got, _ := b.Check(context.Background(), spammy); got.Verdict != Hold {
+		t.Errorf("spam got %v", got.Verdict)
+	}
+	if got, _ := b.Check(context.Background(), Submission{Body: "thanks, the goroutines section was great"}); got.Verdict != Pass {
+		t.Errorf("ham got %v", got.Verdict)
+	}
+}
@@ -0,0 +1,72 @@
+package spam
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NewFormToken returns a token recording when a comment form was rendered,
+// signed with secret so it can't be backdated.
+func NewFormToken(secret []byte, renderedAt time.Time) string {
+	ts := strconv.FormatInt(renderedAt.Unix(), 10)
+	return ts + "." + signFormToken(secret, ts)
+}
+
+func signFormToken(secret []byte, ts string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("comment-form:" + ts))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// parseFormToken returns when the form was rendered if the token is genuine.
+func parseFormToken(secret []byte, token string) (time.Time, bool) {
+	ts, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return time.Time{}, false
+	}
+	if !hmac.Equal([]byte(sig), []byte(signFormToken(secret, ts))) {
+		return time.Time{}, false
+	}
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(unix, 0), true
+}
+
+// TimeOnPage rejects comments posted without a genuine form token, or sooner
+// than Min after the form was rendered, which people don't manage. Tokens
+// older than Max are held rather than rejected since readers do leave tabs
+// open.
+type TimeOnPage struct {
+	Secret []byte
+	Min    time.Duration
+	Max    time.Duration
+	// Now defaults to time.Now.
+	Now func() time.Time
+}
+
+func (c TimeOnPage) Check(_ context.Context, s Submission) (Result, error) {
+	renderedAt, ok := parseFormToken(c.Secret, s.FormToken)
+	if !ok {
+		return Result{Verdict: Reject, Reason: "missing or forged form token"}, nil
+	}
+
+	now := time.Now
+	if c.Now != nil {
+		now = c.Now
+	}
+	elapsed := now().Sub(renderedAt)
+	switch {
+	case elapsed < c.Min:
+		return Result{Verdict: Reject, Reason: "posted " + elapsed.Round(time.Millisecond).String() + " after loading the page"}, nil
+	case c.Max > 0 && elapsed > c.Max:
+		return Result{Verdict: Hold, Reason: "stale form token"}, nil
+	}
+	return Result{}, nil
+}
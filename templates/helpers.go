@@ -3,6 +3,7 @@ package templates
 import (
 	"context"
 	"fmt"
+	"slices"
 	"time"
 
 	"blog.simoni.dev/models"
@@ -30,8 +31,7 @@ func GetTagLink(tag string) templ.SafeURL {
 }
 
 func GetPostSlug(post models.BlogPost) templ.SafeURL {
-	t := post.PublishedAt.Local()
-	return templ.SafeURL(fmt.Sprintf("/post/%02d/%02d/%d/%s", t.Month(), t.Day(), t.Year(), post.Slug))
+	return templ.SafeURL(post.Permalink())
 }
 
 func GetUserLink(username string) templ.SafeURL {
@@ -92,3 +92,55 @@ func FormatAsDateTime(t time.Time) string {
 
 	return timeString
 }
+
+func GetUsername(ctx context.Context) string {
+	username, _ := ctx.Value("username").(string)
+	return username
+}
+
+// CanComment reports whether the visitor may comment, either because they are
+// signed in or because guest comments are allowed.
+func CanComment(ctx context.Context) bool {
+	if authed, _ := ctx.Value("authed").(bool); authed {
+		return true
+	}
+	guests, _ := ctx.Value("guestComments").(bool)
+	return guests
+}
+
+// CanModifyComment reports whether the visitor may edit or delete c. The
+// server checks again when the change is made.
+func CanModifyComment(ctx context.Context, c models.Comment) bool {
+	if c.Deleted {
+		return false
+	}
+	if IsAdmin(ctx) {
+		return true
+	}
+	userId, ok := ctx.Value("userId").(uint)
+	if !ok || c.UserId == nil || *c.UserId != int64(userId) {
+		return false
+	}
+	return time.Now().Before(c.EditableUntil)
+}
+
+// GetCommentFormToken is the signed render time comment forms are posted
+// with.
+func GetCommentFormToken(ctx context.Context) string {
+	token, _ := ctx.Value("commentFormToken").(string)
+	return token
+}
+
+// RequiresPow reports whether form has to solve a proof-of-work challenge
+// before it is sent. Forms that do carry data-pow with their name.
+func RequiresPow(ctx context.Context, form string) bool {
+	forms, _ := ctx.Value("powForms").([]string)
+	return slices.Contains(forms, form)
+}
+
+// RegistrationOpen reports whether anyone may create an account, without
+// needing an invite.
+func RegistrationOpen(ctx context.Context) bool {
+	mode, _ := ctx.Value("registration").(string)
+	return mode == "open"
+}
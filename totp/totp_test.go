@@ -0,0 +1,81 @@
+package totp
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCodeRFC6238(t *testing.T) {
+	// The SHA1 test vectors from RFC 6238 appendix B.
+	key := []byte("12345678901234567890")
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	}
+	for _, tt := range tests {
+		if got := hotp(key, uint64(Step(time.Unix(tt.unix, 0))), 8); got != tt.want {
+			t.Errorf("code at %d = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+
+	secret := base32.StdEncoding.EncodeToString(key)
+	got, err := Code(secret, time.Unix(59, 0))
+	if err != nil || got != "287082" {
+		t.Errorf("Code = %q, %v, want 287082", got, err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret, err := NewSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1700000000, 0)
+	current, _ := Code(secret, now)
+	previous, _ := Code(secret, now.Add(-Period))
+	tooOld, _ := Code(secret, now.Add(-3*Period))
+
+	step, ok := Validate(secret, current, now, 0)
+	if !ok || step != Step(now) {
+		t.Fatalf("current code: step %d, ok %v", step, ok)
+	}
+	if _, ok := Validate(secret, current, now, step); ok {
+		t.Error("code accepted twice")
+	}
+	if _, ok := Validate(secret, previous, now, 0); !ok {
+		t.Error("code from the previous step refused")
+	}
+	if _, ok := Validate(secret, previous, now, step); ok {
+		t.Error("code older than the last one accepted")
+	}
+	if _, ok := Validate(secret, tooOld, now, 0); ok && tooOld != current && tooOld != previous {
+		t.Error("code from three steps ago accepted")
+	}
+	if _, ok := Validate(secret, "abcdef", now, 0); ok {
+		t.Error("garbage accepted")
+	}
+	if _, ok := Validate("not base32!", current, now, 0); ok {
+		t.Error("bad secret accepted")
+	}
+}
+
+func TestURI(t *testing.T) {
+	uri := URI("My Blog", "ann@example.com", "JBSWY3DPEHPK3PXP")
+	if !strings.HasPrefix(uri, "otpauth://totp/My%20Blog:ann@example.com?") {
+		t.Errorf("uri = %s", uri)
+	}
+	for _, part := range []string{"secret=JBSWY3DPEHPK3PXP", "issuer=My+Blog", "digits=6", "period=30"} {
+		if !strings.Contains(uri, part) {
+			t.Errorf("uri %s is missing %s", uri, part)
+		}
+	}
+}